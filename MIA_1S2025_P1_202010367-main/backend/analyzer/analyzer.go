@@ -6,8 +6,121 @@ import (
 	"strings" // Importa el paquete "strings" para manipulación de cadenas
 
 	commands "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/commands" // Importa el paquete "commands" que contiene las funciones para analizar comandos
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
 )
 
+// mutatingCommands son los comandos que modifican la partición indicada con -id o la de
+// la sesión; se rechazan si está montada como solo lectura
+var mutatingCommands = map[string]bool{
+	"mkfs":        true,
+	"mkdir":       true,
+	"mkfile":      true,
+	"mkgrp":       true,
+	"rmgrp":       true,
+	"mkusr":       true,
+	"rmusr":       true,
+	"chgrp":       true,
+	"remove":      true,
+	"edit":        true,
+	"rename":      true,
+	"chmod":       true,
+	"copy":        true,
+	"move":        true,
+	"chown":       true,
+	"loss":        true,
+	"recovery":    true,
+	"snapshot":    true,
+	"rollback":    true,
+	"dedup":       true,
+	"trash":       true,
+	"undelete":    true,
+	"import":      true,
+	"import_file": true,
+	"clonepart":   true,
+	"discard":     true,
+	"defrag":      true,
+}
+
+// sessionPathCommands son los comandos cuyo -path es una ruta de la sesión, que puede
+// pasar por carpetas donde se montaron otras particiones con mount -at
+var sessionPathCommands = map[string]bool{
+	"mkfile":      true,
+	"mkdir":       true,
+	"edit":        true,
+	"remove":      true,
+	"rename":      true,
+	"chmod":       true,
+	"chown":       true,
+	"import_file": true,
+}
+
+// targetPartitions devuelve los IDs de las particiones que modificará el comando. copy
+// modifica la partición del destino y move también la del origen; ambas pueden indicarse
+// como ID:/ruta o estar montadas en una carpeta con mount -at. Los comandos de
+// sessionPathCommands modifican la partición que contiene -path. clonepart modifica -dest
+func targetPartitions(command string, tokens []string) []string {
+	var ids []string
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.Trim(parts[1], "\"")
+		switch key := strings.ToLower(parts[0]); {
+		case key == "-id":
+			return []string{strings.ToUpper(value)}
+		case sessionPathCommands[command]:
+			if key == "-path" {
+				id, _ := stores.ResolvePath(stores.CurrentSession.ID, value)
+				return []string{id}
+			}
+		case command == "clonepart":
+			if key == "-dest" {
+				return []string{strings.ToUpper(value)}
+			}
+		case command == "copy" || command == "move":
+			if key == "-destino" || key == "-dest" || (command == "move" && (key == "-path" || key == "-src")) {
+				id, _ := stores.ResolvePath(stores.SplitEndpoint(value))
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return []string{stores.CurrentSession.ID}
+	}
+	return ids
+}
+
+// isMutating indica si el comando modifica la partición. trash -list, undelete sin
+// -recover ni -inode y tunefs sin -reset solo leen o cambian la configuración del servidor
+func isMutating(command string, tokens []string) bool {
+	switch command {
+	case "trash":
+		for _, token := range tokens {
+			if strings.ToLower(token) == "-list" {
+				return false
+			}
+		}
+	case "tunefs":
+		for _, token := range tokens {
+			if strings.ToLower(token) == "-reset" {
+				return true
+			}
+		}
+		return false
+	case "undelete":
+		for _, token := range tokens {
+			key := strings.ToLower(strings.SplitN(token, "=", 2)[0])
+			if key == "-recover" || key == "-inode" {
+				return true
+			}
+		}
+		return false
+	}
+	return mutatingCommands[command]
+}
+
 // splitCommand divide la entrada respetando cadenas entre comillas
 // splitCommand divide la entrada respetando cadenas entre comillas
 func splitCommand(input string) []string {
@@ -58,7 +171,39 @@ func Analyzer(input string) (string, error) {
 	// Convertir el comando a minúsculas para hacerlo case-insensitive
 	command := strings.ToLower(tokens[0])
 
-	// Ejecutar el comando correspondiente
+	// Rechazar los comandos que modifican una partición montada como solo lectura
+	if isMutating(command, tokens[1:]) {
+		for _, id := range targetPartitions(command, tokens[1:]) {
+			if _, mounted := stores.MountedPartitions[id]; mounted && stores.GetMountOptions(id).ReadOnly {
+				return "", fmt.Errorf("%s: la partición %s está montada como solo lectura (ro)", command, id)
+			}
+		}
+	}
+
+	result, err := execute(command, tokens)
+
+	// Los bitmaps en memoria del asignador solo valen durante el comando
+	structures.ResetAllocators()
+
+	// Las particiones montadas con la opción discard descartan los bloques que liberó el comando
+	var discardErr error
+	if err == nil && isMutating(command, tokens[1:]) {
+		discardErr = commands.DiscardMounted(targetPartitions(command, tokens[1:]))
+	}
+	structures.ResetReleasedBlocks()
+	if discardErr != nil {
+		return result, discardErr
+	}
+
+	// Las particiones montadas con la opción sync se escriben en disco después de cada comando
+	if syncErr := stores.SyncMountedDisks(); syncErr != nil && err == nil {
+		return result, syncErr
+	}
+	return result, err
+}
+
+// execute ejecuta el comando correspondiente
+func execute(command string, tokens []string) (string, error) {
 	switch command {
 	case "mkdisk":
 		return commands.ParseMkdisk(tokens[1:])
@@ -70,6 +215,10 @@ func Analyzer(input string) (string, error) {
 		return commands.ParseMount(tokens[1:])
 	case "mounted":
 		return commands.ParseMounted(tokens[1:])
+	case "automount":
+		return commands.ParseAutomount(tokens[1:])
+	case "tunefs":
+		return commands.ParseTunefs(tokens[1:])
 	case "unmount":
 		return commands.ParseUnmount(tokens[1:])
 	case "mkfs":
@@ -118,6 +267,40 @@ func Analyzer(input string) (string, error) {
 		return commands.ParseLoss(tokens[1:])
 	case "recovery":
 		return commands.ParseRecovery(tokens[1:])
+	case "snapshot":
+		return commands.ParseSnapshot(tokens[1:])
+	case "rollback":
+		return commands.ParseRollback(tokens[1:])
+	case "snapshots":
+		return commands.ParseSnapshots(tokens[1:])
+	case "dedup":
+		return commands.ParseDedup(tokens[1:])
+	case "trash":
+		return commands.ParseTrash(tokens[1:])
+	case "undelete":
+		return commands.ParseUndelete(tokens[1:])
+	case "export":
+		return commands.ParseExport(tokens[1:])
+	case "import":
+		return commands.ParseImport(tokens[1:])
+	case "export_file":
+		return commands.ParseExportFile(tokens[1:])
+	case "import_file":
+		return commands.ParseImportFile(tokens[1:])
+	case "clonedisk":
+		return commands.ParseClonedisk(tokens[1:])
+	case "clonepart":
+		return commands.ParseClonepart(tokens[1:])
+	case "ptable":
+		return commands.ParsePtable(tokens[1:])
+	case "compactdisk":
+		return commands.ParseCompactdisk(tokens[1:])
+	case "discard":
+		return commands.ParseDiscard(tokens[1:])
+	case "resizedisk":
+		return commands.ParseResizedisk(tokens[1:])
+	case "defrag":
+		return commands.ParseDefrag(tokens[1:])
 	default:
 		return "", fmt.Errorf("comando desconocido: %s", command)
 	}
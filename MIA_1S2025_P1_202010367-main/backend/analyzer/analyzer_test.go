@@ -0,0 +1,622 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+)
+
+var mountedID = regexp.MustCompile(`con ID: (\S+)`)
+
+// run ejecuta un comando y falla la prueba si devuelve error
+func run(t testing.TB, input string) string {
+	t.Helper()
+	output, err := Analyzer(input)
+	if err != nil {
+		t.Fatalf("%s: %v", input, err)
+	}
+	return output
+}
+
+// newPartition crea un disco en una carpeta temporal con una partición de 5 MB formateada
+// con fs (2fs o 3fs), la monta e inicia sesión como root. Devuelve el ID y la ruta del disco
+func newPartition(t testing.TB, fs string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	stores.MountTablePath = filepath.Join(dir, "fstab.mia")
+	stores.CurrentSession = stores.Session{}
+
+	diskPath := filepath.Join(dir, "Disco.mia")
+	run(t, "mkdisk -size=8 -unit=M -path="+diskPath)
+	run(t, "fdisk -size=5 -unit=M -path="+diskPath+" -name=Part1")
+	match := mountedID.FindStringSubmatch(run(t, "mount -path="+diskPath+" -name=Part1"))
+	if match == nil {
+		t.Fatal("mount no devolvió el ID de la partición")
+	}
+	id := match[1]
+	t.Cleanup(func() {
+		stores.CurrentSession = stores.Session{}
+		Analyzer("unmount -id=" + id)
+	})
+	run(t, "mkfs -id="+id+" -fs="+fs)
+	run(t, "login -user=root -pass=123 -id="+id)
+	return id, diskPath
+}
+
+// checkBlockRefs compara el contador de referencias de cada bloque del bitmap con las
+// referencias de los inodos en uso: los bloques de datos y de apuntadores de los archivos y
+// los bloques directos de las carpetas
+func checkBlockRefs(t *testing.T, id string) {
+	t.Helper()
+	sb, _, diskPath, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inodes, err := sb.ReadInodeTable(diskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inodeBitmap, err := sb.ReadInodeBitmap(diskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live := make([]int32, sb.S_blocks_count)
+	for i := range inodes {
+		if inodeBitmap[i] != '1' {
+			continue
+		}
+		blocks := []int32{}
+		if inodes[i].I_type[0] == '1' {
+			data, pointers, err := sb.FileBlocks(diskPath, &inodes[i])
+			if err != nil {
+				t.Fatalf("inodo %d: %v", i, err)
+			}
+			blocks = append(data, pointers...)
+		} else {
+			for _, blockNum := range inodes[i].I_block[:12] {
+				if blockNum != -1 {
+					blocks = append(blocks, blockNum)
+				}
+			}
+		}
+		for _, blockNum := range blocks {
+			live[blockNum]++
+		}
+	}
+
+	free := int32(0)
+	for blockNum := range live {
+		refs, err := sb.GetBlockRefs(diskPath, int32(blockNum))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if refs != live[blockNum] {
+			t.Errorf("bloque %d: bitmap=%d, referencias de inodos=%d", blockNum, refs, live[blockNum])
+		}
+		if refs == 0 {
+			free++
+		}
+	}
+	if free != sb.S_free_blocks_count {
+		t.Errorf("S_free_blocks_count=%d, bloques libres en el bitmap=%d", sb.S_free_blocks_count, free)
+	}
+}
+
+func TestDedupThenEditKeepsBlockRefs(t *testing.T) {
+	id, _ := newPartition(t, "2fs")
+	// Tres bloques iguales en el mismo archivo y uno igual en otro
+	run(t, "mkfile -path=/a.txt -cont="+strings.Repeat("x", 3*64))
+	run(t, "mkfile -path=/b.txt -cont="+strings.Repeat("x", 64))
+	if output := run(t, "dedup -id="+id); !strings.Contains(output, "3 bloques fusionados") {
+		t.Fatalf("dedup: %s", output)
+	}
+	checkBlockRefs(t, id)
+
+	run(t, "edit -path=/a.txt -cont="+strings.Repeat("y", 2*64))
+	checkBlockRefs(t, id)
+	run(t, "edit -path=/b.txt -cont="+strings.Repeat("z", 5*64))
+	checkBlockRefs(t, id)
+}
+
+func TestDedupThenRemoveKeepsBlockRefs(t *testing.T) {
+	id, _ := newPartition(t, "2fs")
+	run(t, "mkfile -path=/a.txt -cont="+strings.Repeat("x", 20*64))
+	run(t, "mkfile -path=/b.txt -cont="+strings.Repeat("x", 2*64))
+	run(t, "dedup -id="+id)
+	checkBlockRefs(t, id)
+
+	run(t, "remove -path=/a.txt")
+	checkBlockRefs(t, id)
+	run(t, "remove -path=/b.txt")
+	checkBlockRefs(t, id)
+}
+
+func TestMain(m *testing.M) {
+	// Las pruebas no deben dejar la tabla de montaje en la carpeta del paquete
+	stores.Automount = false
+	stores.MountTablePath = filepath.Join(os.TempDir(), "fstab-test.mia")
+	code := m.Run()
+	os.Remove(stores.MountTablePath)
+	os.Exit(code)
+}
+
+// TestMSDOSLayoutMatchesStandardMBR lee el sector 0 de un disco -table=MSDOS como lo hace
+// cualquier herramienta del anfitrión: firma 0x55AA y entradas de 16 bytes desde el byte 446
+// con el tipo en el byte 4, el primer sector en el 8 y la cantidad de sectores en el 12
+func TestMSDOSLayoutMatchesStandardMBR(t *testing.T) {
+	diskPath := filepath.Join(t.TempDir(), "Disco.mia")
+	run(t, "mkdisk -size=8 -unit=M -table=MSDOS -path="+diskPath)
+	run(t, "fdisk -size=3 -unit=M -path="+diskPath+" -name=Part1")
+	run(t, "fdisk -size=1001 -unit=K -path="+diskPath+" -name=Part2")
+
+	want := []struct{ start, sectors uint32 }{
+		{2, 3 * 1024 * 1024 / 512},
+		{2 + 3*1024*1024/512, 1001 * 1024 / 512},
+	}
+
+	sector := make([]byte, 512)
+	file, err := os.Open(diskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.ReadAt(sector, 0); err != nil {
+		t.Fatal(err)
+	}
+	if sector[510] != 0x55 || sector[511] != 0xAA {
+		t.Fatalf("firma del MBR: %#x %#x", sector[510], sector[511])
+	}
+	for i := 0; i < 4; i++ {
+		entry := sector[446+16*i : 446+16*(i+1)]
+		partType := entry[4]
+		start := binary.LittleEndian.Uint32(entry[8:12])
+		sectors := binary.LittleEndian.Uint32(entry[12:16])
+		if i >= len(want) {
+			if partType != 0 || start != 0 || sectors != 0 {
+				t.Errorf("entrada %d: se esperaba vacía, tipo=%#x inicio=%d sectores=%d", i, partType, start, sectors)
+			}
+			continue
+		}
+		if partType != 0x83 || start != want[i].start || sectors != want[i].sectors {
+			t.Errorf("entrada %d: tipo=%#x inicio=%d sectores=%d, se esperaba tipo=0x83 inicio=%d sectores=%d",
+				i, partType, start, sectors, want[i].start, want[i].sectors)
+		}
+	}
+
+	// Si el anfitrión tiene sfdisk, debe leer la misma tabla
+	if _, err := exec.LookPath("sfdisk"); err != nil {
+		t.Log("sfdisk no está disponible, se omite la comparación")
+		return
+	}
+	output, err := exec.Command("sfdisk", "--json", diskPath).Output()
+	if err != nil {
+		t.Fatalf("sfdisk --json: %v", err)
+	}
+	var dump struct {
+		PartitionTable struct {
+			Label      string `json:"label"`
+			Partitions []struct {
+				Start uint32 `json:"start"`
+				Size  uint32 `json:"size"`
+				Type  string `json:"type"`
+			} `json:"partitions"`
+		} `json:"partitiontable"`
+	}
+	if err := json.Unmarshal(output, &dump); err != nil {
+		t.Fatalf("salida de sfdisk: %v", err)
+	}
+	if dump.PartitionTable.Label != "dos" || len(dump.PartitionTable.Partitions) != len(want) {
+		t.Fatalf("sfdisk: etiqueta %q con %d particiones", dump.PartitionTable.Label, len(dump.PartitionTable.Partitions))
+	}
+	for i, p := range dump.PartitionTable.Partitions {
+		if p.Start != want[i].start || p.Size != want[i].sectors || p.Type != "83" {
+			t.Errorf("sfdisk, partición %d: inicio=%d sectores=%d tipo=%s", i+1, p.Start, p.Size, p.Type)
+		}
+	}
+}
+
+func TestUndeleteRecoversFileWithIndirectBlocks(t *testing.T) {
+	id, _ := newPartition(t, "2fs")
+	var content strings.Builder
+	for i := 0; content.Len() < 1000; i++ {
+		content.WriteString(string(rune('a' + i%26)))
+	}
+	run(t, "mkfile -path=/small.txt -cont=pequeño")
+	run(t, "mkfile -path=/big.txt -cont="+content.String())
+	run(t, "remove -path=/small.txt")
+	run(t, "remove -path=/big.txt")
+
+	output := run(t, "undelete -id="+id)
+	if !strings.Contains(output, "small.txt") || !strings.Contains(output, "(sin nombre)  archivo  1000 bytes") {
+		t.Fatalf("undelete no listó los dos archivos:\n%s", output)
+	}
+	if strings.Contains(output, "no recuperable") {
+		t.Fatalf("un archivo aparece como no recuperable:\n%s", output)
+	}
+
+	output = run(t, "undelete -id="+id+" -recover")
+	match := regexp.MustCompile(`#\d+`).FindString(output)
+	if match == "" || !strings.Contains(output, "small.txt") {
+		t.Fatalf("undelete -recover: %s", output)
+	}
+	if output := run(t, "cat -file1=/lost+found/"+match); !strings.Contains(output, content.String()) {
+		t.Fatalf("contenido recuperado incorrecto:\n%s", output)
+	}
+	checkBlockRefs(t, id)
+}
+
+func TestDedupMergesIndirectBlocks(t *testing.T) {
+	id, _ := newPartition(t, "2fs")
+	run(t, "mkfile -path=/a.txt -cont="+strings.Repeat("x", 20*64))
+	if output := run(t, "dedup -id="+id); !strings.Contains(output, "19 bloques fusionados") {
+		t.Fatalf("dedup: %s", output)
+	}
+	if output := run(t, "cat -file1=/a.txt"); !strings.Contains(output, strings.Repeat("x", 20*64)) {
+		t.Fatalf("contenido después de dedup:\n%s", output)
+	}
+	checkBlockRefs(t, id)
+}
+
+func TestReadOnlyMountRejectsLowercaseID(t *testing.T) {
+	id, diskPath := newPartition(t, "2fs")
+	run(t, "mkfile -path=/a.txt -cont=hola")
+	run(t, "unmount -id="+id)
+	stores.CurrentSession = stores.Session{}
+	match := mountedID.FindStringSubmatch(run(t, "mount -path="+diskPath+" -name=Part1 -options=ro"))
+	if match == nil {
+		t.Fatal("mount no devolvió el ID de la partición")
+	}
+	id = match[1]
+	run(t, "login -user=root -pass=123 -id="+id)
+
+	lower := strings.ToLower(id)
+	for _, input := range []string{
+		"snapshot -id=" + lower + " -name=s1",
+		"dedup -id=" + lower,
+		"discard -id=" + lower,
+		"defrag -id=" + lower,
+		"undelete -id=" + lower + " -recover",
+		"mkfile -path=/b.txt -cont=chau",
+	} {
+		if _, err := Analyzer(input); err == nil || !strings.Contains(err.Error(), "solo lectura") {
+			t.Errorf("%s: se esperaba el rechazo por ro, error: %v", input, err)
+		}
+	}
+}
+
+func TestRestoredMountCountsAsMount(t *testing.T) {
+	id, _ := newPartition(t, "2fs")
+	sb, _, _, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mounts := sb.S_mnt_count
+
+	maxMountCount := stores.MaxMountCount
+	stores.MaxMountCount = mounts + 1
+	stores.Automount = true
+	defer func() {
+		stores.MaxMountCount = maxMountCount
+		stores.Automount = false
+	}()
+
+	// Simular un reinicio del servidor con automount: la partición solo queda en la tabla
+	// de montaje
+	if err := stores.SaveMountTable(); err != nil {
+		t.Fatal(err)
+	}
+	delete(stores.MountedPartitions, id)
+	messages, err := stores.RestoreMountTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sb, _, _, err = stores.GetMountedPartitionSuperblock(id); err != nil {
+		t.Fatal(err)
+	}
+	if sb.S_mnt_count != mounts+1 {
+		t.Errorf("S_mnt_count=%d después de restaurar, se esperaba %d", sb.S_mnt_count, mounts+1)
+	}
+	if !strings.Contains(strings.Join(messages, "\n"), id+": AVISO: la partición requiere fsck") {
+		t.Errorf("no se avisó que la partición requiere fsck:\n%s", strings.Join(messages, "\n"))
+	}
+}
+
+// login cambia la sesión a root en la partición id
+func login(t *testing.T, id string) {
+	t.Helper()
+	stores.CurrentSession = stores.Session{}
+	run(t, "login -user=root -pass=123 -id="+id)
+}
+
+func TestSessionPathCommandsFollowGrafts(t *testing.T) {
+	host, _ := newPartition(t, "2fs")
+	data, dataDisk := newPartition(t, "2fs")
+	run(t, "mkfile -path=/inner.txt -cont=adentro")
+	run(t, "mkusr -user=ana -pass=123 -grp=root")
+	login(t, host)
+	run(t, "mkdir -p -path=/mnt/data")
+	run(t, "mount -id="+strings.ToLower(data)+" -at=/mnt/data")
+
+	run(t, "mkfile -path=/mnt/data/new.txt -cont=nuevo")
+	run(t, "mkdir -path=/mnt/data/dd")
+	run(t, "edit -path=/mnt/data/inner.txt -cont=editado")
+	run(t, "rename -path=/mnt/data/new.txt -name=otro.txt")
+	run(t, "chmod -path=/mnt/data/otro.txt -ugo=640")
+	run(t, "chown -path=/mnt/data/dd -user=ana")
+	if output := run(t, "cat -file1=/mnt/data/inner.txt -file2=/mnt/data/otro.txt"); !strings.Contains(output, "editado") || !strings.Contains(output, "nuevo") {
+		t.Fatalf("cat a través del punto de montaje:\n%s", output)
+	}
+	if _, err := Analyzer("remove -path=/mnt"); err == nil || !strings.Contains(err.Error(), "puntos de montaje") {
+		t.Errorf("remove de una carpeta con un punto de montaje: %v", err)
+	}
+	run(t, "remove -path=/mnt/data/otro.txt")
+
+	// Los cambios quedaron en la partición montada y no en la carpeta de la anfitriona
+	run(t, "unmount -at=/mnt/data")
+	if output := run(t, "find -path=/mnt/data -name=*"); strings.Contains(output, "dd") || strings.Contains(output, "inner.txt") {
+		t.Errorf("la carpeta de la anfitriona tiene archivos de la partición montada:\n%s", output)
+	}
+	login(t, data)
+	if output := run(t, "cat -file1=/inner.txt"); !strings.Contains(output, "editado") {
+		t.Errorf("inner.txt no se editó en la partición montada:\n%s", output)
+	}
+	checkBlockRefs(t, host)
+	checkBlockRefs(t, data)
+
+	// Solo lectura: la partición montada en la carpeta sigue protegida
+	run(t, "unmount -id="+data)
+	match := mountedID.FindStringSubmatch(run(t, "mount -path="+dataDisk+" -name=Part1 -options=ro"))
+	if match == nil {
+		t.Fatal("mount no devolvió el ID de la partición")
+	}
+	login(t, host)
+	run(t, "mount -id="+match[1]+" -at=/mnt/data")
+	for _, input := range []string{
+		"mkfile -path=/mnt/data/x.txt -cont=x",
+		"mkdir -path=/mnt/data/x",
+		"edit -path=/mnt/data/inner.txt -cont=x",
+		"remove -path=/mnt/data/inner.txt",
+	} {
+		if _, err := Analyzer(input); err == nil || !strings.Contains(err.Error(), "solo lectura") {
+			t.Errorf("%s: se esperaba el rechazo por ro, error: %v", input, err)
+		}
+	}
+}
+
+func TestClonediskDropsSnapshotHolds(t *testing.T) {
+	id, diskPath := newPartition(t, "2fs")
+	run(t, "mkfile -path=/a.txt -cont=antes")
+	run(t, "snapshot -id="+id+" -name=s1")
+	run(t, "edit -path=/a.txt -cont=despues")
+
+	clonePath := filepath.Join(filepath.Dir(diskPath), "Copia.mia")
+	run(t, "clonedisk -src="+diskPath+" -dest="+clonePath)
+	match := mountedID.FindStringSubmatch(run(t, "mount -path="+clonePath+" -name=Part1"))
+	if match == nil {
+		t.Fatal("mount no devolvió el ID de la partición")
+	}
+	clone := match[1]
+	defer Analyzer("unmount -id=" + clone)
+
+	// Los snapshots siguen siendo del disco de origen: la copia no guarda sus bloques
+	checkBlockRefs(t, clone)
+	login(t, clone)
+	run(t, "remove -path=/a.txt")
+	checkBlockRefs(t, clone)
+}
+
+func TestMkdiskRejectsSizesOverInt32(t *testing.T) {
+	dir := t.TempDir()
+	for _, input := range []string{
+		"-size=5120 -unit=M -sparse",
+		"-size=3072 -unit=M -table=GPT -sparse",
+		"-size=2049 -unit=M -table=MSDOS -sparse",
+	} {
+		diskPath := filepath.Join(dir, "Grande.mia")
+		if _, err := Analyzer("mkdisk " + input + " -path=" + diskPath); err == nil || !strings.Contains(err.Error(), "tamaño máximo") {
+			t.Errorf("mkdisk %s: se esperaba el rechazo por tamaño, error: %v", input, err)
+		}
+		if _, err := os.Stat(diskPath); !os.IsNotExist(err) {
+			t.Errorf("mkdisk %s dejó el archivo %s", input, diskPath)
+		}
+	}
+}
+
+func TestDiscardOptionPunchesOnlyReleasedBlocks(t *testing.T) {
+	id, diskPath := newPartition(t, "2fs")
+	run(t, "unmount -id="+id)
+	stores.CurrentSession = stores.Session{}
+	match := mountedID.FindStringSubmatch(run(t, "mount -path="+diskPath+" -name=Part1 -options=discard"))
+	if match == nil {
+		t.Fatal("mount no devolvió el ID de la partición")
+	}
+	id = match[1]
+	login(t, id)
+
+	content := strings.Repeat("descartar", 20)
+	run(t, "mkfile -path=/a.txt -cont="+content)
+	sb, _, _, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inodes, err := sb.ReadInodeTable(diskPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fileBlocks []int32
+	for i := range inodes {
+		if inodes[i].I_type[0] == '1' && inodes[i].I_size == int32(len(content)) {
+			fileBlocks, _, err = sb.FileBlocks(diskPath, &inodes[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if len(fileBlocks) == 0 {
+		t.Fatal("no se encontraron los bloques de a.txt")
+	}
+
+	file, err := os.OpenFile(diskPath, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	blockAt := func(blockNum int32) int64 {
+		return int64(sb.S_block_start) + int64(blockNum)*int64(sb.S_block_size)
+	}
+	readBlock := func(blockNum int32) []byte {
+		buffer := make([]byte, sb.S_block_size)
+		if _, err := file.ReadAt(buffer, blockAt(blockNum)); err != nil {
+			t.Fatal(err)
+		}
+		return buffer
+	}
+
+	// Un bloque que ya estaba libre no se vuelve a descartar en cada comando
+	freeBlock := sb.S_blocks_count - 1
+	if _, err := file.WriteAt([]byte("basura"), blockAt(freeBlock)); err != nil {
+		t.Fatal(err)
+	}
+	run(t, "mkdir -path=/d")
+	if !strings.HasPrefix(string(readBlock(freeBlock)), "basura") {
+		t.Error("mkdir descartó un bloque libre que no liberó")
+	}
+
+	run(t, "remove -path=/a.txt")
+	for _, blockNum := range fileBlocks {
+		if data := readBlock(blockNum); strings.Trim(string(data), "\x00") != "" {
+			t.Errorf("el bloque %d de a.txt no se descartó: %q", blockNum, data[:16])
+		}
+	}
+	if !strings.HasPrefix(string(readBlock(freeBlock)), "basura") {
+		t.Error("remove descartó un bloque libre que no liberó")
+	}
+}
+
+// BenchmarkWriteFileContent escribe un archivo de 2000 bloques y lo vacía. "asignador" usa
+// WriteFileContent, que lee el bitmap una vez y reserva los bloques en memoria; "bitmap por
+// bloque" lee el bitmap completo antes de reservar cada bloque, como FindFreeBlock antes
+// del asignador
+func BenchmarkWriteFileContent(b *testing.B) {
+	id, diskPath := newPartition(b, "2fs")
+	run(b, "mkfile -path=/big.txt -cont=x")
+	sb, _, _, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		b.Fatal(err)
+	}
+	inodes, err := sb.ReadInodeTable(diskPath)
+	if err != nil {
+		b.Fatal(err)
+	}
+	var inode *structures.Inode
+	for i := range inodes {
+		if inodes[i].I_type[0] == '1' && inodes[i].I_size == 1 {
+			inode = &inodes[i]
+		}
+	}
+	if inode == nil {
+		b.Fatal("no se encontró el inodo de big.txt")
+	}
+	blockSize := int(sb.S_block_size)
+	content := bytes.Repeat([]byte("x"), 2000*blockSize)
+
+	// Cada vuelta es un comando nuevo: los bitmaps en memoria se descartan
+	empty := func(b *testing.B) {
+		structures.ResetAllocators()
+		if err := sb.WriteFileContent(diskPath, inode, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("asignador", func(b *testing.B) {
+		for b.Loop() {
+			structures.ResetAllocators()
+			if err := sb.WriteFileContent(diskPath, inode, content); err != nil {
+				b.Fatal(err)
+			}
+			empty(b)
+		}
+	})
+
+	b.Run("bitmap por bloque", func(b *testing.B) {
+		for b.Loop() {
+			var blocks []int32
+			for i := 0; i < len(content); i += blockSize {
+				structures.ResetAllocators()
+				blockNum, err := sb.FindFreeBlock(diskPath)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if err := sb.UpdateBitmapBlock(diskPath, blockNum); err != nil {
+					b.Fatal(err)
+				}
+				sb.S_free_blocks_count--
+				fileBlock := &structures.FileBlock{}
+				copy(fileBlock.B_content[:], content[i:])
+				if err := fileBlock.Serialize(diskPath, int64(sb.S_block_start)+int64(blockNum)*int64(blockSize)); err != nil {
+					b.Fatal(err)
+				}
+				blocks = append(blocks, blockNum)
+			}
+			if err := sb.SetFileBlocks(diskPath, inode, blocks); err != nil {
+				b.Fatal(err)
+			}
+			inode.I_size = int32(len(content))
+			empty(b)
+		}
+	})
+}
+
+func TestPtableRestoreDropsLogicalsAddedAfterDump(t *testing.T) {
+	dir := t.TempDir()
+	diskPath := filepath.Join(dir, "Disco.mia")
+	layoutPath := filepath.Join(dir, "layout.json")
+	run(t, "mkdisk -size=4 -unit=M -path="+diskPath)
+	run(t, "fdisk -size=1 -unit=M -path="+diskPath+" -name=P1")
+	run(t, "fdisk -size=2 -unit=M -type=E -path="+diskPath+" -name=E1")
+	run(t, "ptable -dump -path="+diskPath+" -file="+layoutPath)
+
+	run(t, "fdisk -size=512 -unit=K -type=L -path="+diskPath+" -name=L1")
+	run(t, "ptable -restore -path="+diskPath+" -file="+layoutPath)
+
+	if output := run(t, "ptable -dump -path="+diskPath); strings.Contains(output, "L1") {
+		t.Errorf("la partición lógica creada después del respaldo sobrevivió:\n%s", output)
+	}
+	if _, err := Analyzer("mount -path=" + diskPath + " -name=L1"); err == nil {
+		t.Error("mount montó la partición lógica borrada por el restore")
+	}
+	run(t, "fdisk -size=512 -unit=K -type=L -path="+diskPath+" -name=L1")
+}
+
+func TestTrashKeepsNamesWithCommas(t *testing.T) {
+	id, _ := newPartition(t, "2fs")
+	run(t, "trash -enable")
+	run(t, "mkdir -path=/d,x")
+	run(t, "mkfile -path=/d,x/a,b.txt -cont=coma")
+	run(t, "remove -path=/d,x/a,b.txt")
+
+	if output := run(t, "trash -list"); !strings.Contains(output, "a,b.txt") || !strings.Contains(output, "/d,x/a,b.txt") {
+		t.Fatalf("trash -list no muestra la entrada con comas:\n%s", output)
+	}
+	run(t, "trash -restore=a,b.txt")
+	if output := run(t, "cat -file1=/d,x/a,b.txt"); !strings.Contains(output, "coma") {
+		t.Errorf("a,b.txt no se restauró en su ruta original:\n%s", output)
+	}
+
+	run(t, "remove -path=/d,x/a,b.txt")
+	run(t, "trash -empty")
+	if output := run(t, "find -path=/ -name=*"); strings.Contains(output, "a,b.txt") {
+		t.Errorf("trash -empty no borró la entrada con comas:\n%s", output)
+	}
+	checkBlockRefs(t, id)
+}
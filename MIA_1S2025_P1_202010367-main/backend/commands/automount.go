@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+)
+
+// AUTOMOUNT estructura que representa el comando automount con sus parámetros
+type AUTOMOUNT struct {
+	mode string // "on" u "off"; vacío para mostrar la configuración
+}
+
+/*
+   automount
+   automount -mode=off
+*/
+
+func ParseAutomount(tokens []string) (string, error) {
+	cmd := &AUTOMOUNT{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key, value := strings.ToLower(parts[0]), strings.ToLower(parts[1])
+
+		switch key {
+		case "-mode":
+			if value != "on" && value != "off" {
+				return "", errors.New("el modo debe ser on u off")
+			}
+			cmd.mode = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	return commandAutomount(cmd)
+}
+
+// commandAutomount cambia o muestra si las particiones de la tabla de montaje se vuelven a
+// montar al iniciar el servidor
+func commandAutomount(automount *AUTOMOUNT) (string, error) {
+	if automount.mode != "" {
+		stores.Automount = automount.mode == "on"
+		if err := stores.SaveMountTable(); err != nil {
+			return "", fmt.Errorf("error al cambiar automount: %v", err)
+		}
+	}
+
+	state := "desactivado"
+	if stores.Automount {
+		state = "activado"
+	}
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("AUTOMOUNT: %s (tabla de montaje: %s)\n", state, stores.MountTablePath))
+	for _, entry := range stores.MountEntries() {
+		output.WriteString(fmt.Sprintf("  %s  %s  %s  %s\n", entry.ID, entry.Path, entry.Name, entry.Options))
+	}
+	return strings.TrimSuffix(output.String(), "\n"), nil
+}
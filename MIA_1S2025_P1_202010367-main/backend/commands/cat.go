@@ -1,6 +1,7 @@
 package commands
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"strings"
@@ -12,19 +13,23 @@ import (
 
 // CAT estructura que representa el comando cat con sus parámetros
 type CAT struct {
-	files []string // Lista de rutas de archivos a leer
+	files  []string // Lista de rutas de archivos a leer
+	base64 bool     // Mostrar en base64 el contenido que no es texto
 }
 
 /*
    cat -file1=/test.txt
    cat -file1=/file.txt -file2=/folder/subfolder/newfile.txt
+   cat -file1=/home/imagen.bin -base64
 */
 
 func ParseCat(tokens []string) (string, error) {
 	cmd := &CAT{files: []string{}}
 
 	for _, token := range tokens {
-		if strings.HasPrefix(token, "-file") {
+		if strings.ToLower(token) == "-base64" {
+			cmd.base64 = true
+		} else if strings.HasPrefix(token, "-file") {
 			parts := strings.SplitN(token, "=", 2)
 			if len(parts) != 2 || parts[1] == "" {
 				return "", fmt.Errorf("formato inválido para %s, debe ser -fileX=PATH", parts[0])
@@ -53,18 +58,25 @@ func commandCat(cat *CAT) (string, error) {
 		return "", errors.New("debe iniciar sesión primero")
 	}
 
-	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
-	if err != nil {
-		return "", fmt.Errorf("error al obtener la partición montada: %w", err)
-	}
-
 	var output strings.Builder
 	for i, filePath := range cat.files {
-		content, err := readFile(partitionSuperblock, partitionPath, filePath)
+		// Cada archivo puede estar en otra partición montada con mount -at
+		target, err := resolveSessionPath(filePath)
+		if err != nil {
+			return "", err
+		}
+		content, err := readFile(target.sb, target.diskPath, target.path)
 		if err != nil {
 			return "", fmt.Errorf("error al leer %s: %w", filePath, err)
 		}
-		output.WriteString(fmt.Sprintf("Contenido de %s:\n%s\n", filePath, content))
+		if err := touchFileAtime(target.id, target.sb, target.diskPath, target.path); err != nil {
+			return "", err
+		}
+		if cat.base64 && !utils.IsText(content) {
+			output.WriteString(fmt.Sprintf("Contenido de %s (base64):\n%s\n", filePath, base64.StdEncoding.EncodeToString(content)))
+		} else {
+			output.WriteString(fmt.Sprintf("Contenido de %s:\n%s\n", filePath, content))
+		}
 		if i < len(cat.files)-1 {
 			output.WriteString("\n")
 		}
@@ -73,7 +85,8 @@ func commandCat(cat *CAT) (string, error) {
 	return output.String(), nil
 }
 
-func readFile(sb *structures.SuperBlock, diskPath string, filePath string) (string, error) {
+// readFile devuelve el contenido de un archivo de la partición; su longitud es I_size
+func readFile(sb *structures.SuperBlock, diskPath string, filePath string) ([]byte, error) {
 	parentDirs, fileName := utils.GetParentDirectories(filePath)
 	currentInode := int32(0) // Empezamos en la raíz
 
@@ -82,10 +95,10 @@ func readFile(sb *structures.SuperBlock, diskPath string, filePath string) (stri
 		inode := &structures.Inode{}
 		err := inode.Deserialize(diskPath, int64(sb.S_inode_start+currentInode*sb.S_inode_size))
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		if inode.I_type[0] != '0' {
-			return "", fmt.Errorf("ruta %s inválida: %s no es un directorio", filePath, dir)
+			return nil, fmt.Errorf("ruta %s inválida: %s no es un directorio", filePath, dir)
 		}
 
 		found := false
@@ -96,7 +109,7 @@ func readFile(sb *structures.SuperBlock, diskPath string, filePath string) (stri
 			block := &structures.FolderBlock{}
 			err = block.Deserialize(diskPath, int64(sb.S_block_start+blockIndex*sb.S_block_size))
 			if err != nil {
-				return "", err
+				return nil, err
 			}
 			for _, content := range block.B_content {
 				name := strings.Trim(string(content.B_name[:]), "\x00")
@@ -111,7 +124,7 @@ func readFile(sb *structures.SuperBlock, diskPath string, filePath string) (stri
 			}
 		}
 		if !found {
-			return "", fmt.Errorf("directorio %s no encontrado en la ruta %s", dir, filePath)
+			return nil, fmt.Errorf("directorio %s no encontrado en la ruta %s", dir, filePath)
 		}
 	}
 
@@ -119,10 +132,10 @@ func readFile(sb *structures.SuperBlock, diskPath string, filePath string) (stri
 	inode := &structures.Inode{}
 	err := inode.Deserialize(diskPath, int64(sb.S_inode_start+currentInode*sb.S_inode_size))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if inode.I_type[0] != '0' {
-		return "", fmt.Errorf("ruta %s inválida: el padre de %s no es un directorio", filePath, fileName)
+		return nil, fmt.Errorf("ruta %s inválida: el padre de %s no es un directorio", filePath, fileName)
 	}
 
 	var fileInodeIndex int32 = -1
@@ -133,7 +146,7 @@ func readFile(sb *structures.SuperBlock, diskPath string, filePath string) (stri
 		block := &structures.FolderBlock{}
 		err = block.Deserialize(diskPath, int64(sb.S_block_start+blockIndex*sb.S_block_size))
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 		for _, content := range block.B_content {
 			name := strings.Trim(string(content.B_name[:]), "\x00")
@@ -147,32 +160,31 @@ func readFile(sb *structures.SuperBlock, diskPath string, filePath string) (stri
 		}
 	}
 	if fileInodeIndex == -1 {
-		return "", fmt.Errorf("archivo %s no encontrado", filePath)
+		return nil, fmt.Errorf("archivo %s no encontrado", filePath)
 	}
 
 	// Leer el inodo del archivo
 	fileInode := &structures.Inode{}
 	err = fileInode.Deserialize(diskPath, int64(sb.S_inode_start+fileInodeIndex*sb.S_inode_size))
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	if fileInode.I_type[0] != '1' {
-		return "", fmt.Errorf("%s no es un archivo", filePath)
+		return nil, fmt.Errorf("%s no es un archivo", filePath)
 	}
 
-	// Leer los bloques de datos
-	var content strings.Builder
-	for _, blockIndex := range fileInode.I_block {
-		if blockIndex == -1 || blockIndex == 0 { // 0 podría ser un valor inicial no usado
-			break
-		}
-		fileBlock := &structures.FileBlock{}
-		err = fileBlock.Deserialize(diskPath, int64(sb.S_block_start+blockIndex*sb.S_block_size))
-		if err != nil {
-			return "", err
-		}
-		content.WriteString(strings.Trim(string(fileBlock.B_content[:]), "\x00"))
-	}
+	return sb.ReadFileContent(diskPath, fileInode)
+}
 
-	return content.String(), nil
+// touchFileAtime actualiza la fecha de último acceso del archivo leído por cat
+func touchFileAtime(id string, sb *structures.SuperBlock, diskPath string, filePath string) error {
+	inodeNum, err := findInodeByPath(diskPath, sb, filePath)
+	if err != nil {
+		return err
+	}
+	inode := &structures.Inode{}
+	if err := inode.Deserialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size)); err != nil {
+		return err
+	}
+	return touchAtime(id, sb, diskPath, inodeNum, inode)
 }
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"os"
@@ -87,10 +86,12 @@ func commandChmod(chmod *CHMOD) error {
 		return errors.New("no hay sesión activa, inicie sesión primero")
 	}
 
-	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	// La ruta puede pasar por carpetas donde se montaron otras particiones con mount -at
+	target, err := resolveSessionPath(chmod.path)
 	if err != nil {
-		return fmt.Errorf("error al obtener la partición montada: %v", err)
+		return err
 	}
+	partitionSuperblock, partitionPath := target.sb, target.diskPath
 
 	file, err := os.OpenFile(partitionPath, os.O_RDWR, 0644)
 	if err != nil {
@@ -99,7 +100,7 @@ func commandChmod(chmod *CHMOD) error {
 	defer file.Close()
 
 	// Normalizar la ruta
-	parentDirs, targetName := utils.GetParentDirectories(chmod.path)
+	parentDirs, targetName := utils.GetParentDirectories(target.path)
 	if targetName == "" {
 		return errors.New("no se puede cambiar permisos de la raíz")
 	}
@@ -206,13 +207,13 @@ func commandChmod(chmod *CHMOD) error {
 	}
 
 	// Registrar en el Journal
-	err = AddJournalEntry(partitionSuperblock, partitionPath, "chmod", chmod.path, chmod.ugo)
+	err = AddJournalEntry(partitionSuperblock, partitionPath, "chmod", target.path, chmod.ugo)
 	if err != nil {
 		return fmt.Errorf("error al registrar en el Journal: %v", err)
 	}
 
 	// Actualizar el superbloque
-	err = partitionSuperblock.Serialize(partitionPath, int64(partitionSuperblock.S_bm_inode_start)-int64(binary.Size(structures.SuperBlock{})))
+	err = partitionSuperblock.Serialize(partitionPath, partitionSuperblock.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
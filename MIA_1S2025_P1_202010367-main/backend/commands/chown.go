@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"os"
@@ -74,11 +73,13 @@ func commandChown(chown *CHOWN) (string, error) {
 		return "", errors.New("permiso denegado: solo root puede ejecutar chown")
 	}
 
-	// Obtener superbloque y ruta del disco
-	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	// Obtener superbloque y ruta del disco; la ruta puede pasar por carpetas donde se
+	// montaron otras particiones con mount -at
+	target, err := resolveSessionPath(chown.path)
 	if err != nil {
-		return "", fmt.Errorf("error al obtener la partición montada: %v", err)
+		return "", err
 	}
+	partitionSuperblock, partitionPath := target.sb, target.diskPath
 
 	// Abrir archivo del disco
 	file, err := os.OpenFile(partitionPath, os.O_RDWR, 0644)
@@ -94,7 +95,7 @@ func commandChown(chown *CHOWN) (string, error) {
 	}
 
 	// Encontrar el inodo de la ruta
-	inodeNum, err := findInodeByPath(partitionPath, partitionSuperblock, chown.path)
+	inodeNum, err := findInodeByPath(partitionPath, partitionSuperblock, target.path)
 	if err != nil {
 		return "", fmt.Errorf("error al localizar la ruta %s: %v", chown.path, err)
 	}
@@ -107,7 +108,7 @@ func commandChown(chown *CHOWN) (string, error) {
 
 	// Registrar en el Journal (si es EXT3)
 	if partitionSuperblock.S_filesystem_type == 3 {
-		err = AddJournalEntry(partitionSuperblock, partitionPath, "chown", chown.path, chown.user)
+		err = AddJournalEntry(partitionSuperblock, partitionPath, "chown", target.path, chown.user)
 		if err != nil {
 			return "", fmt.Errorf("error al registrar en el Journal: %v", err)
 		}
@@ -130,22 +131,13 @@ func getUserUID(diskPath string, sb *structures.SuperBlock, username string) (in
 	}
 
 	// Leer contenido de users.txt
-	var content bytes.Buffer
-	for _, blockNum := range inode.I_block[:12] {
-		if blockNum == -1 {
-			break
-		}
-		fileBlock := &structures.FileBlock{}
-		err := fileBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-		if err != nil {
-			return -1, fmt.Errorf("error al leer bloque %d: %v", blockNum, err)
-		}
-		data := strings.Trim(string(fileBlock.B_content[:]), "\x00")
-		content.WriteString(data)
+	content, err := sb.ReadFileContent(diskPath, inode)
+	if err != nil {
+		return -1, fmt.Errorf("error al leer users.txt: %v", err)
 	}
 
 	// Parsear users.txt
-	lines := strings.Split(content.String(), "\n")
+	lines := strings.Split(string(content), "\n")
 	for _, line := range lines {
 		if line == "" {
 			continue
@@ -0,0 +1,301 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+)
+
+// CLONEDISK estructura que representa el comando clonedisk con sus parámetros
+type CLONEDISK struct {
+	src  string // Ruta del disco origen
+	dest string // Ruta del disco nuevo
+}
+
+// CLONEPART estructura que representa el comando clonepart con sus parámetros
+type CLONEPART struct {
+	src  string // ID de la partición origen
+	dest string // ID de la partición destino; su contenido se reemplaza
+}
+
+/*
+   clonedisk -src=/home/discos/Disco1.mia -dest=/home/discos/Disco2.mia
+   clonepart -src=671A -dest=672B
+*/
+
+func ParseClonedisk(tokens []string) (string, error) {
+	cmd := &CLONEDISK{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key, value := strings.ToLower(parts[0]), strings.Trim(parts[1], "\"")
+
+		switch key {
+		case "-src":
+			cmd.src = value
+		case "-dest":
+			cmd.dest = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.src == "" || cmd.dest == "" {
+		return "", errors.New("faltan parámetros requeridos: -src, -dest")
+	}
+
+	size, err := commandClonedisk(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al clonar el disco: %v", err)
+	}
+	return fmt.Sprintf("CLONEDISK: %s clonado en %s (%d bytes)", cmd.src, cmd.dest, size), nil
+}
+
+func ParseClonepart(tokens []string) (string, error) {
+	cmd := &CLONEPART{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key, value := strings.ToLower(parts[0]), strings.ToUpper(parts[1])
+
+		switch key {
+		case "-src":
+			cmd.src = value
+		case "-dest":
+			cmd.dest = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.src == "" || cmd.dest == "" {
+		return "", errors.New("faltan parámetros requeridos: -src, -dest")
+	}
+
+	inodes, blocks, err := commandClonepart(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al clonar la partición: %v", err)
+	}
+	return fmt.Sprintf("CLONEPART: %s clonada en %s (%d inodos y %d bloques copiados)", cmd.src, cmd.dest, inodes, blocks), nil
+}
+
+// commandClonedisk copia el disco completo, como dd. Las particiones del disco nuevo
+// quedan desmontadas
+func commandClonedisk(clone *CLONEDISK) (int64, error) {
+	if filepath.Clean(clone.src) == filepath.Clean(clone.dest) {
+		return 0, errors.New("el origen y el destino son el mismo disco")
+	}
+	if _, err := os.Stat(clone.dest); err == nil {
+		return 0, fmt.Errorf("el disco %s ya existe", clone.dest)
+	}
+
+	src, err := os.Open(clone.src)
+	if err != nil {
+		return 0, fmt.Errorf("error al abrir %s: %v", clone.src, err)
+	}
+	defer src.Close()
+	if err := os.MkdirAll(filepath.Dir(clone.dest), os.ModePerm); err != nil {
+		return 0, err
+	}
+	dest, err := os.OpenFile(clone.dest, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("error al crear %s: %v", clone.dest, err)
+	}
+	size, err := io.Copy(dest, src)
+	if closeErr := dest.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(clone.dest)
+		return 0, fmt.Errorf("error al copiar %s: %v", clone.src, err)
+	}
+
+	if err := releaseSnapshotHolds(clone.src, clone.dest); err != nil {
+		os.Remove(clone.dest)
+		return 0, err
+	}
+	if err := clearMountState(clone.dest); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// releaseSnapshotHolds quita de las particiones del disco copiado las referencias que los
+// snapshots del origen tienen sobre sus bloques: los snapshots quedan en la carpeta del
+// disco de origen
+func releaseSnapshotHolds(srcPath, destPath string) error {
+	table, err := structures.ReadPartitionTable(destPath)
+	if err != nil {
+		return fmt.Errorf("error al leer la tabla de particiones: %v", err)
+	}
+	for _, partition := range table.UsedPartitions() {
+		snapshots, err := loadSnapshots(snapshotDir(srcPath, partition), partition)
+		if err != nil {
+			return fmt.Errorf("error al leer los snapshots de %s: %v", strings.Trim(string(partition.Part_name[:]), "\x00"), err)
+		}
+		if len(snapshots) == 0 {
+			continue
+		}
+		sb := &structures.SuperBlock{}
+		if err := sb.Deserialize(destPath, int64(partition.Part_start)); err != nil || sb.S_magic != 0xEF53 {
+			continue
+		}
+		for _, snap := range snapshots {
+			if err := sb.ReleaseSnapshot(destPath, snap); err != nil {
+				return err
+			}
+		}
+		if err := sb.Serialize(destPath, sb.Offset()); err != nil {
+			return fmt.Errorf("error al actualizar superbloque: %v", err)
+		}
+	}
+	return nil
+}
+
+// clearMountState marca como desmontadas todas las particiones de un disco y borra sus IDs
+func clearMountState(path string) error {
+	table, err := structures.ReadPartitionTable(path)
+	if err != nil {
+		return fmt.Errorf("error al leer la tabla de particiones: %v", err)
+	}
+	for _, p := range table.Partitions() {
+		if p.Part_status[0] == '1' {
+			p.Part_status = [1]byte{'0'}
+			p.Part_id = [4]byte{}
+		}
+	}
+	if err := table.Write(path); err != nil {
+		return fmt.Errorf("error al escribir la tabla de particiones: %v", err)
+	}
+
+	extPartition := table.ExtendedPartition()
+	if extPartition == nil || extPartition.Part_status[0] == 'N' {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	var ebr structures.EBR
+	offset := int64(extPartition.Part_start)
+	for {
+		if err := ebr.Deserialize(file, offset); err != nil {
+			return fmt.Errorf("error al leer EBR: %v", err)
+		}
+		if ebr.Part_status[0] == '1' {
+			ebr.Part_status = [1]byte{'0'}
+			ebr.Part_id = [4]byte{}
+			if err := ebr.Serialize(file, offset); err != nil {
+				return fmt.Errorf("error al escribir EBR: %v", err)
+			}
+		}
+		if ebr.Part_next == -1 {
+			return nil
+		}
+		offset = int64(ebr.Part_next)
+	}
+}
+
+// commandClonepart copia el sistema de archivos de una partición montada sobre otra. Solo
+// se copian los inodos y bloques usados; si el destino es más grande, el sistema de
+// archivos crece para ocuparlo
+func commandClonepart(clone *CLONEPART) (int32, int32, error) {
+	if clone.src == clone.dest {
+		return 0, 0, errors.New("el origen y el destino son la misma partición")
+	}
+	if clone.dest == stores.CurrentSession.ID {
+		return 0, 0, fmt.Errorf("%s es la partición de la sesión activa; cierre sesión antes de reemplazarla", clone.dest)
+	}
+	for _, id := range []string{clone.src, clone.dest} {
+		if graft, exists := stores.GraftOf(id); exists {
+			return 0, 0, fmt.Errorf("la partición %s está montada en %s:%s; desmóntela primero", id, graft.Host, graft.Path)
+		}
+	}
+	if points := stores.GraftsUnder(clone.dest, "/"); len(points) > 0 {
+		return 0, 0, fmt.Errorf("la partición %s tiene otras particiones montadas en %s", clone.dest, strings.Join(points, ", "))
+	}
+
+	srcPath, srcStart, _, err := mountedPartitionBounds(clone.src)
+	if err != nil {
+		return 0, 0, err
+	}
+	destPath, destStart, destSize, err := mountedPartitionBounds(clone.dest)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	sb := &structures.SuperBlock{}
+	if err := sb.Deserialize(srcPath, srcStart); err != nil || sb.S_magic != 0xEF53 {
+		return 0, 0, fmt.Errorf("la partición %s no está formateada", clone.src)
+	}
+	fs := "2fs"
+	if sb.S_filesystem_type == 3 {
+		fs = "3fs"
+	}
+	n := calculateN(int32(destSize), fs)
+	if n < sb.S_inodes_count {
+		return 0, 0, fmt.Errorf("la partición %s es muy pequeña: admite %d inodos y el origen tiene %d", clone.dest, n, sb.S_inodes_count)
+	}
+	dest := createSuperBlock(destStart, n, fs)
+
+	inodes, blocks, err := sb.CloneTo(srcPath, dest, destPath)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Las referencias que los snapshots del origen tienen sobre sus bloques no pasan a la
+	// copia: los snapshots quedan en la carpeta de la partición de origen
+	if table, err := structures.ReadPartitionTable(srcPath); err == nil {
+		if partition, _ := table.GetPartitionByID(clone.src); partition != nil {
+			snapshots, err := loadSnapshots(snapshotDir(srcPath, partition), partition)
+			if err != nil {
+				return 0, 0, fmt.Errorf("error al leer los snapshots de %s: %v", clone.src, err)
+			}
+			for _, snap := range snapshots {
+				if err := dest.ReleaseSnapshot(destPath, snap); err != nil {
+					return 0, 0, err
+				}
+			}
+		}
+	}
+	if err := dest.Serialize(destPath, dest.Offset()); err != nil {
+		return 0, 0, fmt.Errorf("error al actualizar superbloque: %v", err)
+	}
+
+	if err := AddJournalEntry(dest, destPath, "clonepart", "/", clone.src); err != nil {
+		return 0, 0, fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	return inodes, blocks, nil
+}
+
+// mountedPartitionBounds devuelve el disco, el inicio y el tamaño de una partición primaria
+// montada
+func mountedPartitionBounds(id string) (string, int64, int64, error) {
+	path, exists := stores.MountedPartitions[id]
+	if !exists {
+		return "", 0, 0, fmt.Errorf("la partición %s no está montada", id)
+	}
+	table, err := structures.ReadPartitionTable(path)
+	if err != nil {
+		return "", 0, 0, fmt.Errorf("error al leer la tabla de particiones: %v", err)
+	}
+	partition, err := table.GetPartitionByID(id)
+	if err != nil || partition == nil {
+		return "", 0, 0, fmt.Errorf("la partición %s no es una partición primaria del disco", id)
+	}
+	return path, int64(partition.Part_start), int64(partition.Part_size), nil
+}
@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+)
+
+// COMPACTDISK estructura que representa el comando compactdisk con sus parámetros
+type COMPACTDISK struct {
+	path string // Ruta del disco
+}
+
+/*
+   compactdisk -path=/home/discos/Disco1.mia
+*/
+
+func ParseCompactdisk(tokens []string) (string, error) {
+	cmd := &COMPACTDISK{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		switch key := strings.ToLower(parts[0]); key {
+		case "-path":
+			cmd.path = strings.Trim(parts[1], "\"")
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.path == "" {
+		return "", errors.New("faltan parámetros requeridos: -path")
+	}
+
+	moved, free, err := commandCompactdisk(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al compactar el disco: %v", err)
+	}
+	return fmt.Sprintf("COMPACTDISK: %s compactado (%d particiones movidas, %d bytes libres al final)", cmd.path, moved, free), nil
+}
+
+// commandCompactdisk junta las particiones al inicio del disco, y las lógicas al inicio de
+// la extendida, para que el espacio libre quede en un solo hueco al final. Devuelve las
+// particiones movidas y los bytes libres que quedan al final del disco
+func commandCompactdisk(compact *COMPACTDISK) (int, int64, error) {
+	if _, err := os.Stat(compact.path); os.IsNotExist(err) {
+		return 0, 0, fmt.Errorf("el disco en %s no existe", compact.path)
+	}
+	for id, mountedPath := range stores.MountedPartitions {
+		if mountedPath == compact.path {
+			return 0, 0, fmt.Errorf("el disco en %s tiene una partición montada (ID: %s), desmonte primero", compact.path, id)
+		}
+	}
+
+	file, err := os.OpenFile(compact.path, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	table, err := structures.ReadPartitionTable(compact.path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al leer la tabla de particiones: %v", err)
+	}
+
+	moved := 0
+	next := table.MetadataSize()
+	for _, partition := range table.UsedPartitions() {
+		if table.PrimaryOnly() {
+			next = (next + structures.SectorSize - 1) / structures.SectorSize * structures.SectorSize
+		}
+		if next < int64(partition.Part_start) {
+			if err := relocatePartition(table, compact.path, file, partition, next); err != nil {
+				return 0, 0, fmt.Errorf("error al mover %s: %v", strings.Trim(string(partition.Part_name[:]), "\x00"), err)
+			}
+			moved++
+		}
+		if partition.Part_type[0] == 'E' {
+			count, err := compactLogicalPartitions(file, compact.path, partition)
+			if err != nil {
+				return 0, 0, err
+			}
+			moved += count
+		}
+		next = int64(partition.Part_start) + int64(partition.Part_size)
+	}
+
+	// El MBR del proyecto ubica cada partición nueva después de las entradas ocupadas
+	// anteriores, así que sus entradas deben quedar en el orden del disco
+	if table.MBR != nil {
+		sortMBREntries(table.MBR)
+		if err := table.Write(compact.path); err != nil {
+			return 0, 0, fmt.Errorf("error al escribir la tabla de particiones: %v", err)
+		}
+	}
+	return moved, table.UsableEnd() - next, nil
+}
+
+// compactLogicalPartitions junta las particiones lógicas al inicio de la extendida
+func compactLogicalPartitions(file *os.File, path string, extPartition *structures.Partition) (int, error) {
+	chain, err := readEBRChain(file, extPartition)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	next := int64(extPartition.Part_start)
+	for i := range chain {
+		if next < int64(chain[i].ebr.Part_start) {
+			if err := relocateLogicalPartition(file, path, extPartition, chain, i, next); err != nil {
+				return 0, fmt.Errorf("error al mover %s: %v", strings.Trim(string(chain[i].ebr.Part_name[:]), "\x00"), err)
+			}
+			// Los EBR cambiaron de posición; la cadena se vuelve a leer
+			if chain, err = readEBRChain(file, extPartition); err != nil {
+				return 0, err
+			}
+			moved++
+		}
+		next = int64(chain[i].ebr.Part_start) + int64(chain[i].ebr.Part_size)
+	}
+	return moved, nil
+}
+
+// sortMBREntries deja las entradas ocupadas del MBR primero y ordenadas por su inicio
+func sortMBREntries(mbr *structures.MBR) {
+	entries := mbr.Mbr_partitions[:]
+	sort.SliceStable(entries, func(i, j int) bool {
+		iUsed, jUsed := entries[i].Part_start >= 0 && entries[i].Part_size > 0, entries[j].Part_start >= 0 && entries[j].Part_size > 0
+		if iUsed != jUsed {
+			return iUsed
+		}
+		return iUsed && entries[i].Part_start < entries[j].Part_start
+	})
+}
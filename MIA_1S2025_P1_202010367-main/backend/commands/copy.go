@@ -14,27 +14,37 @@ import (
 )
 
 type COPY struct {
-	path    string
-	destino string
+	path    string // Ruta origen (-path o -src, admite ID:/ruta)
+	destino string // Ruta destino completa (-destino o -dest, admite ID:/ruta)
+	reflink bool   // Opción -reflink (comparte los bloques de datos en lugar de duplicarlos)
 }
 
+/*
+   copy -path=/home/a.txt -destino=/home/b.txt
+   copy -src=671A:/home/user -dest=672A:/backup
+*/
+
 func ParseCopy(tokens []string) (string, error) {
 	cmd := &COPY{}
 
 	for _, token := range tokens {
 		parts := strings.SplitN(token, "=", 2)
+		if len(parts) == 1 && strings.ToLower(parts[0]) == "-reflink" {
+			cmd.reflink = true
+			continue
+		}
 		if len(parts) != 2 {
 			return "", fmt.Errorf("formato de parámetro inválido: %s", token)
 		}
 		key := strings.ToLower(parts[0])
 		value := strings.Trim(parts[1], "\"")
 
-		if key == "-path" {
+		if key == "-path" || key == "-src" {
 			if value == "" {
 				return "", errors.New("la ruta origen no puede estar vacía")
 			}
 			cmd.path = value
-		} else if key == "-destino" {
+		} else if key == "-destino" || key == "-dest" {
 			if value == "" {
 				return "", errors.New("la ruta destino no puede estar vacía")
 			}
@@ -45,7 +55,7 @@ func ParseCopy(tokens []string) (string, error) {
 	}
 
 	if cmd.path == "" || cmd.destino == "" {
-		return "", errors.New("faltan parámetros requeridos: -path, -destino")
+		return "", errors.New("faltan parámetros requeridos: -path (-src), -destino (-dest)")
 	}
 
 	err := commandCopy(cmd)
@@ -61,10 +71,23 @@ func commandCopy(copy *COPY) error {
 		return errors.New("no hay sesión activa, inicie sesión primero")
 	}
 
-	sb, mountedPartition, diskPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	// Las rutas pueden indicar la partición (ID:/ruta) o pasar por carpetas donde se
+	// montaron otras particiones
+	src, err := resolveEndpoint(copy.path)
+	if err != nil {
+		return err
+	}
+	dest, err := resolveEndpoint(copy.destino)
 	if err != nil {
-		return fmt.Errorf("error al obtener la partición montada: %v", err)
+		return err
+	}
+	if src.id != dest.id {
+		if copy.reflink {
+			return errors.New("-reflink solo puede compartir bloques dentro de una misma partición")
+		}
+		return transferAcross(src, dest, false)
 	}
+	sb, mountedPartition, diskPath := dest.sb, dest.partition, dest.diskPath
 
 	file, err := os.OpenFile(diskPath, os.O_RDWR, 0644)
 	if err != nil {
@@ -72,9 +95,14 @@ func commandCopy(copy *COPY) error {
 	}
 	defer file.Close()
 
+	// Liberar espacio de la papelera si quedan pocos bloques libres
+	if _, err := purgeTrash(sb, diskPath); err != nil {
+		return fmt.Errorf("error al purgar la papelera: %v", err)
+	}
+
 	// Normalizar rutas
-	srcParentDirs, srcName := utils.GetParentDirectories(copy.path)
-	destParentDirs, destName := utils.GetParentDirectories(copy.destino)
+	srcParentDirs, srcName := utils.GetParentDirectories(src.path)
+	destParentDirs, destName := utils.GetParentDirectories(dest.path)
 	if srcName == "" || destName == "" {
 		return errors.New("no se puede copiar o escribir en la raíz")
 	}
@@ -132,7 +160,7 @@ func commandCopy(copy *COPY) error {
 	}
 
 	// Copiar el archivo o carpeta
-	_, err = copyInode(sb, diskPath, srcInodeNum, destParentInodeNum, destName)
+	_, err = copyInode(sb, diskPath, srcInodeNum, destParentInodeNum, destName, copy.reflink)
 	if err != nil {
 		return fmt.Errorf("error al copiar %s: %v", copy.path, err)
 	}
@@ -144,7 +172,7 @@ func commandCopy(copy *COPY) error {
 	}
 
 	// Registrar en el Journal
-	err = AddJournalEntry(sb, diskPath, "copy", copy.path, copy.destino)
+	err = AddJournalEntry(sb, diskPath, "copy", src.path, dest.path)
 	if err != nil {
 		return fmt.Errorf("error al registrar en el Journal: %v", err)
 	}
@@ -221,8 +249,10 @@ func findInode(sb *structures.SuperBlock, diskPath string, parentDirs []string,
 	return -1, fmt.Errorf("archivo/carpeta %s no encontrado", name)
 }
 
-// copyInode copia un inodo (archivo o carpeta) y sus bloques al destino
-func copyInode(sb *structures.SuperBlock, diskPath string, srcInodeNum, destParentInodeNum int32, destName string) (int32, error) {
+// copyInode copia un inodo (archivo o carpeta) y sus bloques al destino. Con reflink los
+// archivos comparten los bloques de datos del origen mediante contadores de referencias;
+// las carpetas siempre reciben bloques propios.
+func copyInode(sb *structures.SuperBlock, diskPath string, srcInodeNum, destParentInodeNum int32, destName string, reflink bool) (int32, error) {
 	srcInode := &structures.Inode{}
 	err := srcInode.Deserialize(diskPath, int64(sb.S_inode_start+srcInodeNum*sb.S_inode_size))
 	if err != nil {
@@ -249,10 +279,23 @@ func copyInode(sb *structures.SuperBlock, diskPath string, srcInodeNum, destPare
 		I_atime: float32(time.Now().Unix()),
 		I_ctime: float32(time.Now().Unix()),
 		I_mtime: float32(time.Now().Unix()),
+		I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
 		I_type:  srcInode.I_type,
 		I_perm:  srcInode.I_perm,
 	}
 
+	// Reservar el inodo antes de copiar el contenido: la copia de los hijos busca
+	// inodos libres y se enlaza a esta carpeta leyéndola desde el disco
+	err = newInode.Serialize(diskPath, int64(sb.S_inode_start+newInodeNum*sb.S_inode_size))
+	if err != nil {
+		return -1, fmt.Errorf("error al escribir inodo %d: %v", newInodeNum, err)
+	}
+	err = sb.UpdateBitmapInode(diskPath, newInodeNum)
+	if err != nil {
+		return -1, err
+	}
+	sb.S_free_inodes_count--
+
 	// Vincular al directorio padre
 	destParentInode := &structures.Inode{}
 	err = destParentInode.Deserialize(diskPath, int64(sb.S_inode_start+destParentInodeNum*sb.S_inode_size))
@@ -286,7 +329,14 @@ func copyInode(sb *structures.SuperBlock, diskPath string, srcInodeNum, destPare
 				return -1, fmt.Errorf("error al encontrar bloque libre: %v", err)
 			}
 			destParentInode.I_block[i] = newBlockNum
-			blockToUpdate = &structures.FolderBlock{}
+			blockToUpdate = &structures.FolderBlock{
+				B_content: [4]structures.FolderContent{
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+				},
+			}
 			targetBlockIndex = newBlockNum
 			contentIndex = 0
 
@@ -312,15 +362,27 @@ func copyInode(sb *structures.SuperBlock, diskPath string, srcInodeNum, destPare
 
 	// Copiar contenido según el tipo
 	if srcInode.I_type[0] == '1' { // Archivo
-		for i, blockNum := range srcInode.I_block[:12] {
-			if blockNum == -1 {
-				break
+		srcBlocks, _, err := sb.FileBlocks(diskPath, srcInode)
+		if err != nil {
+			return -1, err
+		}
+		newBlocks := make([]int32, len(srcBlocks))
+		for i, blockNum := range srcBlocks {
+			// Reflink: el bloque queda compartido y se duplica al editar cualquiera de los dos
+			if reflink {
+				err = sb.IncBlockRefs(diskPath, blockNum)
+				if err != nil {
+					return -1, fmt.Errorf("error al compartir bloque %d: %v", blockNum, err)
+				}
+				newBlocks[i] = blockNum
+				continue
 			}
+
 			newBlockNum, err := sb.FindFreeBlock(diskPath)
 			if err != nil {
 				return -1, fmt.Errorf("error al encontrar bloque libre: %v", err)
 			}
-			newInode.I_block[i] = newBlockNum
+			newBlocks[i] = newBlockNum
 
 			srcBlock := &structures.FileBlock{}
 			err = srcBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
@@ -338,6 +400,18 @@ func copyInode(sb *structures.SuperBlock, diskPath string, srcInodeNum, destPare
 			}
 			sb.S_free_blocks_count--
 		}
+
+		// Los bloques de apuntadores nunca se comparten: cada copia tiene los suyos
+		err = sb.SetFileBlocks(diskPath, newInode, newBlocks)
+		if err != nil {
+			return -1, err
+		}
+
+		// Serializar nuevo inodo
+		err = newInode.Serialize(diskPath, int64(sb.S_inode_start+newInodeNum*sb.S_inode_size))
+		if err != nil {
+			return -1, fmt.Errorf("error al escribir inodo %d: %v", newInodeNum, err)
+		}
 	} else { // Carpeta
 		// Crear bloque inicial
 		newBlockNum, err := sb.FindFreeBlock(diskPath)
@@ -363,11 +437,13 @@ func copyInode(sb *structures.SuperBlock, diskPath string, srcInodeNum, destPare
 		}
 		sb.S_free_blocks_count--
 
-		// Copiar contenido de la carpeta recursivamente
-		currentFolderBlocks := []*structures.FolderBlock{newBlock}
-		currentFolderBlockIndex := newBlockNum
-		currentContentIndex := 2 // Después de . y ..
+		// Serializar nuevo inodo
+		err = newInode.Serialize(diskPath, int64(sb.S_inode_start+newInodeNum*sb.S_inode_size))
+		if err != nil {
+			return -1, fmt.Errorf("error al escribir inodo %d: %v", newInodeNum, err)
+		}
 
+		// Copiar contenido de la carpeta recursivamente; cada hijo se enlaza a la nueva carpeta
 		for _, blockNum := range srcInode.I_block[:12] {
 			if blockNum == -1 {
 				continue
@@ -379,84 +455,335 @@ func copyInode(sb *structures.SuperBlock, diskPath string, srcInodeNum, destPare
 			}
 			for _, content := range srcBlock.B_content {
 				name := strings.Trim(string(content.B_name[:]), "\x00")
-				if name == "." || name == ".." || content.B_inodo == -1 {
+				if name == "" || name == "." || name == ".." || content.B_inodo == -1 {
 					continue
 				}
 
-				// Asegurar espacio en el FolderBlock
-				if currentContentIndex >= 4 {
-					// Crear nuevo FolderBlock
-					newBlockNum, err = sb.FindFreeBlock(diskPath)
-					if err != nil {
-						return -1, fmt.Errorf("error al encontrar bloque libre: %v", err)
-					}
-					for i, blockIndex := range newInode.I_block {
-						if blockIndex == -1 && i < 12 {
-							newInode.I_block[i] = newBlockNum
-							break
-						}
-					}
-					newBlock = &structures.FolderBlock{}
-					err = newBlock.Serialize(diskPath, int64(sb.S_block_start+newBlockNum*sb.S_block_size))
-					if err != nil {
-						return -1, fmt.Errorf("error al escribir bloque %d: %v", newBlockNum, err)
-					}
-					err = sb.UpdateBitmapBlock(diskPath, newBlockNum)
-					if err != nil {
-						return -1, err
-					}
-					sb.S_free_blocks_count--
-					currentFolderBlocks = append(currentFolderBlocks, newBlock)
-					currentFolderBlockIndex = newBlockNum
-					currentContentIndex = 0
-
-					// Actualizar inodo
-					err = newInode.Serialize(diskPath, int64(sb.S_inode_start+newInodeNum*sb.S_inode_size))
-					if err != nil {
-						return -1, fmt.Errorf("error al escribir inodo %d: %v", newInodeNum, err)
-					}
-				}
-
 				// Copiar archivo/carpeta hijo
-				newChildInodeNum, err := copyInode(sb, diskPath, content.B_inodo, newInodeNum, name)
+				_, err := copyInode(sb, diskPath, content.B_inodo, newInodeNum, name, reflink)
 				if err != nil {
 					return -1, fmt.Errorf("error al copiar %s: %v", name, err)
 				}
-
-				// Añadir al FolderBlock actual
-				currentFolderBlocks[len(currentFolderBlocks)-1].B_content[currentContentIndex].B_inodo = newChildInodeNum
-				copy(currentFolderBlocks[len(currentFolderBlocks)-1].B_content[currentContentIndex].B_name[:], name)
-				err = currentFolderBlocks[len(currentFolderBlocks)-1].Serialize(diskPath, int64(sb.S_block_start+currentFolderBlockIndex*sb.S_block_size))
-				if err != nil {
-					return -1, fmt.Errorf("error al actualizar bloque %d: %v", currentFolderBlockIndex, err)
-				}
-				currentContentIndex++
 			}
 		}
 	}
 
-	// Serializar nuevo inodo
-	err = newInode.Serialize(diskPath, int64(sb.S_inode_start+newInodeNum*sb.S_inode_size))
+	// Actualizar bloque padre
+	blockToUpdate.B_content[contentIndex].B_inodo = newInodeNum
+	copy(blockToUpdate.B_content[contentIndex].B_name[:], destName)
+	err = blockToUpdate.Serialize(diskPath, int64(sb.S_block_start+targetBlockIndex*sb.S_block_size))
 	if err != nil {
-		return -1, fmt.Errorf("error al escribir inodo %d: %v", newInodeNum, err)
+		return -1, fmt.Errorf("error al actualizar bloque padre %d: %v", targetBlockIndex, err)
 	}
-	err = sb.UpdateBitmapInode(diskPath, newInodeNum)
+
+	return newInodeNum, nil
+}
+
+// transferAcross copia src en dest cuando están en particiones distintas y, si move es
+// true, después elimina el origen. Antes de escribir verifica que la partición destino
+// tenga los inodos y bloques necesarios, para no dejar una copia a medias
+func transferAcross(src, dest *resolvedPath, move bool) error {
+	srcParentDirs, srcName := utils.GetParentDirectories(src.path)
+	destParentDirs, destName := utils.GetParentDirectories(dest.path)
+	if srcName == "" || destName == "" {
+		return errors.New("no se puede copiar o escribir en la raíz")
+	}
+	if len(destName) > 12 {
+		return fmt.Errorf("el nombre %s excede 12 caracteres", destName)
+	}
+
+	// Origen
+	srcParentInodeNum := int32(0)
+	if len(srcParentDirs) > 0 {
+		var err error
+		srcParentInodeNum, err = findInode(src.sb, src.diskPath, srcParentDirs[:len(srcParentDirs)-1], srcParentDirs[len(srcParentDirs)-1])
+		if err != nil {
+			return fmt.Errorf("error al encontrar directorio padre origen %s: %v", strings.Join(srcParentDirs, "/"), err)
+		}
+	}
+	srcInodeNum, err := findInode(src.sb, src.diskPath, srcParentDirs, srcName)
+	if err != nil {
+		return fmt.Errorf("error al encontrar origen %s:%s: %v", src.id, src.path, err)
+	}
+	srcInode := &structures.Inode{}
+	err = srcInode.Deserialize(src.diskPath, int64(src.sb.S_inode_start+srcInodeNum*src.sb.S_inode_size))
+	if err != nil {
+		return fmt.Errorf("error al leer inodo origen %d: %v", srcInodeNum, err)
+	}
+	if !checkReadPermission(srcInode, stores.CurrentSession) {
+		return fmt.Errorf("no tiene permisos de lectura para %s:%s", src.id, src.path)
+	}
+	if move {
+		srcParentInode := &structures.Inode{}
+		err = srcParentInode.Deserialize(src.diskPath, int64(src.sb.S_inode_start+srcParentInodeNum*src.sb.S_inode_size))
+		if err != nil {
+			return fmt.Errorf("error al leer inodo padre origen %d: %v", srcParentInodeNum, err)
+		}
+		if !checkWritePermission(srcParentInode, stores.CurrentSession) {
+			return fmt.Errorf("no tiene permisos de escritura en el directorio padre origen %s", strings.Join(srcParentDirs, "/"))
+		}
+	}
+
+	// Destino: liberar espacio de la papelera y verificar que alcance
+	if _, err := purgeTrash(dest.sb, dest.diskPath); err != nil {
+		return fmt.Errorf("error al purgar la papelera: %v", err)
+	}
+	if checkParentExists(dest.sb, dest.diskPath, append(destParentDirs, destName)) {
+		return fmt.Errorf("ya existe %s en el directorio destino", destName)
+	}
+	inodes, blocks, err := countTree(src.sb, src.diskPath, srcInodeNum)
+	if err != nil {
+		return err
+	}
+	parentInodes, parentBlocks, err := missingParents(dest.sb, dest.diskPath, destParentDirs)
+	if err != nil {
+		return err
+	}
+	inodes += parentInodes
+	blocks += parentBlocks
+	if inodes > dest.sb.S_free_inodes_count || blocks > dest.sb.S_free_blocks_count {
+		return fmt.Errorf("la partición %s no tiene espacio suficiente: se necesitan %d inodos y %d bloques, hay %d inodos y %d bloques libres",
+			dest.id, inodes, blocks, dest.sb.S_free_inodes_count, dest.sb.S_free_blocks_count)
+	}
+
+	if len(destParentDirs) > 0 {
+		if err := createParentFolders(dest.sb, dest.diskPath, destParentDirs); err != nil {
+			return fmt.Errorf("error al crear directorios padres para %s: %v", dest.path, err)
+		}
+	}
+	destParentInodeNum := int32(0)
+	if len(destParentDirs) > 0 {
+		destParentInodeNum, err = findInode(dest.sb, dest.diskPath, destParentDirs[:len(destParentDirs)-1], destParentDirs[len(destParentDirs)-1])
+		if err != nil {
+			return fmt.Errorf("error al encontrar directorio padre destino %s: %v", strings.Join(destParentDirs, "/"), err)
+		}
+	}
+	destParentInode := &structures.Inode{}
+	err = destParentInode.Deserialize(dest.diskPath, int64(dest.sb.S_inode_start+destParentInodeNum*dest.sb.S_inode_size))
+	if err != nil {
+		return fmt.Errorf("error al leer inodo padre destino %d: %v", destParentInodeNum, err)
+	}
+	if !checkWritePermission(destParentInode, stores.CurrentSession) {
+		return fmt.Errorf("no tiene permisos de escritura en el directorio destino %s", strings.Join(destParentDirs, "/"))
+	}
+
+	if _, err := copyAcross(src, dest, srcInodeNum, destParentInodeNum, destName); err != nil {
+		return fmt.Errorf("error al copiar %s:%s: %v", src.id, src.path, err)
+	}
+	if err := dest.sb.Serialize(dest.diskPath, dest.sb.Offset()); err != nil {
+		return fmt.Errorf("error al serializar superbloque de %s: %v", dest.id, err)
+	}
+
+	operation := "copy"
+	if move {
+		operation = "move"
+	}
+	err = AddJournalEntry(dest.sb, dest.diskPath, operation, dest.path, src.id+":"+src.path)
+	if err != nil {
+		return fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	if !move {
+		return nil
+	}
+
+	// Mover: eliminar el origen una vez que la copia quedó completa
+	if err := deleteInode(src.sb, src.diskPath, srcInodeNum, srcName); err != nil {
+		return fmt.Errorf("error al eliminar el origen %s:%s: %v", src.id, src.path, err)
+	}
+	if err := unlinkEntry(src.sb, src.diskPath, srcParentInodeNum, srcName, srcInodeNum); err != nil {
+		return err
+	}
+	if err := src.sb.Serialize(src.diskPath, src.sb.Offset()); err != nil {
+		return fmt.Errorf("error al serializar superbloque de %s: %v", src.id, err)
+	}
+	err = AddJournalEntry(src.sb, src.diskPath, "move", src.path, dest.id+":"+dest.path)
+	if err != nil {
+		return fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	return nil
+}
+
+// copyAcross copia el inodo srcInodeNum de la partición src, con todo su contenido, a la
+// carpeta destParentInodeNum de la partición dest. A diferencia de copyInode conserva el
+// propietario, los permisos y las fechas del origen
+func copyAcross(src, dest *resolvedPath, srcInodeNum, destParentInodeNum int32, destName string) (int32, error) {
+	srcInode := &structures.Inode{}
+	err := srcInode.Deserialize(src.diskPath, int64(src.sb.S_inode_start+srcInodeNum*src.sb.S_inode_size))
+	if err != nil {
+		return -1, fmt.Errorf("error al leer inodo origen %d: %v", srcInodeNum, err)
+	}
+
+	sb, diskPath := dest.sb, dest.diskPath
+	newInodeNum, err := sb.FindFreeInode(diskPath)
 	if err != nil {
+		return -1, fmt.Errorf("error al encontrar inodo libre: %v", err)
+	}
+	newInode := &structures.Inode{
+		I_uid:   srcInode.I_uid,
+		I_gid:   srcInode.I_gid,
+		I_atime: srcInode.I_atime,
+		I_ctime: srcInode.I_ctime,
+		I_mtime: srcInode.I_mtime,
+		I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_type:  srcInode.I_type,
+		I_perm:  srcInode.I_perm,
+	}
+	offset := int64(sb.S_inode_start + newInodeNum*sb.S_inode_size)
+	if err := newInode.Serialize(diskPath, offset); err != nil {
+		return -1, fmt.Errorf("error al escribir inodo %d: %v", newInodeNum, err)
+	}
+	if err := sb.UpdateBitmapInode(diskPath, newInodeNum); err != nil {
 		return -1, err
 	}
 	sb.S_free_inodes_count--
 
-	// Actualizar bloque padre
-	blockToUpdate.B_content[contentIndex].B_inodo = newInodeNum
-	copy(blockToUpdate.B_content[contentIndex].B_name[:], destName)
-	err = blockToUpdate.Serialize(diskPath, int64(sb.S_block_start+targetBlockIndex*sb.S_block_size))
-	if err != nil {
-		return -1, fmt.Errorf("error al actualizar bloque padre %d: %v", targetBlockIndex, err)
+	if srcInode.I_type[0] == '1' { // Archivo
+		content, err := src.sb.ReadFileContent(src.diskPath, srcInode)
+		if err != nil {
+			return -1, err
+		}
+		if err := sb.WriteFileContent(diskPath, newInode, content); err != nil {
+			return -1, err
+		}
+	} else { // Carpeta
+		newBlockNum, err := sb.FindFreeBlock(diskPath)
+		if err != nil {
+			return -1, fmt.Errorf("error al encontrar bloque libre: %v", err)
+		}
+		newInode.I_block[0] = newBlockNum
+		newBlock := &structures.FolderBlock{
+			B_content: [4]structures.FolderContent{
+				{B_name: [12]byte{'.'}, B_inodo: newInodeNum},
+				{B_name: [12]byte{'.', '.'}, B_inodo: destParentInodeNum},
+				{B_name: [12]byte{'-'}, B_inodo: -1},
+				{B_name: [12]byte{'-'}, B_inodo: -1},
+			},
+		}
+		if err := newBlock.Serialize(diskPath, int64(sb.S_block_start+newBlockNum*sb.S_block_size)); err != nil {
+			return -1, fmt.Errorf("error al escribir bloque %d: %v", newBlockNum, err)
+		}
+		if err := sb.UpdateBitmapBlock(diskPath, newBlockNum); err != nil {
+			return -1, err
+		}
+		sb.S_free_blocks_count--
+		if err := newInode.Serialize(diskPath, offset); err != nil {
+			return -1, fmt.Errorf("error al escribir inodo %d: %v", newInodeNum, err)
+		}
+
+		for _, blockNum := range srcInode.I_block[:12] {
+			if blockNum == -1 {
+				continue
+			}
+			srcBlock := &structures.FolderBlock{}
+			err = srcBlock.Deserialize(src.diskPath, int64(src.sb.S_block_start+blockNum*src.sb.S_block_size))
+			if err != nil {
+				return -1, fmt.Errorf("error al leer bloque origen %d: %v", blockNum, err)
+			}
+			for _, content := range srcBlock.B_content {
+				name := strings.Trim(string(content.B_name[:]), "\x00")
+				if name == "" || name == "." || name == ".." || content.B_inodo == -1 {
+					continue
+				}
+				if _, err := copyAcross(src, dest, content.B_inodo, newInodeNum, name); err != nil {
+					return -1, fmt.Errorf("error al copiar %s: %v", name, err)
+				}
+			}
+		}
+
+		// Enlazar los hijos actualiza la carpeta; se recupera su fecha de modificación
+		if err := newInode.Deserialize(diskPath, offset); err != nil {
+			return -1, fmt.Errorf("error al leer inodo %d: %v", newInodeNum, err)
+		}
+		newInode.I_mtime = srcInode.I_mtime
 	}
 
+	if err := newInode.Serialize(diskPath, offset); err != nil {
+		return -1, fmt.Errorf("error al escribir inodo %d: %v", newInodeNum, err)
+	}
+	if err := sb.LinkInode(diskPath, destParentInodeNum, destName, newInodeNum); err != nil {
+		return -1, fmt.Errorf("error al enlazar %s: %v", destName, err)
+	}
 	return newInodeNum, nil
 }
 
+// countTree cuenta los inodos y bloques que ocupará una copia del inodo inodeNum y todo su
+// contenido: bloques de datos y de apuntadores de cada archivo y bloques de cada carpeta
+func countTree(sb *structures.SuperBlock, diskPath string, inodeNum int32) (int32, int32, error) {
+	inode := &structures.Inode{}
+	err := inode.Deserialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size))
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al leer inodo %d: %v", inodeNum, err)
+	}
+	if inode.I_type[0] == '1' {
+		dataBlocks := int((inode.I_size + sb.S_block_size - 1) / sb.S_block_size)
+		return 1, int32(dataBlocks) + structures.PointerBlocksFor(dataBlocks), nil
+	}
+
+	inodes, blocks, children := int32(1), int32(0), int32(0)
+	for _, blockNum := range inode.I_block[:12] {
+		if blockNum == -1 {
+			continue
+		}
+		folderBlock := &structures.FolderBlock{}
+		err = folderBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
+		if err != nil {
+			return 0, 0, fmt.Errorf("error al leer bloque %d: %v", blockNum, err)
+		}
+		for _, content := range folderBlock.B_content {
+			name := strings.Trim(string(content.B_name[:]), "\x00")
+			if name == "" || name == "." || name == ".." || content.B_inodo == -1 {
+				continue
+			}
+			childInodes, childBlocks, err := countTree(sb, diskPath, content.B_inodo)
+			if err != nil {
+				return 0, 0, err
+			}
+			inodes += childInodes
+			blocks += childBlocks
+			children++
+		}
+	}
+	// El primer bloque guarda "." y ".." y dos entradas; los siguientes, cuatro
+	return inodes, blocks + 1 + (max(children-2, 0)+3)/4, nil
+}
+
+// missingParents cuenta los inodos y bloques que se usarán al crear las carpetas de dirs
+// que no existen y al enlazar la primera de ellas, o el destino, a la última existente
+func missingParents(sb *structures.SuperBlock, diskPath string, dirs []string) (int32, int32, error) {
+	parentInodeNum := int32(0)
+	existing := 0
+	for ; existing < len(dirs); existing++ {
+		inodeNum, err := findInode(sb, diskPath, dirs[:existing], dirs[existing])
+		if err != nil {
+			break
+		}
+		parentInodeNum = inodeNum
+	}
+	missing := int32(len(dirs) - existing)
+
+	parent := &structures.Inode{}
+	err := parent.Deserialize(diskPath, int64(sb.S_inode_start+parentInodeNum*sb.S_inode_size))
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al leer inodo %d: %v", parentInodeNum, err)
+	}
+	for _, blockNum := range parent.I_block[:12] {
+		if blockNum == -1 {
+			break
+		}
+		folderBlock := &structures.FolderBlock{}
+		err = folderBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
+		if err != nil {
+			return 0, 0, fmt.Errorf("error al leer bloque %d: %v", blockNum, err)
+		}
+		for _, content := range folderBlock.B_content {
+			if content.B_inodo == -1 {
+				return missing, missing, nil
+			}
+		}
+	}
+	return missing, missing + 1, nil
+}
+
 // checkReadPermission verifica si el usuario tiene permisos de lectura
 func checkReadPermission(inode *structures.Inode, session stores.Session) bool {
 	permStr := string(inode.I_perm[:])
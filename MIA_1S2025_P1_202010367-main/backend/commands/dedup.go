@@ -0,0 +1,170 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+)
+
+// DEDUP estructura que representa el comando dedup con sus parámetros
+type DEDUP struct {
+	id string // ID de la partición
+}
+
+/*
+   dedup -id=671A
+*/
+
+func ParseDedup(tokens []string) (string, error) {
+	cmd := &DEDUP{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key := strings.ToLower(parts[0])
+
+		if key == "-id" {
+			cmd.id = strings.ToUpper(parts[1])
+		} else {
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.id == "" {
+		return "", errors.New("faltan parámetros requeridos: -id")
+	}
+
+	merged, reclaimed, err := commandDedup(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al deduplicar: %v", err)
+	}
+	return fmt.Sprintf("DEDUP: %d bloques fusionados en la partición %s, %d bytes recuperados", merged, cmd.id, reclaimed), nil
+}
+
+// commandDedup fusiona los bloques de archivo con contenido idéntico en un único bloque
+// compartido. Devuelve los bloques fusionados y los bytes que quedaron libres.
+func commandDedup(dedup *DEDUP) (int, int32, error) {
+	if stores.CurrentSession.ID == "" {
+		return 0, 0, errors.New("no hay sesión activa, inicie sesión primero")
+	}
+	if stores.CurrentSession.Username != "root" {
+		return 0, 0, errors.New("solo el usuario root puede deduplicar una partición")
+	}
+
+	sb, _, diskPath, err := stores.GetMountedPartitionSuperblock(dedup.id)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al obtener la partición montada: %v", err)
+	}
+	if sb.S_magic != 0xEF53 {
+		return 0, 0, fmt.Errorf("la partición %s no está formateada", dedup.id)
+	}
+
+	inodes, err := sb.ReadInodeTable(diskPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al leer tabla de inodos: %v", err)
+	}
+	inodeBitmap, err := sb.ReadInodeBitmap(diskPath)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+
+	// Bloque canónico por contenido; se compara el contenido completo para no depender
+	// solo del hash
+	canonical := make(map[[32]byte]int32)
+	contents := make(map[int32][64]byte)
+	merged := 0
+	freed := int32(0)
+
+	for i := range inodes {
+		inode := &inodes[i]
+		if inodeBitmap[i] != '1' || inode.I_type[0] != '1' {
+			continue
+		}
+
+		// Se recorren todos los bloques de datos, también los de los apuntadores indirectos
+		data, _, err := sb.FileBlocks(diskPath, inode)
+		if err != nil {
+			return merged, freed * sb.S_block_size, fmt.Errorf("error al leer bloques del inodo %d: %v", i, err)
+		}
+		changed := false
+		for j, blockNum := range data {
+			content, ok := contents[blockNum]
+			if !ok {
+				fileBlock := &structures.FileBlock{}
+				err := fileBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
+				if err != nil {
+					return merged, freed * sb.S_block_size, fmt.Errorf("error al leer bloque %d: %v", blockNum, err)
+				}
+				content = fileBlock.B_content
+				contents[blockNum] = content
+			}
+
+			hash := sha256.Sum256(content[:])
+			target, exists := canonical[hash]
+			if !exists {
+				canonical[hash] = blockNum
+				continue
+			}
+			if target == blockNum || contents[target] != content {
+				continue
+			}
+
+			// Si el bloque canónico ya no admite más referencias, este pasa a serlo
+			refs, err := sb.GetBlockRefs(diskPath, target)
+			if err != nil {
+				return merged, freed * sb.S_block_size, err
+			}
+			if refs >= structures.MaxBlockRefs {
+				canonical[hash] = blockNum
+				continue
+			}
+
+			// Apuntar al bloque canónico y soltar la referencia al duplicado
+			if err := sb.IncBlockRefs(diskPath, target); err != nil {
+				return merged, freed * sb.S_block_size, fmt.Errorf("error al compartir bloque %d: %v", target, err)
+			}
+			wasFreed, err := sb.ReleaseBlock(diskPath, blockNum)
+			if err != nil {
+				return merged, freed * sb.S_block_size, fmt.Errorf("error al liberar bloque %d: %v", blockNum, err)
+			}
+			if wasFreed {
+				freed++
+			}
+			data[j] = target
+			merged++
+			changed = true
+		}
+
+		if changed {
+			// Los bloques de apuntadores se reescriben con los bloques fusionados; uno
+			// compartido con un snapshot no se modifica en su lugar
+			if err := sb.SetFileBlocks(diskPath, inode, data); err != nil {
+				return merged, freed * sb.S_block_size, fmt.Errorf("error al actualizar bloques del inodo %d: %v", i, err)
+			}
+			err := inode.Serialize(diskPath, int64(sb.S_inode_start+int32(i)*sb.S_inode_size))
+			if err != nil {
+				return merged, freed * sb.S_block_size, fmt.Errorf("error al actualizar inodo %d: %v", i, err)
+			}
+		}
+	}
+
+	// Actualizar el superbloque
+	err = sb.Serialize(diskPath, sb.Offset())
+	if err != nil {
+		return merged, freed * sb.S_block_size, fmt.Errorf("error al actualizar superbloque: %v", err)
+	}
+
+	// Registrar en el Journal
+	err = AddJournalEntry(sb, diskPath, "dedup", "/", fmt.Sprintf("%d bloques", merged))
+	if err != nil {
+		return merged, freed * sb.S_block_size, fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+
+	return merged, freed * sb.S_block_size, nil
+}
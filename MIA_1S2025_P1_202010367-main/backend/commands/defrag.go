@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+)
+
+// defragStarted es el contenido de la entrada del Journal que abre una desfragmentación;
+// al terminar se registra otra con el resultado
+const defragStarted = "inicio"
+
+// DEFRAG estructura que representa el comando defrag con sus parámetros
+type DEFRAG struct {
+	id   string // ID de la partición
+	path string // Archivo o carpeta a desfragmentar (por defecto, toda la partición)
+}
+
+/*
+   defrag -id=671A
+   defrag -id=671A -path=/home/user
+*/
+
+func ParseDefrag(tokens []string) (string, error) {
+	cmd := &DEFRAG{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		switch key := strings.ToLower(parts[0]); key {
+		case "-id":
+			cmd.id = strings.ToUpper(parts[1])
+		case "-path":
+			cmd.path = strings.Trim(parts[1], "\"")
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.id == "" {
+		return "", errors.New("faltan parámetros requeridos: -id")
+	}
+	if cmd.path == "" {
+		cmd.path = "/"
+	}
+
+	if stores.CurrentSession.ID == "" {
+		return "", errors.New("error al desfragmentar: no hay sesión activa, inicie sesión primero")
+	}
+	if stores.CurrentSession.Username != "root" {
+		return "", errors.New("error al desfragmentar: solo el usuario root puede desfragmentar una partición")
+	}
+
+	result, reclaimed, err := commandDefrag(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al desfragmentar: %v", err)
+	}
+
+	var output strings.Builder
+	if reclaimed > 0 {
+		fmt.Fprintf(&output, "DEFRAG: se liberaron %d bloques reservados por una desfragmentación interrumpida\n", reclaimed)
+	}
+	fmt.Fprintf(&output, "DEFRAG: %s en %s, %d inodos revisados, %d movidos (%d bloques)",
+		cmd.path, cmd.id, result.Inodes, result.Moved, result.Blocks)
+	if result.Shared > 0 {
+		fmt.Fprintf(&output, ", %d con bloques compartidos", result.Shared)
+	}
+	if result.Full > 0 {
+		fmt.Fprintf(&output, ", %d sin bloques libres seguidos suficientes", result.Full)
+	}
+	fmt.Fprintf(&output, "; fragmentación %.1f%% -> %.1f%%", result.Before, result.After)
+	return output.String(), nil
+}
+
+// commandDefrag desfragmenta el archivo o el árbol de la ruta indicada. En EXT3 la
+// desfragmentación queda registrada en el Journal antes de empezar: si la anterior no
+// llegó a registrar su final, primero se liberan los bloques que dejó reservados. Devuelve
+// el resultado y los bloques recuperados de la desfragmentación interrumpida
+func commandDefrag(defrag *DEFRAG) (*structures.DefragResult, int32, error) {
+	sb, partition, diskPath, err := stores.GetMountedPartitionSuperblock(defrag.id)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error al obtener la partición montada: %v", err)
+	}
+	if sb.S_magic != 0xEF53 {
+		return nil, 0, fmt.Errorf("la partición %s no está formateada", defrag.id)
+	}
+
+	rootInodeNum, err := findInodeByPath(diskPath, sb, defrag.path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("la ruta %s no existe: %v", defrag.path, err)
+	}
+	var inodeNums []int32
+	if err := defragTree(sb, diskPath, rootInodeNum, make(map[int32]bool), &inodeNums); err != nil {
+		return nil, 0, err
+	}
+
+	reclaimed := int32(0)
+	if sb.S_filesystem_type == 3 {
+		interrupted, err := defragInterrupted(sb, diskPath)
+		if err != nil {
+			return nil, 0, err
+		}
+		if interrupted {
+			snapshots, err := loadSnapshots(snapshotDir(diskPath, partition), partition)
+			if err != nil {
+				return nil, 0, fmt.Errorf("error al leer los snapshots: %v", err)
+			}
+			var held []int32
+			for _, snap := range snapshots {
+				held = append(held, snap.SharedBlocks...)
+			}
+			if reclaimed, err = sb.ReclaimLeakedBlocks(diskPath, held); err != nil {
+				return nil, 0, fmt.Errorf("error al liberar los bloques de la desfragmentación interrumpida: %v", err)
+			}
+		}
+		if err := AddJournalEntry(sb, diskPath, "defrag", defrag.path, defragStarted); err != nil {
+			return nil, reclaimed, fmt.Errorf("error al registrar en el Journal: %v", err)
+		}
+	}
+
+	result, err := sb.Defrag(diskPath, inodeNums)
+	if err != nil {
+		return nil, reclaimed, err
+	}
+
+	err = AddJournalEntry(sb, diskPath, "defrag", defrag.path,
+		fmt.Sprintf("%d bloques, %.1f%% -> %.1f%%", result.Blocks, result.Before, result.After))
+	if err != nil {
+		return nil, reclaimed, fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	return result, reclaimed, nil
+}
+
+// defragTree agrega a inodeNums el inodo inodeNum y, si es una carpeta, todo su contenido.
+// Un inodo enlazado desde varias carpetas se agrega una sola vez
+func defragTree(sb *structures.SuperBlock, diskPath string, inodeNum int32, visited map[int32]bool, inodeNums *[]int32) error {
+	if visited[inodeNum] {
+		return nil
+	}
+	visited[inodeNum] = true
+	*inodeNums = append(*inodeNums, inodeNum)
+
+	inode := &structures.Inode{}
+	err := inode.Deserialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size))
+	if err != nil {
+		return fmt.Errorf("error al leer inodo %d: %v", inodeNum, err)
+	}
+	if inode.I_type[0] != '0' {
+		return nil
+	}
+
+	for _, blockNum := range inode.I_block[:12] {
+		if blockNum == -1 {
+			continue
+		}
+		folderBlock := &structures.FolderBlock{}
+		err := folderBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
+		if err != nil {
+			return fmt.Errorf("error al leer bloque %d: %v", blockNum, err)
+		}
+		for _, content := range folderBlock.B_content {
+			name := strings.Trim(string(content.B_name[:]), "\x00")
+			if name == "" || name == "." || name == ".." || content.B_inodo == -1 {
+				continue
+			}
+			if err := defragTree(sb, diskPath, content.B_inodo, visited, inodeNums); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// defragInterrupted indica si la última desfragmentación registrada en el Journal no
+// registró su final
+func defragInterrupted(sb *structures.SuperBlock, diskPath string) (bool, error) {
+	interrupted := false
+	for i := int32(0); i < sb.S_journal_count; i++ {
+		entry := &structures.Journal{}
+		offset := int64(sb.S_journal_start) + int64(i*int32(binary.Size(entry)))
+		if err := entry.Deserialize(diskPath, offset); err != nil {
+			return false, fmt.Errorf("error al deserializar entrada %d: %v", i, err)
+		}
+		operation := strings.Trim(string(entry.Content.Operation[:]), "\x00")
+		if entry.Count == 0 || operation == "" {
+			break
+		}
+		if operation == "defrag" {
+			interrupted = strings.Trim(string(entry.Content.Content[:]), "\x00") == defragStarted
+		}
+	}
+	return interrupted, nil
+}
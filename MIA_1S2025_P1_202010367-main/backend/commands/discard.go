@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+)
+
+// DISCARD estructura que representa el comando discard con sus parámetros
+type DISCARD struct {
+	id string // ID de la partición
+}
+
+/*
+   discard -id=671A
+*/
+
+func ParseDiscard(tokens []string) (string, error) {
+	cmd := &DISCARD{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key := strings.ToLower(parts[0])
+
+		if key == "-id" {
+			cmd.id = strings.ToUpper(parts[1])
+		} else {
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.id == "" {
+		return "", errors.New("faltan parámetros requeridos: -id")
+	}
+
+	if stores.CurrentSession.ID == "" {
+		return "", errors.New("error al descartar bloques: no hay sesión activa, inicie sesión primero")
+	}
+	if stores.CurrentSession.Username != "root" {
+		return "", errors.New("error al descartar bloques: solo el usuario root puede descartar los bloques libres")
+	}
+	blocks, size, err := discardPartition(cmd.id)
+	if err != nil {
+		return "", fmt.Errorf("error al descartar bloques: %v", err)
+	}
+	return fmt.Sprintf("DISCARD: %d bloques libres descartados en la partición %s (%d bytes)", blocks, cmd.id, size), nil
+}
+
+// discardPartition descarta los bloques libres de una partición montada y devuelve la
+// cantidad de bloques y de bytes descartados
+func discardPartition(id string) (int32, int64, error) {
+	sb, _, diskPath, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al obtener la partición montada: %v", err)
+	}
+	if sb.S_magic != 0xEF53 {
+		return 0, 0, fmt.Errorf("la partición %s no está formateada", id)
+	}
+	blocks, err := sb.DiscardFreeBlocks(diskPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	return blocks, int64(blocks) * int64(sb.S_block_size), nil
+}
+
+// DiscardMounted descarta los bloques que el comando liberó en las particiones indicadas
+// que están montadas con la opción discard. Se llama después de cada comando que las
+// modifica
+func DiscardMounted(ids []string) error {
+	for _, id := range ids {
+		if _, mounted := stores.MountedPartitions[id]; !mounted || !stores.GetMountOptions(id).Discard {
+			continue
+		}
+		sb, _, diskPath, err := stores.GetMountedPartitionSuperblock(id)
+		if err != nil || sb.S_magic != 0xEF53 {
+			continue
+		}
+		if _, err := sb.DiscardReleasedBlocks(diskPath); err != nil {
+			return fmt.Errorf("error al descartar los bloques liberados de %s: %v", id, err)
+		}
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"os"
@@ -58,10 +57,12 @@ func commandEdit(edit *EDIT) error {
 		return errors.New("no hay sesión activa, inicie sesión primero")
 	}
 
-	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	// La ruta puede pasar por carpetas donde se montaron otras particiones con mount -at
+	target, err := resolveSessionPath(edit.path)
 	if err != nil {
-		return fmt.Errorf("error al obtener la partición montada: %v", err)
+		return err
 	}
+	partitionSuperblock, partitionPath := target.sb, target.diskPath
 
 	file, err := os.OpenFile(partitionPath, os.O_RDWR, 0644)
 	if err != nil {
@@ -69,8 +70,13 @@ func commandEdit(edit *EDIT) error {
 	}
 	defer file.Close()
 
+	// Liberar espacio de la papelera si quedan pocos bloques libres
+	if _, err := purgeTrash(partitionSuperblock, partitionPath); err != nil {
+		return fmt.Errorf("error al purgar la papelera: %v", err)
+	}
+
 	// Normalizar la ruta
-	parentDirs, destFile := utils.GetParentDirectories(edit.path)
+	parentDirs, destFile := utils.GetParentDirectories(target.path)
 	if destFile == "" {
 		return errors.New("la ruta no puede ser la raíz")
 	}
@@ -176,64 +182,13 @@ func commandEdit(edit *EDIT) error {
 		return fmt.Errorf("no tiene permisos de escritura para %s", edit.path)
 	}
 
-	// Dividir el contenido en bloques de 64 bytes
-	chunks := utils.SplitStringIntoChunks(edit.cont)
-	newBlocksNeeded := len(chunks)
-	currentBlocks := 0
-	for _, blockNum := range targetInode.I_block[:12] {
-		if blockNum == -1 {
-			break
-		}
-		currentBlocks++
-	}
-
-	// Verificar si hay suficientes bloques libres
-	if newBlocksNeeded > currentBlocks {
-		availableBlocks := int(partitionSuperblock.S_free_blocks_count)
-		if newBlocksNeeded-currentBlocks > availableBlocks {
-			return fmt.Errorf("no hay suficientes bloques libres para almacenar el contenido")
-		}
-	}
-
-	// Liberar bloques actuales si son más de los necesarios
-	for i := newBlocksNeeded; i < currentBlocks; i++ {
-		blockNum := targetInode.I_block[i]
-		if blockNum != -1 {
-			err = partitionSuperblock.UpdateBitmapBlock(partitionPath, blockNum)
-			if err != nil {
-				return fmt.Errorf("error al liberar bloque %d: %v", blockNum, err)
-			}
-			partitionSuperblock.S_free_blocks_count++
-			targetInode.I_block[i] = -1
-		}
-	}
-
-	// Asignar nuevos bloques si son necesarios
-	for i := currentBlocks; i < newBlocksNeeded; i++ {
-		newBlockNum, err := partitionSuperblock.FindFreeBlock(partitionPath)
-		if err != nil {
-			return fmt.Errorf("error al encontrar bloque libre: %v", err)
-		}
-		err = partitionSuperblock.UpdateBitmapBlock(partitionPath, newBlockNum)
-		if err != nil {
-			return fmt.Errorf("error al marcar bloque %d: %v", newBlockNum, err)
-		}
-		partitionSuperblock.S_free_blocks_count--
-		targetInode.I_block[i] = newBlockNum
-	}
-
-	// Escribir el nuevo contenido
-	for i, chunk := range chunks {
-		fileBlock := &structures.FileBlock{}
-		copy(fileBlock.B_content[:], chunk)
-		err = fileBlock.Serialize(partitionPath, int64(partitionSuperblock.S_block_start+targetInode.I_block[i]*partitionSuperblock.S_block_size))
-		if err != nil {
-			return fmt.Errorf("error al escribir bloque %d: %v", targetInode.I_block[i], err)
-		}
+	// Escribir el nuevo contenido; los bloques compartidos se copian antes de modificarlos
+	err = partitionSuperblock.WriteFileContent(partitionPath, targetInode, []byte(edit.cont))
+	if err != nil {
+		return err
 	}
 
 	// Actualizar el inodo
-	targetInode.I_size = int32(len(edit.cont))
 	targetInode.I_mtime = float32(time.Now().Unix())
 	err = targetInode.Serialize(partitionPath, int64(partitionSuperblock.S_inode_start+targetInodeNum*partitionSuperblock.S_inode_size))
 	if err != nil {
@@ -245,13 +200,13 @@ func commandEdit(edit *EDIT) error {
 	if len(truncatedCont) > 64 {
 		truncatedCont = truncatedCont[:64]
 	}
-	err = AddJournalEntry(partitionSuperblock, partitionPath, "edit", edit.path, truncatedCont)
+	err = AddJournalEntry(partitionSuperblock, partitionPath, "edit", target.path, truncatedCont)
 	if err != nil {
 		return fmt.Errorf("error al registrar en el Journal: %v", err)
 	}
 
 	// Actualizar el superbloque
-	err = partitionSuperblock.Serialize(partitionPath, int64(partitionSuperblock.S_bm_inode_start)-int64(binary.Size(structures.SuperBlock{})))
+	err = partitionSuperblock.Serialize(partitionPath, partitionSuperblock.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
@@ -0,0 +1,272 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+	utils "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/utils"
+)
+
+// EXPORT estructura que representa el comando export con sus parámetros
+type EXPORT struct {
+	id     string // ID de la partición
+	format string // Formato de salida (ext2)
+	out    string // Ruta del archivo de salida en el anfitrión
+	tar    string // Ruta del archivo tar de salida en el anfitrión
+	path   string // Carpeta o archivo a exportar al tar
+}
+
+/*
+   export -id=671A -format=ext2 -out=/home/user/particion.img
+   export -id=671A -path=/home -tar=/home/user/home.tar
+*/
+
+func ParseExport(tokens []string) (string, error) {
+	cmd := &EXPORT{path: "/"}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key, value := strings.ToLower(parts[0]), strings.Trim(parts[1], "\"")
+
+		switch key {
+		case "-id":
+			cmd.id = strings.ToUpper(value)
+		case "-format":
+			value = strings.ToLower(value)
+			if value != "ext2" {
+				return "", errors.New("el formato debe ser ext2")
+			}
+			cmd.format = value
+		case "-out":
+			cmd.out = value
+		case "-tar":
+			cmd.tar = value
+		case "-path":
+			if !strings.HasPrefix(value, "/") {
+				return "", errors.New("la ruta debe ser absoluta")
+			}
+			cmd.path = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.tar != "" {
+		if cmd.format != "" || cmd.out != "" {
+			return "", errors.New("-tar no se puede combinar con -format ni -out")
+		}
+	} else {
+		if cmd.id == "" || cmd.format == "" || cmd.out == "" {
+			return "", errors.New("faltan parámetros requeridos: -id, -format, -out (o -id, -tar)")
+		}
+		if cmd.path != "/" {
+			return "", errors.New("-path solo se admite con -tar")
+		}
+	}
+	if cmd.id == "" {
+		return "", errors.New("faltan parámetros requeridos: -id")
+	}
+
+	count, err := commandExport(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al exportar: %v", err)
+	}
+	if cmd.tar != "" {
+		return fmt.Sprintf("EXPORT: %s:%s exportado a %s (tar, %d inodos)", cmd.id, cmd.path, cmd.tar, count), nil
+	}
+	return fmt.Sprintf("EXPORT: Partición %s exportada a %s (%s, %d inodos)", cmd.id, cmd.out, cmd.format, count), nil
+}
+
+func commandExport(export *EXPORT) (int, error) {
+	if stores.CurrentSession.ID == "" {
+		return 0, errors.New("no hay sesión activa, inicie sesión primero")
+	}
+	if stores.CurrentSession.Username != "root" {
+		return 0, errors.New("solo el usuario root puede exportar una partición")
+	}
+
+	sb, partition, diskPath, err := stores.GetMountedPartitionSuperblock(export.id)
+	if err != nil {
+		return 0, fmt.Errorf("error al obtener la partición montada: %v", err)
+	}
+	if sb.S_magic != 0xEF53 {
+		return 0, fmt.Errorf("la partición %s no está formateada", export.id)
+	}
+
+	if export.tar != "" {
+		return exportTar(export, sb, diskPath)
+	}
+
+	root, count, err := buildExt2Tree(sb, diskPath, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := utils.CreateParentDirs(export.out); err != nil {
+		return 0, err
+	}
+	volume := strings.Trim(string(partition.Part_name[:]), "\x00")
+	if err := structures.WriteExt2Image(export.out, root, int64(partition.Part_size), volume); err != nil {
+		return 0, fmt.Errorf("error al escribir la imagen ext2: %v", err)
+	}
+	return count, nil
+}
+
+// exportTar guarda la carpeta o archivo export.path en un tar, con los nombres de usuario
+// y grupo tomados de users.txt
+func exportTar(export *EXPORT, sb *structures.SuperBlock, diskPath string) (int, error) {
+	inodeNum, err := findInodeByPath(diskPath, sb, export.path)
+	if err != nil {
+		return 0, fmt.Errorf("no se encontró %s: %v", export.path, err)
+	}
+	users, groups, err := readAccountNames(sb, diskPath)
+	if err != nil {
+		return 0, err
+	}
+
+	root, count, err := buildExt2Tree(sb, diskPath, inodeNum)
+	if err != nil {
+		return 0, err
+	}
+
+	name := ""
+	if inodeNum != 0 {
+		name = path.Base(export.path)
+	}
+	if err := utils.CreateParentDirs(export.tar); err != nil {
+		return 0, err
+	}
+	owner := func(uid, gid uint16) (string, string) {
+		return users[int32(uid)], groups[int32(gid)]
+	}
+	if err := structures.WriteTarArchive(export.tar, name, root, owner); err != nil {
+		return 0, fmt.Errorf("error al escribir el archivo tar: %v", err)
+	}
+	return count, nil
+}
+
+// readAccountNames lee users.txt y devuelve los nombres de los usuarios y grupos activos
+// indexados por su ID
+func readAccountNames(sb *structures.SuperBlock, diskPath string) (map[int32]string, map[int32]string, error) {
+	inodeNum, err := findInodeByPath(diskPath, sb, "/users.txt")
+	if err != nil {
+		return nil, nil, fmt.Errorf("no se encontró users.txt: %v", err)
+	}
+	inode := &structures.Inode{}
+	if err := inode.Deserialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size)); err != nil {
+		return nil, nil, fmt.Errorf("error al leer inodo de users.txt: %v", err)
+	}
+	content, err := sb.ReadFileContent(diskPath, inode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	users := make(map[int32]string)
+	groups := make(map[int32]string)
+	for _, line := range strings.Split(string(content), "\n") {
+		parts := strings.Split(strings.TrimSpace(line), ",")
+		if len(parts) < 3 || parts[0] == "0" {
+			continue
+		}
+		id, err := utils.StringToInt(parts[0])
+		if err != nil {
+			continue
+		}
+		switch {
+		case parts[1] == "G" && len(parts) == 3:
+			groups[int32(id)] = parts[2]
+		case parts[1] == "U" && len(parts) == 5:
+			users[int32(id)] = parts[3]
+		case parts[1] == "U" && len(parts) == 4:
+			users[int32(id)] = parts[2]
+		}
+	}
+	return users, groups, nil
+}
+
+// permBits convierte los permisos UGO guardados como dígitos a bits octales
+func permBits(perm [3]byte) uint16 {
+	bits := uint16(0)
+	for _, digit := range perm {
+		bits <<= 3
+		if digit >= '0' && digit <= '7' {
+			bits |= uint16(digit - '0')
+		}
+	}
+	return bits
+}
+
+// buildExt2Tree recorre la partición desde el inodo start y arma el árbol a escribir en la
+// imagen. Devuelve también la cantidad de inodos recorridos
+func buildExt2Tree(sb *structures.SuperBlock, diskPath string, start int32) (*structures.Ext2ImageNode, int, error) {
+	inodes, err := sb.ReadInodeTable(diskPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error al leer tabla de inodos: %v", err)
+	}
+	inodeBitmap, err := sb.ReadInodeBitmap(diskPath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+
+	nodes := make(map[int32]*structures.Ext2ImageNode)
+	var visit func(inodeNum int32) (*structures.Ext2ImageNode, error)
+	visit = func(inodeNum int32) (*structures.Ext2ImageNode, error) {
+		if node, ok := nodes[inodeNum]; ok {
+			return node, nil
+		}
+		if inodeNum < 0 || int(inodeNum) >= len(inodes) || inodeBitmap[inodeNum] != '1' {
+			return nil, fmt.Errorf("entrada con inodo inválido %d", inodeNum)
+		}
+		inode := &inodes[inodeNum]
+		node := &structures.Ext2ImageNode{
+			IsDir: inode.I_type[0] == '0',
+			Perm:  permBits(inode.I_perm),
+			Uid:   uint16(inode.I_uid),
+			Gid:   uint16(inode.I_gid),
+			Atime: uint32(inode.I_atime),
+			Ctime: uint32(inode.I_ctime),
+			Mtime: uint32(inode.I_mtime),
+		}
+		nodes[inodeNum] = node
+
+		if !node.IsDir {
+			node.Content, err = sb.ReadFileContent(diskPath, inode)
+			return node, err
+		}
+
+		for _, blockNum := range inode.I_block[:12] {
+			if blockNum == -1 {
+				continue
+			}
+			folderBlock := &structures.FolderBlock{}
+			if err := folderBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size)); err != nil {
+				return nil, fmt.Errorf("error al leer bloque %d: %v", blockNum, err)
+			}
+			for _, entry := range folderBlock.B_content {
+				name := strings.Trim(string(entry.B_name[:]), "\x00")
+				if entry.B_inodo == -1 || name == "" || name == "." || name == ".." {
+					continue
+				}
+				child, err := visit(entry.B_inodo)
+				if err != nil {
+					return nil, err
+				}
+				node.Children = append(node.Children, structures.Ext2ImageEntry{Name: name, Node: child})
+			}
+		}
+		return node, nil
+	}
+
+	root, err := visit(start)
+	if err != nil {
+		return nil, 0, err
+	}
+	return root, len(nodes), nil
+}
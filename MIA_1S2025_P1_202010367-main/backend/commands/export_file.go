@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+	utils "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/utils"
+)
+
+// EXPORTFILE estructura que representa el comando export_file con sus parámetros
+type EXPORTFILE struct {
+	path string // Ruta del archivo dentro de la partición
+	dest string // Ruta del archivo de salida en el anfitrión
+}
+
+/*
+   export_file -path=/home/a.txt -dest=/home/user/a.txt
+*/
+
+func ParseExportFile(tokens []string) (string, error) {
+	cmd := &EXPORTFILE{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key, value := strings.ToLower(parts[0]), strings.Trim(parts[1], "\"")
+
+		switch key {
+		case "-path":
+			if !strings.HasPrefix(value, "/") {
+				return "", errors.New("la ruta debe ser absoluta")
+			}
+			cmd.path = value
+		case "-dest":
+			cmd.dest = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.path == "" || cmd.dest == "" {
+		return "", errors.New("faltan parámetros requeridos: -path, -dest")
+	}
+
+	size, err := commandExportFile(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al exportar el archivo: %v", err)
+	}
+	return fmt.Sprintf("EXPORT_FILE: %s copiado a %s (%d bytes)", cmd.path, cmd.dest, size), nil
+}
+
+func commandExportFile(export *EXPORTFILE) (int, error) {
+	if stores.CurrentSession.ID == "" {
+		return 0, errors.New("no hay sesión activa, inicie sesión primero")
+	}
+
+	// La ruta puede pasar por carpetas donde se montaron otras particiones con mount -at
+	target, err := resolveSessionPath(export.path)
+	if err != nil {
+		return 0, err
+	}
+	sb, diskPath := target.sb, target.diskPath
+
+	inodeNum, err := findInodeByPath(diskPath, sb, target.path)
+	if err != nil {
+		return 0, fmt.Errorf("no se encontró %s: %v", export.path, err)
+	}
+	inode := &structures.Inode{}
+	if err := inode.Deserialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size)); err != nil {
+		return 0, fmt.Errorf("error al leer inodo %d: %v", inodeNum, err)
+	}
+	if inode.I_type[0] != '1' {
+		return 0, fmt.Errorf("%s no es un archivo", export.path)
+	}
+	if !checkReadPermission(inode, stores.CurrentSession) {
+		return 0, fmt.Errorf("no tiene permiso de lectura sobre %s", export.path)
+	}
+
+	content, err := sb.ReadFileContent(diskPath, inode)
+	if err != nil {
+		return 0, err
+	}
+	if err := touchAtime(stores.CurrentSession.ID, sb, diskPath, inodeNum, inode); err != nil {
+		return 0, err
+	}
+	if err := utils.CreateParentDirs(export.dest); err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(export.dest, content, 0644); err != nil {
+		return 0, fmt.Errorf("error al escribir %s: %v", export.dest, err)
+	}
+	return len(content), nil
+}
@@ -23,15 +23,21 @@ type FDISK struct {
 	name   string // Nombre de la partición
 	delete string // Modo de eliminación (fast, full)
 	add    int    // Tamaño a añadir o reducir
+	move   bool   // Opción -move
+	start  int    // Nuevo byte de inicio para -move, en la unidad de -unit
 }
 
 // ParseFdisk parsea el comando fdisk y devuelve una instancia de FDISK
 func ParseFdisk(tokens []string) (string, error) {
-	cmd := &FDISK{}
+	cmd := &FDISK{start: -1}
 
 	// Procesar cada token
 	for _, token := range tokens {
 		parts := strings.SplitN(token, "=", 2)
+		if len(parts) == 1 && strings.ToLower(parts[0]) == "-move" {
+			cmd.move = true
+			continue
+		}
 		if len(parts) != 2 {
 			return "", fmt.Errorf("formato inválido: %s", token)
 		}
@@ -85,6 +91,12 @@ func ParseFdisk(tokens []string) (string, error) {
 				return "", errors.New("el valor de add debe ser un entero")
 			}
 			cmd.add = add
+		case "-start":
+			start, err := strconv.Atoi(value)
+			if err != nil || start < 0 {
+				return "", errors.New("el inicio debe ser un entero no negativo")
+			}
+			cmd.start = start
 		default:
 			return "", fmt.Errorf("parámetro desconocido: %s", key)
 		}
@@ -96,7 +108,20 @@ func ParseFdisk(tokens []string) (string, error) {
 	}
 
 	// Validar combinaciones de parámetros
-	if cmd.delete != "" {
+	if cmd.move {
+		if cmd.delete != "" || cmd.add != 0 || cmd.size > 0 || cmd.typ != "" || cmd.fit != "" {
+			return "", errors.New("el parámetro -move no puede combinarse con -delete, -add, -size, -type o -fit")
+		}
+		if cmd.start < 0 {
+			return "", errors.New("faltan parámetros requeridos: -start")
+		}
+		// El inicio se indica en bytes salvo que se use -unit
+		if cmd.unit == "" {
+			cmd.unit = "B"
+		}
+	} else if cmd.start >= 0 {
+		return "", errors.New("el parámetro -start solo puede usarse con -move")
+	} else if cmd.delete != "" {
 		if cmd.add != 0 || cmd.typ != "" || cmd.fit != "" {
 			return "", errors.New("el parámetro -delete no puede combinarse con -add, -type o -fit")
 		}
@@ -122,10 +147,10 @@ func ParseFdisk(tokens []string) (string, error) {
 	if cmd.unit == "" {
 		cmd.unit = "K"
 	}
-	if cmd.fit == "" && cmd.delete == "" && cmd.add == 0 {
+	if cmd.fit == "" && cmd.delete == "" && cmd.add == 0 && !cmd.move {
 		cmd.fit = "WF"
 	}
-	if cmd.typ == "" && cmd.delete == "" && cmd.add == 0 {
+	if cmd.typ == "" && cmd.delete == "" && cmd.add == 0 && !cmd.move {
 		cmd.typ = "P"
 	}
 
@@ -139,11 +164,13 @@ func ParseFdisk(tokens []string) (string, error) {
 		return fmt.Sprintf("FDISK: Partición %s eliminada correctamente en %s", cmd.name, cmd.path), nil
 	} else if cmd.add != 0 {
 		return fmt.Sprintf("FDISK: Tamaño de partición %s ajustado correctamente en %s", cmd.name, cmd.path), nil
+	} else if cmd.move {
+		return fmt.Sprintf("FDISK: Partición %s movida correctamente en %s", cmd.name, cmd.path), nil
 	}
 	return fmt.Sprintf("FDISK: Partición %s creada correctamente en %s", cmd.name, cmd.path), nil
 }
 
-// commandFdisk implementa la lógica para crear, añadir o eliminar particiones
+// commandFdisk implementa la lógica para crear, añadir, eliminar o mover particiones
 func commandFdisk(fdisk *FDISK) error {
 	// Crear directorios padre si no existen
 	if err := utils.CreateParentDirs(fdisk.path); err != nil {
@@ -153,11 +180,11 @@ func commandFdisk(fdisk *FDISK) error {
 	// Verificar si la partición está montada
 	for id, path := range stores.MountedPartitions {
 		if path == fdisk.path {
-			var mbr structures.MBR
-			if err := mbr.Deserialize(fdisk.path); err != nil {
-				return fmt.Errorf("error al deserializar MBR: %v", err)
+			table, err := structures.ReadPartitionTable(fdisk.path)
+			if err != nil {
+				return fmt.Errorf("error al leer la tabla de particiones: %v", err)
 			}
-			partition, _ := mbr.GetPartitionByNameFromID(id)
+			partition, _ := table.GetPartitionByID(id)
 			if partition != nil && strings.Trim(string(partition.Part_name[:]), "\x00") == fdisk.name {
 				return errors.New("no se puede modificar una partición montada")
 			}
@@ -171,20 +198,25 @@ func commandFdisk(fdisk *FDISK) error {
 	}
 	defer file.Close()
 
-	// Leer MBR
-	var mbr structures.MBR
-	if err := mbr.Deserialize(fdisk.path); err != nil {
-		return fmt.Errorf("error al deserializar MBR: %v", err)
+	// Leer la tabla de particiones (MBR o GPT)
+	table, err := structures.ReadPartitionTable(fdisk.path)
+	if err != nil {
+		return fmt.Errorf("error al leer la tabla de particiones: %v", err)
 	}
 
 	// Manejar -delete
 	if fdisk.delete != "" {
-		return deletePartition(&mbr, fdisk, file)
+		return deletePartition(table, fdisk, file)
 	}
 
 	// Manejar -add
 	if fdisk.add != 0 {
-		return addPartitionSize(&mbr, fdisk, file)
+		return addPartitionSize(table, fdisk, file)
+	}
+
+	// Manejar -move
+	if fdisk.move {
+		return movePartition(table, fdisk, file)
 	}
 
 	// Crear nueva partición
@@ -195,10 +227,15 @@ func commandFdisk(fdisk *FDISK) error {
 	}
 
 	// Validar nombre duplicado en primarias/extendidas
-	if _, idx := mbr.GetPartitionByName(fdisk.name); idx != -1 {
+	if _, idx := table.GetPartitionByName(fdisk.name); idx != -1 {
 		return fmt.Errorf("el nombre '%s' ya existe en particiones primarias/extendidas", fdisk.name)
 	}
 
+	// GPT y el MBR estándar no tienen particiones extendidas ni lógicas
+	if table.PrimaryOnly() && fdisk.typ != "P" {
+		return fmt.Errorf("los discos %s solo admiten particiones primarias", table.TypeName())
+	}
+
 	switch fdisk.typ {
 	case "P":
 		return createPrimaryPartition(fdisk, sizeBytes)
@@ -212,17 +249,13 @@ func commandFdisk(fdisk *FDISK) error {
 }
 
 // deletePartition elimina una partición primaria o lógica
-func deletePartition(mbr *structures.MBR, fdisk *FDISK, file *os.File) error {
+func deletePartition(table *structures.PartitionTable, fdisk *FDISK, file *os.File) error {
 	// Buscar partición primaria
-	partition, _ := mbr.GetPartitionByName(fdisk.name)
+	partition, _ := table.GetPartitionByName(fdisk.name)
 	if partition != nil {
 		if fdisk.delete == "full" {
-			// Sobrescribir con ceros
-			if _, err := file.Seek(int64(partition.Part_start), 0); err != nil {
-				return err
-			}
-			zeros := make([]byte, partition.Part_size)
-			if _, err := file.Write(zeros); err != nil {
+			// Sobrescribir con ceros; en Linux el rango se libera en el anfitrión
+			if err := structures.PunchHole(fdisk.path, int64(partition.Part_start), int64(partition.Part_size)); err != nil {
 				return err
 			}
 		}
@@ -235,18 +268,12 @@ func deletePartition(mbr *structures.MBR, fdisk *FDISK, file *os.File) error {
 		partition.Part_type = [1]byte{}
 		partition.Part_fit = [1]byte{}
 		partition.Part_correlative = 0
-		return mbr.Serialize(fdisk.path)
+		return table.Write(fdisk.path)
 	}
 
 	// Buscar partición lógica
-	var extPartition *structures.Partition
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_type[0] == 'E' && p.Part_status[0] != 'N' {
-			extPartition = &p
-			break
-		}
-	}
-	if extPartition == nil {
+	extPartition := table.ExtendedPartition()
+	if extPartition == nil || extPartition.Part_status[0] == 'N' {
 		return fmt.Errorf("partición %s no encontrada", fdisk.name)
 	}
 
@@ -259,12 +286,8 @@ func deletePartition(mbr *structures.MBR, fdisk *FDISK, file *os.File) error {
 		}
 		if strings.Trim(string(currentEBR.Part_name[:]), "\x00") == fdisk.name {
 			if fdisk.delete == "full" {
-				// Sobrescribir con ceros
-				if _, err := file.Seek(int64(currentEBR.Part_start), 0); err != nil {
-					return err
-				}
-				zeros := make([]byte, currentEBR.Part_size)
-				if _, err := file.Write(zeros); err != nil {
+				// Sobrescribir con ceros; en Linux el rango se libera en el anfitrión
+				if err := structures.PunchHole(fdisk.path, int64(currentEBR.Part_start), int64(currentEBR.Part_size)); err != nil {
 					return err
 				}
 			}
@@ -313,7 +336,7 @@ func deletePartition(mbr *structures.MBR, fdisk *FDISK, file *os.File) error {
 }
 
 // addPartitionSize ajusta el tamaño de una partición primaria o lógica
-func addPartitionSize(mbr *structures.MBR, fdisk *FDISK, file *os.File) error {
+func addPartitionSize(table *structures.PartitionTable, fdisk *FDISK, file *os.File) error {
 	// Convertir add a bytes
 	addBytes, err := utils.ConvertToBytes(fdisk.add, fdisk.unit)
 	if err != nil {
@@ -324,7 +347,7 @@ func addPartitionSize(mbr *structures.MBR, fdisk *FDISK, file *os.File) error {
 	}
 
 	// Buscar partición primaria
-	partition, _ := mbr.GetPartitionByName(fdisk.name)
+	partition, _ := table.GetPartitionByName(fdisk.name)
 	if partition != nil {
 		newSize := int(partition.Part_size) + addBytes
 		if newSize <= 0 {
@@ -336,12 +359,16 @@ func addPartitionSize(mbr *structures.MBR, fdisk *FDISK, file *os.File) error {
 			return err
 		}
 		diskSize := fileInfo.Size()
+		if table.PrimaryOnly() {
+			// La copia de respaldo de GPT ocupa el final del disco y msdos usa sectores completos
+			diskSize = table.UsableEnd()
+		}
 		endPosition := int64(partition.Part_start) + int64(newSize)
 		if endPosition > diskSize {
 			return errors.New("no hay suficiente espacio en el disco")
 		}
 		// Verificar colisión con otras particiones
-		for _, p := range mbr.Mbr_partitions {
+		for _, p := range table.Partitions() {
 			if p.Part_status[0] == 'N' || p.Part_start == -1 {
 				continue
 			}
@@ -350,18 +377,12 @@ func addPartitionSize(mbr *structures.MBR, fdisk *FDISK, file *os.File) error {
 			}
 		}
 		partition.Part_size = int32(newSize)
-		return mbr.Serialize(fdisk.path)
+		return table.Write(fdisk.path)
 	}
 
 	// Buscar partición lógica
-	var extPartition *structures.Partition
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_type[0] == 'E' && p.Part_status[0] != 'N' {
-			extPartition = &p
-			break
-		}
-	}
-	if extPartition == nil {
+	extPartition := table.ExtendedPartition()
+	if extPartition == nil || extPartition.Part_status[0] == 'N' {
 		return fmt.Errorf("partición %s no encontrada", fdisk.name)
 	}
 
@@ -404,37 +425,30 @@ func addPartitionSize(mbr *structures.MBR, fdisk *FDISK, file *os.File) error {
 
 // createPrimaryPartition crea una partición primaria
 func createPrimaryPartition(fdisk *FDISK, sizeBytes int) error {
-	var mbr structures.MBR
-	if err := mbr.Deserialize(fdisk.path); err != nil {
-		return fmt.Errorf("error deserializando el MBR: %v", err)
+	table, err := structures.ReadPartitionTable(fdisk.path)
+	if err != nil {
+		return fmt.Errorf("error leyendo la tabla de particiones: %v", err)
 	}
 
-	// Contar particiones primarias/extendidas activas
+	// Contar particiones primarias/extendidas activas (GPT admite hasta 128 primarias)
 	count := 0
-	for _, p := range mbr.Mbr_partitions {
+	for _, p := range table.Partitions() {
 		if p.Part_status[0] != 'N' {
 			count++
 		}
 	}
-	if count >= 4 {
-		return errors.New("máximo de 4 particiones primarias/extendidas alcanzado")
-	}
-
-	partition, start, idx := mbr.GetFirstAvailablePartition()
-	if partition == nil {
-		return errors.New("no hay particiones disponibles en el MBR")
+	if max := len(table.Partitions()); count >= max {
+		return fmt.Errorf("máximo de %d particiones primarias/extendidas alcanzado", max)
 	}
 
-	// Verificar espacio disponible desde el inicio hasta el final del disco
-	availableSpace := int(mbr.Mbr_size) - start
-	if sizeBytes > availableSpace {
-		return errors.New("no hay espacio suficiente en el disco")
+	partition, start, err := table.AllocatePartition(sizeBytes)
+	if err != nil {
+		return err
 	}
 
 	partition.CreatePartition(start, sizeBytes, fdisk.typ, fdisk.fit, fdisk.name)
-	mbr.Mbr_partitions[idx] = *partition
-	if err := mbr.Serialize(fdisk.path); err != nil {
-		return fmt.Errorf("error serializando el MBR: %v", err)
+	if err := table.Write(fdisk.path); err != nil {
+		return fmt.Errorf("error serializando la tabla de particiones: %v", err)
 	}
 
 	return nil
@@ -442,21 +456,19 @@ func createPrimaryPartition(fdisk *FDISK, sizeBytes int) error {
 
 // createExtendedPartition crea una partición extendida
 func createExtendedPartition(fdisk *FDISK, sizeBytes int) error {
-	var mbr structures.MBR
-	if err := mbr.Deserialize(fdisk.path); err != nil {
-		return fmt.Errorf("error deserializando el MBR: %v", err)
+	table, err := structures.ReadPartitionTable(fdisk.path)
+	if err != nil {
+		return fmt.Errorf("error leyendo la tabla de particiones: %v", err)
 	}
 
 	// Validar que no exista otra extendida
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_type[0] == 'E' && p.Part_status[0] != 'N' {
-			return errors.New("ya existe una partición extendida en el disco")
-		}
+	if ext := table.ExtendedPartition(); ext != nil && ext.Part_status[0] != 'N' {
+		return errors.New("ya existe una partición extendida en el disco")
 	}
 
 	// Contar particiones primarias/extendidas activas
 	count := 0
-	for _, p := range mbr.Mbr_partitions {
+	for _, p := range table.Partitions() {
 		if p.Part_status[0] != 'N' {
 			count++
 		}
@@ -465,20 +477,14 @@ func createExtendedPartition(fdisk *FDISK, sizeBytes int) error {
 		return errors.New("máximo de 4 particiones primarias/extendidas alcanzado")
 	}
 
-	partition, start, idx := mbr.GetFirstAvailablePartition()
-	if partition == nil {
-		return errors.New("no hay particiones disponibles en el MBR")
-	}
-
-	availableSpace := int(mbr.Mbr_size) - start
-	if sizeBytes > availableSpace {
-		return errors.New("no hay espacio suficiente en el disco")
+	partition, start, err := table.AllocatePartition(sizeBytes)
+	if err != nil {
+		return err
 	}
 
 	partition.CreatePartition(start, sizeBytes, "E", fdisk.fit, fdisk.name)
-	mbr.Mbr_partitions[idx] = *partition
-	if err := mbr.Serialize(fdisk.path); err != nil {
-		return fmt.Errorf("error serializando el MBR: %v", err)
+	if err := table.Write(fdisk.path); err != nil {
+		return fmt.Errorf("error serializando la tabla de particiones: %v", err)
 	}
 
 	return nil
@@ -486,20 +492,14 @@ func createExtendedPartition(fdisk *FDISK, sizeBytes int) error {
 
 // createLogicalPartition crea una partición lógica dentro de una extendida
 func createLogicalPartition(fdisk *FDISK, sizeBytes int) error {
-	var mbr structures.MBR
-	if err := mbr.Deserialize(fdisk.path); err != nil {
-		return fmt.Errorf("error al deserializar MBR: %v", err)
+	table, err := structures.ReadPartitionTable(fdisk.path)
+	if err != nil {
+		return fmt.Errorf("error al leer la tabla de particiones: %v", err)
 	}
 
 	// Buscar partición extendida
-	var extPartition *structures.Partition
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_type[0] == 'E' && p.Part_status[0] != 'N' {
-			extPartition = &p
-			break
-		}
-	}
-	if extPartition == nil {
+	extPartition := table.ExtendedPartition()
+	if extPartition == nil || extPartition.Part_status[0] == 'N' {
 		return errors.New("no hay partición extendida para crear lógicas")
 	}
 
@@ -577,3 +577,183 @@ func createLogicalPartition(fdisk *FDISK, sizeBytes int) error {
 
 	return nil
 }
+
+// ebrLink es un EBR de la cadena de la partición extendida junto con el byte donde está
+type ebrLink struct {
+	offset int64
+	ebr    structures.EBR
+}
+
+// readEBRChain devuelve los EBR de la partición extendida en el orden de la cadena. Una
+// extendida sin particiones lógicas devuelve una cadena vacía
+func readEBRChain(file *os.File, extPartition *structures.Partition) ([]ebrLink, error) {
+	var chain []ebrLink
+	visited := make(map[int64]bool)
+	offset := int64(extPartition.Part_start)
+	for !visited[offset] {
+		visited[offset] = true
+		var ebr structures.EBR
+		if err := ebr.Deserialize(file, offset); err != nil {
+			return nil, fmt.Errorf("error al leer EBR: %v", err)
+		}
+		if ebr.Part_status[0] == 0 || ebr.Part_status[0] == 'N' || ebr.Part_size <= 0 {
+			break
+		}
+		chain = append(chain, ebrLink{offset: offset, ebr: ebr})
+		if ebr.Part_next == -1 {
+			break
+		}
+		offset = int64(ebr.Part_next)
+	}
+	return chain, nil
+}
+
+// mountedLogical devuelve el ID de una partición lógica montada del disco, si hay alguna
+func mountedLogical(table *structures.PartitionTable, path string) (string, bool) {
+	for id, mountedPath := range stores.MountedPartitions {
+		if mountedPath != path {
+			continue
+		}
+		if partition, _ := table.GetPartitionByID(id); partition == nil {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// movePartition mueve una partición primaria, extendida o lógica para que empiece en -start
+func movePartition(table *structures.PartitionTable, fdisk *FDISK, file *os.File) error {
+	start, err := utils.ConvertToBytes(fdisk.start, fdisk.unit)
+	if err != nil {
+		return err
+	}
+
+	if partition, _ := table.GetPartitionByName(fdisk.name); partition != nil {
+		if partition.Part_type[0] == 'E' {
+			if id, exists := mountedLogical(table, fdisk.path); exists {
+				return fmt.Errorf("la partición lógica %s está montada, desmonte primero", id)
+			}
+		}
+		return relocatePartition(table, fdisk.path, file, partition, int64(start))
+	}
+
+	extPartition := table.ExtendedPartition()
+	if extPartition == nil || extPartition.Part_status[0] == 'N' {
+		return fmt.Errorf("partición %s no encontrada", fdisk.name)
+	}
+	chain, err := readEBRChain(file, extPartition)
+	if err != nil {
+		return err
+	}
+	for i, link := range chain {
+		if strings.Trim(string(link.ebr.Part_name[:]), "\x00") != fdisk.name {
+			continue
+		}
+		if link.ebr.Part_status[0] == '1' {
+			return errors.New("no se puede modificar una partición montada")
+		}
+		return relocateLogicalPartition(file, fdisk.path, extPartition, chain, i, int64(start))
+	}
+	return fmt.Errorf("partición lógica %s no encontrada", fdisk.name)
+}
+
+// relocatePartition mueve los datos de una partición primaria o extendida a start. El
+// superbloque de una partición formateada y los snapshots guardan posiciones absolutas del
+// disco, así que se corren junto con los datos; en una extendida se corre la cadena de EBR
+func relocatePartition(table *structures.PartitionTable, path string, file *os.File, partition *structures.Partition, start int64) error {
+	oldStart, size := int64(partition.Part_start), int64(partition.Part_size)
+	if start == oldStart {
+		return nil
+	}
+	if table.PrimaryOnly() && start%structures.SectorSize != 0 {
+		return fmt.Errorf("en discos %s el inicio debe ser múltiplo de %d bytes", table.TypeName(), structures.SectorSize)
+	}
+	if start < table.MetadataSize() || start+size > table.UsableEnd() {
+		return fmt.Errorf("la partición debe quedar entre los bytes %d y %d del disco", table.MetadataSize(), table.UsableEnd())
+	}
+	for _, p := range table.UsedPartitions() {
+		if p == partition {
+			continue
+		}
+		if start < int64(p.Part_start)+int64(p.Part_size) && int64(p.Part_start) < start+size {
+			return fmt.Errorf("la nueva posición se superpone con la partición %s", strings.Trim(string(p.Part_name[:]), "\x00"))
+		}
+	}
+
+	// La cadena se lee antes de mover los datos, con las posiciones viejas
+	var chain []ebrLink
+	if partition.Part_type[0] == 'E' {
+		var err error
+		if chain, err = readEBRChain(file, partition); err != nil {
+			return err
+		}
+	}
+
+	if err := structures.MoveBytes(path, oldStart, start, size); err != nil {
+		return fmt.Errorf("error al mover los datos de la partición: %v", err)
+	}
+	delta := start - oldStart
+
+	if partition.Part_type[0] == 'E' {
+		for _, link := range chain {
+			link.ebr.Part_start += int32(delta)
+			if link.ebr.Part_next != -1 {
+				link.ebr.Part_next += int32(delta)
+			}
+			if err := link.ebr.Serialize(file, link.offset+delta); err != nil {
+				return fmt.Errorf("error al escribir EBR: %v", err)
+			}
+		}
+	} else {
+		var sb structures.SuperBlock
+		if err := sb.Deserialize(path, start); err == nil && sb.S_magic == 0xEF53 && sb.Offset() == oldStart {
+			sb.Rebase(delta)
+			if err := sb.Serialize(path, start); err != nil {
+				return fmt.Errorf("error al actualizar superbloque: %v", err)
+			}
+		}
+		if err := rebaseSnapshots(path, partition, delta); err != nil {
+			return fmt.Errorf("error al actualizar los snapshots: %v", err)
+		}
+	}
+
+	partition.Part_start = int32(start)
+	return table.Write(path)
+}
+
+// relocateLogicalPartition mueve la partición lógica chain[i] a start, dentro del espacio
+// libre que la rodea, y actualiza su EBR y el Part_next del anterior. El primer EBR siempre
+// queda al inicio de la extendida; los demás van al inicio de su partición
+func relocateLogicalPartition(file *os.File, path string, extPartition *structures.Partition, chain []ebrLink, i int, start int64) error {
+	link := chain[i]
+	oldStart, size := int64(link.ebr.Part_start), int64(link.ebr.Part_size)
+	if start == oldStart {
+		return nil
+	}
+
+	low, high := int64(extPartition.Part_start), int64(extPartition.Part_start)+int64(extPartition.Part_size)
+	if i > 0 {
+		low = int64(chain[i-1].ebr.Part_start) + int64(chain[i-1].ebr.Part_size)
+	}
+	if i+1 < len(chain) {
+		high = chain[i+1].offset
+	}
+	if start < low || start+size > high {
+		return fmt.Errorf("la partición lógica solo puede moverse entre los bytes %d y %d", low, high)
+	}
+
+	if err := structures.MoveBytes(path, oldStart, start, size); err != nil {
+		return fmt.Errorf("error al mover los datos de la partición: %v", err)
+	}
+
+	link.ebr.Part_start = int32(start)
+	if i == 0 {
+		return link.ebr.Serialize(file, link.offset)
+	}
+	if err := link.ebr.Serialize(file, start); err != nil {
+		return fmt.Errorf("error al escribir EBR: %v", err)
+	}
+	prev := chain[i-1]
+	prev.ebr.Part_next = int32(start)
+	return prev.ebr.Serialize(file, prev.offset)
+}
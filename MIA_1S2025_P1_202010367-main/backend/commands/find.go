@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -65,11 +66,12 @@ func commandFind(find *FIND) (string, error) {
 		return "", errors.New("no hay sesión activa, inicie sesión primero")
 	}
 
-	// Obtener superbloque y ruta del disco
-	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	// Obtener superbloque y ruta del disco de la partición donde inicia la búsqueda
+	target, err := resolveSessionPath(find.path)
 	if err != nil {
-		return "", fmt.Errorf("error al obtener la partición montada: %v", err)
+		return "", err
 	}
+	partitionSuperblock, partitionPath := target.sb, target.diskPath
 
 	// Abrir archivo del disco
 	file, err := os.OpenFile(partitionPath, os.O_RDWR, 0644)
@@ -79,7 +81,7 @@ func commandFind(find *FIND) (string, error) {
 	defer file.Close()
 
 	// Encontrar el inodo de la ruta inicial
-	inodeNum, err := findInodeByPath(partitionPath, partitionSuperblock, find.path)
+	inodeNum, err := findInodeByPath(partitionPath, partitionSuperblock, target.path)
 	if err != nil {
 		return "", fmt.Errorf("error al localizar la ruta %s: %v", find.path, err)
 	}
@@ -101,14 +103,14 @@ func commandFind(find *FIND) (string, error) {
 
 	// Realizar búsqueda recursiva
 	matches := []string{}
-	err = searchFiles(partitionPath, partitionSuperblock, inodeNum, find.path, find.name, &matches)
+	err = searchFiles(target.id, partitionPath, partitionSuperblock, inodeNum, target.path, stores.CleanPath(find.path), find.name, &matches)
 	if err != nil {
 		return "", fmt.Errorf("error durante la búsqueda: %v", err)
 	}
 
 	// Registrar en el Journal (si es EXT3)
 	if partitionSuperblock.S_filesystem_type == 3 {
-		err = AddJournalEntry(partitionSuperblock, partitionPath, "find", find.path, find.name)
+		err = AddJournalEntry(partitionSuperblock, partitionPath, "find", target.path, find.name)
 		if err != nil {
 			return "", fmt.Errorf("error al registrar en el Journal: %v", err)
 		}
@@ -202,8 +204,10 @@ func hasReadPermission(inode *structures.Inode, uid, gid string) bool {
 	return oPerm&4 != 0
 }
 
-// searchFiles realiza la búsqueda recursiva
-func searchFiles(diskPath string, sb *structures.SuperBlock, inodeNum int32, currentPath, pattern string, matches *[]string) error {
+// searchFiles realiza la búsqueda recursiva. relPath es la ruta dentro de la partición id y
+// currentPath la ruta en la sesión; las carpetas donde se montó otra partición continúan en
+// la raíz de esa partición
+func searchFiles(id, diskPath string, sb *structures.SuperBlock, inodeNum int32, relPath, currentPath, pattern string, matches *[]string) error {
 	inode := &structures.Inode{}
 	err := inode.Deserialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size))
 	if err != nil {
@@ -252,8 +256,20 @@ func searchFiles(diskPath string, sb *structures.SuperBlock, inodeNum int32, cur
 				childPath += "/" + name
 			}
 
-			// Buscar recursivamente
-			err = searchFiles(diskPath, sb, childInodeNum, childPath, pattern, matches)
+			// Buscar recursivamente, cambiando de partición en los puntos de montaje
+			childRel := path.Join(relPath, name)
+			if graftID, mounted := stores.GraftAt(id, childRel); mounted {
+				graftSb, _, graftDisk, err := stores.GetMountedPartitionSuperblock(graftID)
+				if err != nil {
+					return fmt.Errorf("error al obtener la partición montada %s: %v", graftID, err)
+				}
+				err = searchFiles(graftID, graftDisk, graftSb, 0, "/", childPath, pattern, matches)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			err = searchFiles(id, diskPath, sb, childInodeNum, childRel, childPath, pattern, matches)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,328 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+	utils "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/utils"
+)
+
+// IMPORT estructura que representa el comando import con sus parámetros
+type IMPORT struct {
+	id     string // ID de la partición destino
+	format string // Formato de la imagen (ext2)
+	src    string // Ruta de la imagen en el anfitrión
+	tar    string // Ruta del archivo tar en el anfitrión
+	dest   string // Carpeta destino dentro de la partición
+}
+
+/*
+   import -format=ext2 -src=/home/user/particion.img -id=671A
+   import -format=ext2 -src=/home/user/particion.img -id=671A -dest=/importado
+   import -id=671A -tar=/home/user/home.tar -dest=/
+*/
+
+func ParseImport(tokens []string) (string, error) {
+	cmd := &IMPORT{dest: "/"}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key, value := strings.ToLower(parts[0]), strings.Trim(parts[1], "\"")
+
+		switch key {
+		case "-id":
+			cmd.id = strings.ToUpper(value)
+		case "-format":
+			value = strings.ToLower(value)
+			if value != "ext2" {
+				return "", errors.New("el formato debe ser ext2")
+			}
+			cmd.format = value
+		case "-src":
+			cmd.src = value
+		case "-tar":
+			cmd.tar = value
+		case "-dest":
+			if !strings.HasPrefix(value, "/") {
+				return "", errors.New("el destino debe ser una ruta absoluta")
+			}
+			cmd.dest = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.tar != "" {
+		if cmd.format != "" || cmd.src != "" {
+			return "", errors.New("-tar no se puede combinar con -format ni -src")
+		}
+	} else if cmd.id == "" || cmd.format == "" || cmd.src == "" {
+		return "", errors.New("faltan parámetros requeridos: -id, -format, -src (o -id, -tar)")
+	}
+	if cmd.id == "" {
+		return "", errors.New("faltan parámetros requeridos: -id")
+	}
+	source := cmd.src
+	if cmd.tar != "" {
+		source = cmd.tar
+	}
+
+	im, err := commandImport(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al importar: %v", err)
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("IMPORT: %d carpetas y %d archivos importados de %s en %s:%s", im.folders, im.files, source, cmd.id, cmd.dest))
+	if len(im.warnings) > 0 {
+		output.WriteString(fmt.Sprintf("\nNo se pudieron representar %d elementos:", len(im.warnings)))
+		for _, warning := range im.warnings {
+			output.WriteString("\n  " + warning)
+		}
+	}
+	return output.String(), nil
+}
+
+// ext2Importer recrea un árbol leído de una imagen ext2 dentro de la partición
+type ext2Importer struct {
+	sb       *structures.SuperBlock
+	diskPath string
+	seen     map[*structures.Ext2ImageNode]string // Primera ruta de cada nodo importado
+	folders  int
+	files    int
+	warnings []string
+}
+
+func commandImport(imp *IMPORT) (*ext2Importer, error) {
+	if stores.CurrentSession.ID == "" {
+		return nil, errors.New("no hay sesión activa, inicie sesión primero")
+	}
+	if stores.CurrentSession.Username != "root" {
+		return nil, errors.New("solo el usuario root puede importar una imagen")
+	}
+
+	sb, _, diskPath, err := stores.GetMountedPartitionSuperblock(imp.id)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la partición montada: %v", err)
+	}
+	if sb.S_magic != 0xEF53 {
+		return nil, fmt.Errorf("la partición %s no está formateada", imp.id)
+	}
+
+	var root *structures.Ext2ImageNode
+	var warnings []string
+	source := imp.src
+	if imp.tar != "" {
+		source = imp.tar
+		root, warnings, err = readTarForImport(sb, diskPath, imp.tar)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		root, warnings, err = structures.ReadExt2Image(imp.src)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la imagen ext2: %v", err)
+		}
+	}
+
+	im := &ext2Importer{
+		sb:       sb,
+		diskPath: diskPath,
+		seen:     map[*structures.Ext2ImageNode]string{root: "/"},
+		warnings: warnings,
+	}
+
+	var destDirs []string
+	if strings.Trim(imp.dest, "/") != "" {
+		parentDirs, destName := utils.GetParentDirectories(imp.dest)
+		destDirs = append(parentDirs, destName)
+		if err := createParentFolders(sb, diskPath, destDirs); err != nil {
+			return nil, fmt.Errorf("error al crear la carpeta destino: %v", err)
+		}
+	}
+
+	importErr := im.importDir(root, destDirs, "/")
+
+	// El superbloque se guarda aunque la importación se detenga, para que los contadores
+	// coincidan con los bitmaps
+	if err := sb.Serialize(diskPath, sb.Offset()); err != nil {
+		return nil, fmt.Errorf("error al actualizar superbloque: %v", err)
+	}
+	if importErr != nil {
+		return nil, importErr
+	}
+	if err := AddJournalEntry(sb, diskPath, "import", imp.dest, source); err != nil {
+		return nil, fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	return im, nil
+}
+
+// readTarForImport lee un tar asignando a cada entrada el usuario y grupo de users.txt con
+// el mismo nombre; si el nombre no existe en la partición se conserva el ID numérico
+func readTarForImport(sb *structures.SuperBlock, diskPath, tarPath string) (*structures.Ext2ImageNode, []string, error) {
+	users, groups, err := readAccountNames(sb, diskPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	userIDs := make(map[string]uint16)
+	for id, name := range users {
+		userIDs[name] = uint16(id)
+	}
+	groupIDs := make(map[string]uint16)
+	for id, name := range groups {
+		groupIDs[name] = uint16(id)
+	}
+
+	owner := func(uname, gname string, uid, gid int) (uint16, uint16) {
+		if id, ok := userIDs[uname]; ok {
+			uid = int(id)
+		}
+		if id, ok := groupIDs[gname]; ok {
+			gid = int(id)
+		}
+		return uint16(uid), uint16(gid)
+	}
+	root, warnings, err := structures.ReadTarArchive(tarPath, owner)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al leer el archivo tar: %v", err)
+	}
+	return root, warnings, nil
+}
+
+// importDir recrea las entradas de una carpeta de la imagen dentro de parentDirs. Las
+// advertencias usan la ruta dentro de la imagen (imageDir)
+func (im *ext2Importer) importDir(dir *structures.Ext2ImageNode, parentDirs []string, imageDir string) error {
+	for _, entry := range dir.Children {
+		dirs := append(append([]string{}, parentDirs...), entry.Name)
+		path := imageDir + entry.Name
+
+		// lost+found vacío es parte del formato ext2, no del contenido
+		if imageDir == "/" && entry.Name == "lost+found" && entry.Node.IsDir && len(entry.Node.Children) == 0 {
+			continue
+		}
+		if len(entry.Name) > 12 {
+			im.warnings = append(im.warnings, fmt.Sprintf("%s: el nombre excede 12 caracteres", path))
+			continue
+		}
+		if first, ok := im.seen[entry.Node]; ok {
+			if entry.Node.IsDir {
+				im.warnings = append(im.warnings, fmt.Sprintf("%s: carpeta ya importada como %s", path, first))
+				continue
+			}
+			im.warnings = append(im.warnings, fmt.Sprintf("%s: enlace duro a %s, importado como copia", path, first))
+		} else {
+			im.seen[entry.Node] = path
+		}
+
+		existing, findErr := findInode(im.sb, im.diskPath, parentDirs, entry.Name)
+		if entry.Node.IsDir {
+			created := false
+			if findErr == nil {
+				// La carpeta ya existe: se combinan los contenidos
+				inode := &structures.Inode{}
+				if err := inode.Deserialize(im.diskPath, int64(im.sb.S_inode_start+existing*im.sb.S_inode_size)); err != nil {
+					return err
+				}
+				if inode.I_type[0] != '0' {
+					im.warnings = append(im.warnings, fmt.Sprintf("%s: ya existe un archivo con ese nombre", path))
+					continue
+				}
+			} else {
+				if err := im.sb.CreateFolder(im.diskPath, parentDirs, entry.Name); err != nil {
+					return fmt.Errorf("error al crear %s: %v", path, err)
+				}
+				created = true
+				im.folders++
+			}
+			if err := im.importDir(entry.Node, dirs, path+"/"); err != nil {
+				return err
+			}
+			// Los atributos se aplican al final porque agregar entradas actualiza la fecha
+			if created {
+				if err := im.applyAttributes(parentDirs, entry); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if findErr == nil {
+			im.warnings = append(im.warnings, fmt.Sprintf("%s: ya existe en la partición", path))
+			continue
+		}
+		if limit := structures.MaxFileBlocks * int(im.sb.S_block_size); len(entry.Node.Content) > limit {
+			im.warnings = append(im.warnings, fmt.Sprintf("%s: %d bytes exceden el máximo de %d", path, len(entry.Node.Content), limit))
+			continue
+		}
+		if err := im.createFile(parentDirs, entry); err != nil {
+			return fmt.Errorf("error al crear %s: %v", path, err)
+		}
+		im.files++
+	}
+	return nil
+}
+
+// createFile crea el archivo de la entrada con su contenido y atributos. No depende de
+// la sesión activa, por lo que también se usa al formatear con mkfs -from
+func (im *ext2Importer) createFile(parentDirs []string, entry structures.Ext2ImageEntry) error {
+	parentNum := int32(0)
+	if len(parentDirs) > 0 {
+		var err error
+		parentNum, err = findInode(im.sb, im.diskPath, parentDirs[:len(parentDirs)-1], parentDirs[len(parentDirs)-1])
+		if err != nil {
+			return err
+		}
+	}
+
+	inodeNum, err := im.sb.FindFreeInode(im.diskPath)
+	if err != nil {
+		return err
+	}
+	inode := &structures.Inode{
+		I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_type:  [1]byte{'1'},
+	}
+	setImageAttributes(inode, entry.Node)
+	if err := im.sb.WriteFileContent(im.diskPath, inode, entry.Node.Content); err != nil {
+		return err
+	}
+	if err := inode.Serialize(im.diskPath, int64(im.sb.S_inode_start+inodeNum*im.sb.S_inode_size)); err != nil {
+		return err
+	}
+	if err := im.sb.UpdateBitmapInode(im.diskPath, inodeNum); err != nil {
+		return err
+	}
+	im.sb.S_free_inodes_count--
+	return im.sb.LinkInode(im.diskPath, parentNum, entry.Name, inodeNum)
+}
+
+// applyAttributes copia permisos, propietario y fechas del nodo de la imagen a la carpeta creada
+func (im *ext2Importer) applyAttributes(parentDirs []string, entry structures.Ext2ImageEntry) error {
+	inodeNum, err := findInode(im.sb, im.diskPath, parentDirs, entry.Name)
+	if err != nil {
+		return err
+	}
+	offset := int64(im.sb.S_inode_start + inodeNum*im.sb.S_inode_size)
+	inode := &structures.Inode{}
+	if err := inode.Deserialize(im.diskPath, offset); err != nil {
+		return err
+	}
+	setImageAttributes(inode, entry.Node)
+	return inode.Serialize(im.diskPath, offset)
+}
+
+// setImageAttributes copia permisos, propietario y fechas de un nodo al inodo
+func setImageAttributes(inode *structures.Inode, node *structures.Ext2ImageNode) {
+	inode.I_uid = int32(node.Uid)
+	inode.I_gid = int32(node.Gid)
+	copy(inode.I_perm[:], fmt.Sprintf("%03o", node.Perm&0o777))
+	inode.I_atime = float32(node.Atime)
+	inode.I_ctime = float32(node.Ctime)
+	inode.I_mtime = float32(node.Mtime)
+}
@@ -0,0 +1,141 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+	utils "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/utils"
+)
+
+// IMPORTFILE estructura que representa el comando import_file con sus parámetros
+type IMPORTFILE struct {
+	src  string // Ruta del archivo en el anfitrión
+	path string // Ruta del archivo dentro de la partición
+	r    bool   // Crear carpetas padre recursivamente
+}
+
+/*
+   import_file -src=/home/user/b.bin -path=/home/b.bin
+   import_file -src=/home/user/b.bin -path=/datos/nuevos/b.bin -r
+*/
+
+func ParseImportFile(tokens []string) (string, error) {
+	cmd := &IMPORTFILE{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		key := strings.ToLower(parts[0])
+		if key == "-r" && len(parts) == 1 {
+			cmd.r = true
+			continue
+		}
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		value := strings.Trim(parts[1], "\"")
+
+		switch key {
+		case "-src":
+			cmd.src = value
+		case "-path":
+			if !strings.HasPrefix(value, "/") {
+				return "", errors.New("la ruta debe ser absoluta")
+			}
+			cmd.path = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.src == "" || cmd.path == "" {
+		return "", errors.New("faltan parámetros requeridos: -src, -path")
+	}
+
+	size, err := commandImportFile(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al importar el archivo: %v", err)
+	}
+	return fmt.Sprintf("IMPORT_FILE: %s copiado a %s (%d bytes)", cmd.src, cmd.path, size), nil
+}
+
+// commandImportFile copia un archivo del anfitrión a la partición de la sesión. Si el
+// archivo ya existe se reemplaza su contenido conservando propietario y permisos
+func commandImportFile(imp *IMPORTFILE) (int, error) {
+	if stores.CurrentSession.ID == "" {
+		return 0, errors.New("no hay sesión activa, inicie sesión primero")
+	}
+
+	// La ruta puede pasar por carpetas donde se montaron otras particiones con mount -at
+	target, err := resolveSessionPath(imp.path)
+	if err != nil {
+		return 0, err
+	}
+	sb, diskPath := target.sb, target.diskPath
+
+	content, err := os.ReadFile(imp.src)
+	if err != nil {
+		return 0, fmt.Errorf("error al leer %s: %v", imp.src, err)
+	}
+	if limit := structures.MaxFileBlocks * int(sb.S_block_size); len(content) > limit {
+		return 0, fmt.Errorf("%s tiene %d bytes, el máximo es %d", imp.src, len(content), limit)
+	}
+
+	parentDirs, fileName := utils.GetParentDirectories(target.path)
+	if fileName == "" {
+		return 0, errors.New("la ruta debe indicar un archivo")
+	}
+	if len(fileName) > 12 {
+		return 0, fmt.Errorf("el nombre %s excede 12 caracteres", fileName)
+	}
+
+	// Liberar espacio de la papelera si quedan pocos bloques libres
+	if _, err := purgeTrash(sb, diskPath); err != nil {
+		return 0, fmt.Errorf("error al purgar la papelera: %v", err)
+	}
+
+	if len(parentDirs) > 0 {
+		if imp.r {
+			if err := createParentFolders(sb, diskPath, parentDirs); err != nil {
+				return 0, fmt.Errorf("error al crear directorios padres: %v", err)
+			}
+		} else if !checkParentExists(sb, diskPath, parentDirs) {
+			return 0, fmt.Errorf("el directorio padre %s no existe (use -r para crearlo)", strings.Join(parentDirs, "/"))
+		}
+	}
+
+	if inodeNum, err := findInode(sb, diskPath, parentDirs, fileName); err == nil {
+		offset := int64(sb.S_inode_start + inodeNum*sb.S_inode_size)
+		inode := &structures.Inode{}
+		if err := inode.Deserialize(diskPath, offset); err != nil {
+			return 0, fmt.Errorf("error al leer inodo %d: %v", inodeNum, err)
+		}
+		if inode.I_type[0] != '1' {
+			return 0, fmt.Errorf("%s es una carpeta", imp.path)
+		}
+		if !checkWritePermission(inode, stores.CurrentSession) {
+			return 0, fmt.Errorf("no tiene permiso de escritura sobre %s", imp.path)
+		}
+		if err := sb.WriteFileContent(diskPath, inode, content); err != nil {
+			return 0, err
+		}
+		inode.I_mtime = float32(time.Now().Unix())
+		if err := inode.Serialize(diskPath, offset); err != nil {
+			return 0, fmt.Errorf("error al actualizar inodo %d: %v", inodeNum, err)
+		}
+	} else if err := createFile(sb, diskPath, parentDirs, fileName, string(content)); err != nil {
+		return 0, fmt.Errorf("error al crear el archivo: %v", err)
+	}
+
+	if err := sb.Serialize(diskPath, sb.Offset()); err != nil {
+		return 0, fmt.Errorf("error al actualizar superbloque: %v", err)
+	}
+	if err := AddJournalEntry(sb, diskPath, "importfile", target.path, imp.src); err != nil {
+		return 0, fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	return len(content), nil
+}
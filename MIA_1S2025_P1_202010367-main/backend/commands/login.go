@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"os"
@@ -126,20 +125,11 @@ func commandLogin(login *LOGIN) error {
 	}
 
 	// Leer todos los bloques del inodo
-	var content strings.Builder
-	for i, blockNum := range usersInode.I_block[:12] {
-		if blockNum == -1 {
-			break
-		}
-		fileBlock := &structures.FileBlock{}
-		err = fileBlock.Deserialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*partitionSuperblock.S_block_size))
-		if err != nil {
-			return fmt.Errorf("error al leer el bloque %d de users.txt: %w", blockNum, err)
-		}
-		content.Write(bytes.Trim(fileBlock.B_content[:], "\x00"))
-		fmt.Printf("DEBUG: Bloque %d leído: %s\n", i, strings.Trim(string(fileBlock.B_content[:]), "\x00"))
+	raw, err := partitionSuperblock.ReadFileContent(partitionPath, usersInode)
+	if err != nil {
+		return fmt.Errorf("error al leer users.txt: %w", err)
 	}
-	usersContent := strings.TrimSpace(content.String())
+	usersContent := strings.TrimSpace(string(raw))
 	fmt.Printf("DEBUG: Contenido de users.txt en login:\n%s\n", usersContent)
 
 	// Mapa para almacenar GIDs de grupos
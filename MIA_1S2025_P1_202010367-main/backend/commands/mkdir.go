@@ -70,20 +70,22 @@ func commandMkdir(mkdir *MKDIR) error {
 		return errors.New("debe iniciar sesión primero")
 	}
 
-	// Obtener la partición montada usando el ID de la sesión
-	partitionSuperblock, mountedPartition, partitionPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	// Obtener la partición que contiene la ruta, que puede pasar por carpetas donde se
+	// montaron otras particiones con mount -at
+	target, err := resolveSessionPath(mkdir.path)
 	if err != nil {
-		return fmt.Errorf("error al obtener la partición montada: %w", err)
+		return err
 	}
+	partitionSuperblock, mountedPartition, partitionPath := target.sb, target.partition, target.diskPath
 
 	// Crear el directorio
-	err = createDirectory(mkdir.path, partitionSuperblock, partitionPath, mountedPartition)
+	err = createDirectory(target.path, partitionSuperblock, partitionPath, mountedPartition)
 	if err != nil {
 		return err
 	}
 
 	// Registrar en el Journal
-	err = AddJournalEntry(partitionSuperblock, partitionPath, "mkdir", mkdir.path, "-")
+	err = AddJournalEntry(partitionSuperblock, partitionPath, "mkdir", target.path, "-")
 	if err != nil {
 		return fmt.Errorf("error al registrar en el Journal: %v", err)
 	}
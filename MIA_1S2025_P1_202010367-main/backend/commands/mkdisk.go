@@ -3,6 +3,7 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
@@ -16,10 +17,12 @@ import (
 )
 
 type MKDISK struct {
-	size int
-	unit string
-	fit  string
-	path string
+	size   int
+	unit   string
+	fit    string
+	path   string
+	table  string // Tipo de tabla de particiones (MBR, MSDOS o GPT)
+	sparse bool   // Opción -sparse: el archivo se crea sin escribir los ceros
 }
 
 func ParseMkdisk(tokens []string) (string, error) {
@@ -34,10 +37,11 @@ func ParseMkdisk(tokens []string) (string, error) {
 
 	// Conjunto de parámetros válidos
 	validParams := map[string]bool{
-		"-size": true,
-		"-unit": true,
-		"-fit":  true,
-		"-path": true,
+		"-size":  true,
+		"-unit":  true,
+		"-fit":   true,
+		"-path":  true,
+		"-table": true,
 	}
 
 	// Procesar cada parámetro encontrado
@@ -82,6 +86,19 @@ func ParseMkdisk(tokens []string) (string, error) {
 				return "", errors.New("el path no puede estar vacío")
 			}
 			cmd.path = value
+		case "-table":
+			value = strings.ToUpper(value)
+			if value != "MBR" && value != "MSDOS" && value != "GPT" {
+				return "", errors.New("la tabla debe ser MBR, MSDOS o GPT")
+			}
+			cmd.table = value
+		}
+	}
+
+	// Las opciones sin valor no tienen la forma -clave=valor
+	for _, token := range tokens {
+		if strings.EqualFold(token, "-sparse") {
+			cmd.sparse = true
 		}
 	}
 
@@ -100,6 +117,9 @@ func ParseMkdisk(tokens []string) (string, error) {
 	if cmd.fit == "" {
 		cmd.fit = "FF"
 	}
+	if cmd.table == "" {
+		cmd.table = "MBR"
+	}
 
 	// Ejecutar el comando solo si todas las validaciones pasan
 	err := commandMkdisk(cmd)
@@ -116,6 +136,10 @@ func commandMkdisk(mkdisk *MKDISK) error {
 	if err != nil {
 		return err
 	}
+	// Las tablas guardan el tamaño del disco en un int32
+	if int64(sizeBytes) > math.MaxInt32 {
+		return fmt.Errorf("el tamaño máximo de un disco es %d bytes", math.MaxInt32)
+	}
 
 	// Crear el disco
 	err = createDisk(mkdisk, sizeBytes)
@@ -123,9 +147,18 @@ func commandMkdisk(mkdisk *MKDISK) error {
 		return err
 	}
 
-	// Crear el MBR
-	err = createMBR(mkdisk, sizeBytes)
+	// Crear la tabla de particiones
+	switch mkdisk.table {
+	case "GPT":
+		err = createGPT(mkdisk, sizeBytes)
+	case "MSDOS":
+		err = createMSDOS(mkdisk, sizeBytes)
+	default:
+		err = createMBR(mkdisk, sizeBytes)
+	}
 	if err != nil {
+		// Sin tabla de particiones el archivo no sirve como disco
+		os.Remove(mkdisk.path)
 		return err
 	}
 
@@ -146,6 +179,12 @@ func createDisk(mkdisk *MKDISK, sizeBytes int) error {
 	}
 	defer file.Close()
 
+	// Un archivo disperso tiene el tamaño del disco pero no ocupa espacio en el anfitrión
+	// hasta que se escriben datos; las lecturas de las zonas no escritas devuelven ceros
+	if mkdisk.sparse {
+		return file.Truncate(int64(sizeBytes))
+	}
+
 	// Escribir ceros usando un buffer de 1 MB
 	buffer := make([]byte, 1024*1024)
 	for sizeBytes > 0 {
@@ -161,18 +200,20 @@ func createDisk(mkdisk *MKDISK, sizeBytes int) error {
 	return nil
 }
 
-func createMBR(mkdisk *MKDISK, sizeBytes int) error {
-	var fitByte byte
-	switch mkdisk.fit {
-	case "FF":
-		fitByte = 'F'
+// diskFitByte convierte el ajuste del disco a su representación de un byte
+func diskFitByte(fit string) byte {
+	switch fit {
 	case "BF":
-		fitByte = 'B'
+		return 'B'
 	case "WF":
-		fitByte = 'W'
+		return 'W'
 	default:
-		return nil
+		return 'F'
 	}
+}
+
+func createMBR(mkdisk *MKDISK, sizeBytes int) error {
+	fitByte := diskFitByte(mkdisk.fit)
 
 	mbr := &structures.MBR{
 		Mbr_size:           int32(sizeBytes),
@@ -180,10 +221,10 @@ func createMBR(mkdisk *MKDISK, sizeBytes int) error {
 		Mbr_disk_signature: rand.Int31(),
 		Mbr_disk_fit:       [1]byte{fitByte},
 		Mbr_partitions: [4]structures.Partition{
-			{Part_status: [1]byte{'N'}, Part_type: [1]byte{'N'}, Part_fit: [1]byte{'N'}, Part_start: -1, Part_size: -1, Part_name: [16]byte{'N'}, Part_correlative: -1, Part_id: [4]byte{'N'}},
-			{Part_status: [1]byte{'N'}, Part_type: [1]byte{'N'}, Part_fit: [1]byte{'N'}, Part_start: -1, Part_size: -1, Part_name: [16]byte{'N'}, Part_correlative: -1, Part_id: [4]byte{'N'}},
-			{Part_status: [1]byte{'N'}, Part_type: [1]byte{'N'}, Part_fit: [1]byte{'N'}, Part_start: -1, Part_size: -1, Part_name: [16]byte{'N'}, Part_correlative: -1, Part_id: [4]byte{'N'}},
-			{Part_status: [1]byte{'N'}, Part_type: [1]byte{'N'}, Part_fit: [1]byte{'N'}, Part_start: -1, Part_size: -1, Part_name: [16]byte{'N'}, Part_correlative: -1, Part_id: [4]byte{'N'}},
+			structures.EmptyPartition(),
+			structures.EmptyPartition(),
+			structures.EmptyPartition(),
+			structures.EmptyPartition(),
 		},
 	}
 
@@ -194,3 +235,18 @@ func createMBR(mkdisk *MKDISK, sizeBytes int) error {
 
 	return nil
 }
+
+// createGPT escribe un MBR protector y una tabla GPT vacía con su copia de respaldo
+func createGPT(mkdisk *MKDISK, sizeBytes int) error {
+	gpt, err := structures.NewGPT(int32(sizeBytes), float32(time.Now().Unix()), rand.Int31(), diskFitByte(mkdisk.fit))
+	if err != nil {
+		return err
+	}
+	return gpt.Serialize(mkdisk.path)
+}
+
+// createMSDOS escribe un MBR con el formato estándar que leen las herramientas del anfitrión
+func createMSDOS(mkdisk *MKDISK, sizeBytes int) error {
+	table := structures.NewMSDOSTable(int32(sizeBytes), float32(time.Now().Unix()), rand.Int31(), diskFitByte(mkdisk.fit))
+	return table.Serialize(mkdisk.path)
+}
@@ -87,14 +87,21 @@ func commandMkfile(mkfile *MKFILE) error {
 		return errors.New("debe iniciar sesión primero")
 	}
 
-	// Obtener la partición montada
-	sb, mountedPartition, diskPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	// Obtener la partición que contiene la ruta, que puede pasar por carpetas donde se
+	// montaron otras particiones con mount -at
+	target, err := resolveSessionPath(mkfile.path)
 	if err != nil {
-		return fmt.Errorf("error al obtener la partición montada: %w", err)
+		return err
+	}
+	sb, mountedPartition, diskPath := target.sb, target.partition, target.diskPath
+
+	// Liberar espacio de la papelera si quedan pocos bloques libres
+	if _, err := purgeTrash(sb, diskPath); err != nil {
+		return fmt.Errorf("error al purgar la papelera: %w", err)
 	}
 
 	// Separar directorios padres y nombre del archivo
-	parentDirs, fileName := utils.GetParentDirectories(mkfile.path)
+	parentDirs, fileName := utils.GetParentDirectories(target.path)
 
 	// Manejar directorios padres
 	if len(parentDirs) > 0 {
@@ -142,7 +149,7 @@ func commandMkfile(mkfile *MKFILE) error {
 	if contentToLog == "" {
 		contentToLog = "-"
 	}
-	err = AddJournalEntry(sb, diskPath, "mkfile", mkfile.path, contentToLog)
+	err = AddJournalEntry(sb, diskPath, "mkfile", target.path, contentToLog)
 	if err != nil {
 		return fmt.Errorf("error al registrar en el Journal: %v", err)
 	}
@@ -235,14 +242,20 @@ func createParentFolders(sb *structures.SuperBlock, diskPath string, parentDirs
 			}
 
 			// Encontrar inodo y bloque libres
-			newInodeIndex, err := sb.FindFreeInode(diskPath)
+			newInodeIndex, err := sb.FindFreeInodeNear(diskPath, currentInode)
 			if err != nil {
 				return fmt.Errorf("error al encontrar inodo libre: %v", err)
 			}
-			newBlockIndex, err := sb.FindFreeBlock(diskPath)
+			newBlockIndex, err := sb.FindFreeBlockNear(diskPath, structures.BlockGoal(inode))
 			if err != nil {
 				return fmt.Errorf("error al encontrar bloque libre: %v", err)
 			}
+			// Se marca de inmediato para que el padre no reciba el mismo bloque si necesita uno nuevo
+			err = sb.UpdateBitmapBlock(diskPath, newBlockIndex)
+			if err != nil {
+				return err
+			}
+			sb.S_free_blocks_count--
 
 			// Crear nuevo inodo para la carpeta
 			newInode := &structures.Inode{
@@ -252,6 +265,7 @@ func createParentFolders(sb *structures.SuperBlock, diskPath string, parentDirs
 				I_atime: float32(time.Now().Unix()),
 				I_ctime: float32(time.Now().Unix()),
 				I_mtime: float32(time.Now().Unix()),
+				I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
 				I_type:  [1]byte{'0'},           // Carpeta
 				I_perm:  [3]byte{'6', '6', '4'}, // Permisos 664
 			}
@@ -289,7 +303,7 @@ func createParentFolders(sb *structures.SuperBlock, diskPath string, parentDirs
 						break
 					}
 				} else if j < 12 { // Usar bloques directos
-					newParentBlockNum, err := sb.FindFreeBlock(diskPath)
+					newParentBlockNum, err := sb.FindFreeBlockNear(diskPath, structures.BlockGoal(inode))
 					if err != nil {
 						return fmt.Errorf("error al encontrar bloque libre para padre: %v", err)
 					}
@@ -326,11 +340,6 @@ func createParentFolders(sb *structures.SuperBlock, diskPath string, parentDirs
 			if err != nil {
 				return fmt.Errorf("error al serializar bloque %d: %v", newBlockIndex, err)
 			}
-			err = sb.UpdateBitmapBlock(diskPath, newBlockIndex)
-			if err != nil {
-				return err
-			}
-			sb.S_free_blocks_count--
 
 			// Serializar nuevo inodo
 			err = newInode.Serialize(diskPath, int64(sb.S_inode_start+newInodeIndex*sb.S_inode_size))
@@ -436,12 +445,19 @@ func createFile(sb *structures.SuperBlock, diskPath string, parentDirs []string,
 				break
 			}
 		} else if i < 12 {
-			newBlockNum, err := sb.FindFreeBlock(diskPath)
+			newBlockNum, err := sb.FindFreeBlockNear(diskPath, structures.BlockGoal(inode))
 			if err != nil {
 				return fmt.Errorf("error al encontrar bloque libre: %v", err)
 			}
 			inode.I_block[i] = newBlockNum
-			blockToUpdate = &structures.FolderBlock{}
+			blockToUpdate = &structures.FolderBlock{
+				B_content: [4]structures.FolderContent{
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+				},
+			}
 			targetBlockIndex = newBlockNum
 			contentIndex = 0
 
@@ -474,7 +490,7 @@ func createFile(sb *structures.SuperBlock, diskPath string, parentDirs []string,
 	if err != nil {
 		return fmt.Errorf("error convirtiendo GID: %v", err)
 	}
-	newInodeNum, err := sb.FindFreeInode(diskPath)
+	newInodeNum, err := sb.FindFreeInodeNear(diskPath, currentInode)
 	if err != nil {
 		return fmt.Errorf("error al encontrar inodo libre: %v", err)
 	}
@@ -485,6 +501,7 @@ func createFile(sb *structures.SuperBlock, diskPath string, parentDirs []string,
 		I_atime: float32(time.Now().Unix()),
 		I_ctime: float32(time.Now().Unix()),
 		I_mtime: float32(time.Now().Unix()),
+		I_block: [15]int32{-1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
 		I_type:  [1]byte{'1'},
 		I_perm:  [3]byte{'6', '6', '4'},
 	}
@@ -497,7 +514,10 @@ func createFile(sb *structures.SuperBlock, diskPath string, parentDirs []string,
 		return err
 	}
 
-	// Asignar bloques para el contenido
+	// Asignar bloques para el contenido, a continuación de los bloques de la carpeta padre
+	if err := sb.SetBlockGoal(diskPath, structures.BlockGoal(inode)); err != nil {
+		return err
+	}
 	if content == "" {
 		newBlockNum, err := sb.FindFreeBlock(diskPath)
 		if err != nil {
@@ -515,27 +535,10 @@ func createFile(sb *structures.SuperBlock, diskPath string, parentDirs []string,
 		}
 		sb.S_free_blocks_count--
 	} else {
-		chunks := utils.SplitStringIntoChunks(content)
-		if len(chunks) > 12 {
-			return fmt.Errorf("contenido demasiado grande, máximo 12 bloques directos")
-		}
-		for i, chunk := range chunks {
-			newBlockNum, err := sb.FindFreeBlock(diskPath)
-			if err != nil {
-				return fmt.Errorf("error al encontrar bloque libre: %v", err)
-			}
-			fileInode.I_block[i] = newBlockNum
-			fileBlock := &structures.FileBlock{B_content: [64]byte{}}
-			copy(fileBlock.B_content[:], chunk)
-			err = fileBlock.Serialize(diskPath, int64(sb.S_block_start+newBlockNum*sb.S_block_size))
-			if err != nil {
-				return err
-			}
-			err = sb.UpdateBitmapBlock(diskPath, newBlockNum)
-			if err != nil {
-				return err
-			}
-			sb.S_free_blocks_count--
+		// Los archivos de más de 12 bloques usan los apuntadores indirectos
+		err = sb.WriteFileContent(diskPath, fileInode, []byte(content))
+		if err != nil {
+			return err
 		}
 	}
 
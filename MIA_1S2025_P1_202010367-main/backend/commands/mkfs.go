@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,14 +16,16 @@ import (
 
 // MKFS estructura que representa el comando mkfs con sus parámetros
 type MKFS struct {
-	id  string // ID del disco
-	typ string // Tipo de formato (full)
-	fs  string // Tipo de sistema de archivos (2fs o 3fs)
+	id   string // ID del disco
+	typ  string // Tipo de formato (full)
+	fs   string // Tipo de sistema de archivos (2fs o 3fs)
+	from string // Carpeta del anfitrión cuyo contenido se copia al formatear
 }
 
 /*
    mkfs -id=vd1 -type=full
    mkfs -id=vd2
+   mkfs -id=vd1 -fs=3fs -from=/home/user/datos
 */
 
 func ParseMkfs(tokens []string) (string, error) {
@@ -53,6 +56,11 @@ func ParseMkfs(tokens []string) (string, error) {
 				return "", errors.New("el fs debe ser 2fs o 3fs")
 			}
 			cmd.fs = value
+		case "-from":
+			cmd.from = strings.Trim(value, "\"")
+			if cmd.from == "" {
+				return "", errors.New("la carpeta de origen no puede estar vacía")
+			}
 		default:
 			return "", fmt.Errorf("parámetro desconocido: %s", key)
 		}
@@ -62,38 +70,51 @@ func ParseMkfs(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -id")
 	}
 
-	err := commandMkfs(cmd)
+	im, err := commandMkfs(cmd)
 	if err != nil {
 		return "", fmt.Errorf("error al formatear la partición: %v", err)
 	}
 
-	return fmt.Sprintf("MKFS: Partición %s formateada con éxito con sistema %s", cmd.id, cmd.fs), nil
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("MKFS: Partición %s formateada con éxito con sistema %s", cmd.id, cmd.fs))
+	if im != nil {
+		output.WriteString(fmt.Sprintf("\nSe copiaron %d carpetas y %d archivos de %s", im.folders, im.files, cmd.from))
+		if len(im.warnings) > 0 {
+			output.WriteString(fmt.Sprintf("\nSe omitieron %d elementos:", len(im.warnings)))
+			for _, warning := range im.warnings {
+				output.WriteString("\n  " + warning)
+			}
+		}
+	}
+	return output.String(), nil
 }
 
-func commandMkfs(mkfs *MKFS) error {
+// commandMkfs formatea la partición. Con -from devuelve el resultado de la copia del
+// contenido del anfitrión; si no, el importador es nil
+func commandMkfs(mkfs *MKFS) (*ext2Importer, error) {
 	partitionPath, exists := stores.MountedPartitions[mkfs.id]
 	if !exists {
-		return errors.New("partición no montada")
+		return nil, errors.New("partición no montada")
 	}
 
 	file, err := os.OpenFile(partitionPath, os.O_RDWR, 0644)
 	if err != nil {
-		return fmt.Errorf("error al abrir disco: %v", err)
+		return nil, fmt.Errorf("error al abrir disco: %v", err)
 	}
 	defer file.Close()
 
-	var mbr structures.MBR
-	if err := mbr.Deserialize(partitionPath); err != nil {
-		return fmt.Errorf("error al deserializar MBR: %v", err)
+	table, err := structures.ReadPartitionTable(partitionPath)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer la tabla de particiones: %v", err)
 	}
 
 	var partition *structures.Partition
 	var startOffset int64
 	var partitionSize int32
-	for _, p := range mbr.Mbr_partitions {
+	for _, p := range table.Partitions() {
 		partID := strings.Trim(string(p.Part_id[:]), "\x00")
 		if partID == mkfs.id {
-			partition = &p
+			partition = p
 			startOffset = int64(p.Part_start)
 			partitionSize = p.Part_size
 			break
@@ -101,22 +122,16 @@ func commandMkfs(mkfs *MKFS) error {
 	}
 
 	if partition == nil {
-		var extPartition *structures.Partition
-		for _, p := range mbr.Mbr_partitions {
-			if p.Part_type[0] == 'E' && p.Part_status[0] != 'N' {
-				extPartition = &p
-				break
-			}
-		}
-		if extPartition == nil {
-			return fmt.Errorf("partición %s no encontrada en el disco", mkfs.id)
+		extPartition := table.ExtendedPartition()
+		if extPartition == nil || extPartition.Part_status[0] == 'N' {
+			return nil, fmt.Errorf("partición %s no encontrada en el disco", mkfs.id)
 		}
 
 		var currentEBR structures.EBR
 		currentOffset := int64(extPartition.Part_start)
 		for {
 			if err := currentEBR.Deserialize(file, currentOffset); err != nil {
-				return fmt.Errorf("error al leer EBR: %v", err)
+				return nil, fmt.Errorf("error al leer EBR: %v", err)
 			}
 			partID := strings.Trim(string(currentEBR.Part_id[:]), "\x00")
 			if partID == mkfs.id {
@@ -125,7 +140,7 @@ func commandMkfs(mkfs *MKFS) error {
 				break
 			}
 			if currentEBR.Part_next == -1 {
-				return fmt.Errorf("partición lógica %s no encontrada", mkfs.id)
+				return nil, fmt.Errorf("partición lógica %s no encontrada", mkfs.id)
 			}
 			currentOffset = int64(currentEBR.Part_next)
 		}
@@ -133,34 +148,189 @@ func commandMkfs(mkfs *MKFS) error {
 
 	var sbCheck structures.SuperBlock
 	if err := sbCheck.Deserialize(partitionPath, startOffset); err == nil && sbCheck.S_magic == 0xEF53 {
-		return errors.New("la partición ya está formateada")
+		return nil, errors.New("la partición ya está formateada")
 	}
 
 	n := calculateN(partitionSize, mkfs.fs)
 	fmt.Printf("DEBUG: partitionSize=%d, n=%d\n", partitionSize, n)
 	superBlock := createSuperBlock(startOffset, n, mkfs.fs)
 
+	// El contenido del anfitrión se revisa antes de formatear para no dejar la partición a medias
+	var hostTree *structures.Ext2ImageNode
+	var hostWarnings []string
+	if mkfs.from != "" {
+		hostTree, hostWarnings, err = readHostTree(mkfs.from, structures.MaxFileBlocks*superBlock.S_block_size)
+		if err != nil {
+			return nil, err
+		}
+		inodes, blocks, err := hostTreeUsage(hostTree, "/", true)
+		if err != nil {
+			return nil, err
+		}
+		if inodes > superBlock.S_free_inodes_count || blocks > superBlock.S_free_blocks_count {
+			return nil, fmt.Errorf("el contenido de %s requiere %d inodos y %d bloques, pero la partición solo tiene %d inodos y %d bloques libres",
+				mkfs.from, inodes, blocks, superBlock.S_free_inodes_count, superBlock.S_free_blocks_count)
+		}
+	}
+
 	if mkfs.fs == "3fs" {
 		if err := structures.FormatEXT3(partitionPath, int32(startOffset), partitionSize); err != nil {
-			return err
+			return nil, err
 		}
 		// Inicializar el Journal
 		if err := initializeJournal(superBlock, partitionPath); err != nil {
-			return fmt.Errorf("error al inicializar Journal: %v", err)
+			return nil, fmt.Errorf("error al inicializar Journal: %v", err)
 		}
 	} else {
 		if err := superBlock.CreateBitMaps(file); err != nil {
-			return err
+			return nil, err
 		}
 		if err := superBlock.CreateUsersFile(partitionPath); err != nil {
-			return err
+			return nil, err
 		}
 		if err := superBlock.Serialize(partitionPath, startOffset); err != nil {
-			return err
+			return nil, err
 		}
 	}
 
-	return nil
+	// Una partición recién formateada cuenta como verificada para la política de fsck
+	if err := stores.MarkChecked(partitionPath, stores.MountedName(mkfs.id)); err != nil {
+		return nil, err
+	}
+
+	if hostTree == nil {
+		return nil, nil
+	}
+	sb := &structures.SuperBlock{}
+	if err := sb.Deserialize(partitionPath, startOffset); err != nil {
+		return nil, fmt.Errorf("error al leer el superbloque: %v", err)
+	}
+	im := &ext2Importer{
+		sb:       sb,
+		diskPath: partitionPath,
+		seen:     map[*structures.Ext2ImageNode]string{hostTree: "/"},
+		warnings: hostWarnings,
+	}
+	importErr := im.importDir(hostTree, nil, "/")
+	if err := sb.Serialize(partitionPath, startOffset); err != nil {
+		return nil, fmt.Errorf("error al actualizar superbloque: %v", err)
+	}
+	if importErr != nil {
+		return nil, fmt.Errorf("error al copiar %s: %v", mkfs.from, importErr)
+	}
+	return im, nil
+}
+
+// readHostTree lee recursivamente una carpeta del anfitrión como árbol de nodos. Los
+// enlaces simbólicos, archivos especiales, nombres de más de 12 caracteres y archivos
+// mayores a maxFile bytes no se pueden representar y se devuelven como advertencias.
+// Los archivos quedan a nombre de root; las tres fechas toman la de modificación
+func readHostTree(dir string, maxFile int32) (*structures.Ext2ImageNode, []string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error al leer %s: %v", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("%s no es una carpeta", dir)
+	}
+
+	var warnings []string
+	var read func(hostPath, treePath string, info os.FileInfo) (*structures.Ext2ImageNode, error)
+	read = func(hostPath, treePath string, info os.FileInfo) (*structures.Ext2ImageNode, error) {
+		mtime := uint32(info.ModTime().Unix())
+		node := &structures.Ext2ImageNode{
+			IsDir: info.IsDir(),
+			Perm:  uint16(info.Mode().Perm()),
+			Uid:   1,
+			Gid:   1,
+			Atime: mtime,
+			Ctime: mtime,
+			Mtime: mtime,
+		}
+		if !node.IsDir {
+			content, err := os.ReadFile(hostPath)
+			if err != nil {
+				return nil, fmt.Errorf("error al leer %s: %v", hostPath, err)
+			}
+			node.Content = content
+			return node, nil
+		}
+
+		entries, err := os.ReadDir(hostPath)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer %s: %v", hostPath, err)
+		}
+		for _, entry := range entries {
+			childPath := treePath + entry.Name()
+			childInfo, err := entry.Info()
+			if err != nil {
+				return nil, fmt.Errorf("error al leer %s: %v", childPath, err)
+			}
+			switch {
+			case childInfo.Mode()&os.ModeSymlink != 0:
+				warnings = append(warnings, fmt.Sprintf("%s: enlace simbólico", childPath))
+				continue
+			case !childInfo.IsDir() && !childInfo.Mode().IsRegular():
+				warnings = append(warnings, fmt.Sprintf("%s: archivo especial", childPath))
+				continue
+			case len(entry.Name()) > 12:
+				warnings = append(warnings, fmt.Sprintf("%s: el nombre excede 12 caracteres", childPath))
+				continue
+			case !childInfo.IsDir() && childInfo.Size() > int64(maxFile):
+				warnings = append(warnings, fmt.Sprintf("%s: %d bytes exceden el máximo de %d", childPath, childInfo.Size(), maxFile))
+				continue
+			}
+
+			child, err := read(filepath.Join(hostPath, entry.Name()), childPath+"/", childInfo)
+			if err != nil {
+				return nil, err
+			}
+			node.Children = append(node.Children, structures.Ext2ImageEntry{Name: entry.Name(), Node: child})
+		}
+		return node, nil
+	}
+
+	root, err := read(dir, "/", info)
+	if err != nil {
+		return nil, nil, err
+	}
+	return root, warnings, nil
+}
+
+// hostTreeUsage calcula los inodos y bloques que ocupará el árbol en una partición recién
+// formateada. La raíz ya tiene un bloque con users.txt y un espacio libre; cada carpeta
+// nueva tiene dos espacios libres en su primer bloque y 4 en cada bloque adicional
+func hostTreeUsage(node *structures.Ext2ImageNode, path string, root bool) (int32, int32, error) {
+	blockSize := int32(binary.Size(structures.FileBlock{}))
+	freeSlots, inodes, blocks := int32(2), int32(0), int32(1)
+	if root {
+		freeSlots, blocks = 1, 0
+	}
+
+	extra := int32(0)
+	if entries := int32(len(node.Children)); entries > freeSlots {
+		extra = (entries - freeSlots + 3) / 4
+	}
+	if extra > 11 {
+		return 0, 0, fmt.Errorf("la carpeta %s tiene %d entradas, el máximo es %d", path, len(node.Children), freeSlots+11*4)
+	}
+	blocks += extra
+
+	for _, entry := range node.Children {
+		inodes++
+		if !entry.Node.IsDir {
+			dataBlocks := (int32(len(entry.Node.Content)) + blockSize - 1) / blockSize
+			blocks += dataBlocks + structures.PointerBlocksFor(int(dataBlocks))
+			continue
+		}
+		childInodes, childBlocks, err := hostTreeUsage(entry.Node, path+entry.Name+"/", false)
+		if err != nil {
+			return 0, 0, err
+		}
+		inodes += childInodes
+		blocks += childBlocks
+	}
+	return inodes, blocks, nil
 }
 
 func calculateN(size int32, fs string) int32 {
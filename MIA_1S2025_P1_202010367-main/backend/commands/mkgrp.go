@@ -1,8 +1,6 @@
 package commands
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"os"
@@ -115,19 +113,11 @@ func commandMkgrp(mkgrp *MKGRP) error {
 		return errors.New("users.txt no es un archivo válido")
 	}
 
-	var content strings.Builder
-	for _, blockNum := range usersInode.I_block[:12] {
-		if blockNum == -1 {
-			break
-		}
-		fileBlock := &structures.FileBlock{}
-		err = fileBlock.Deserialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*partitionSuperblock.S_block_size))
-		if err != nil {
-			return fmt.Errorf("error al leer el bloque de users.txt: %v", err)
-		}
-		content.Write(bytes.Trim(fileBlock.B_content[:], "\x00"))
+	raw, err := partitionSuperblock.ReadFileContent(partitionPath, usersInode)
+	if err != nil {
+		return fmt.Errorf("error al leer users.txt: %v", err)
 	}
-	usersContent := strings.TrimSpace(content.String())
+	usersContent := strings.TrimSpace(string(raw))
 	fmt.Printf("DEBUG: Contenido actual de users.txt en MKGRP:\n%s\n", usersContent)
 
 	lines := strings.Split(usersContent, "\n")
@@ -156,68 +146,17 @@ func commandMkgrp(mkgrp *MKGRP) error {
 	updatedContent := usersContent + "\n" + newLine
 	fmt.Printf("DEBUG: Nuevo contenido de users.txt en MKGRP:\n%s\n", updatedContent)
 
-	blockSize := int(partitionSuperblock.S_block_size)
-	contentBytes := []byte(updatedContent)
-	numBlocksNeeded := (len(contentBytes) + blockSize - 1) / blockSize
-
-	if numBlocksNeeded > 12 {
-		return errors.New("el archivo users.txt excede el límite de bloques directos (12)")
-	}
-
-	for i := 0; i < 12; i++ {
-		if i < numBlocksNeeded {
-			start := i * blockSize
-			end := start + blockSize
-			if end > len(contentBytes) {
-				end = len(contentBytes)
-			}
-			blockContent := contentBytes[start:end]
-
-			var blockNum int32
-			if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-				blockNum = usersInode.I_block[i]
-			} else {
-				blockNum, err = findFreeBlock(partitionSuperblock, partitionPath)
-				if err != nil {
-					return err
-				}
-				usersInode.I_block[i] = blockNum
-				err = partitionSuperblock.UpdateBitmapBlock(partitionPath, blockNum)
-				if err != nil {
-					return fmt.Errorf("error al actualizar bitmap de bloques: %v", err)
-				}
-				partitionSuperblock.S_free_blocks_count--
-			}
-
-			fileBlock := &structures.FileBlock{B_content: [64]byte{}}
-			copy(fileBlock.B_content[:], blockContent)
-			err = fileBlock.Serialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*int32(partitionSuperblock.S_block_size)))
-			if err != nil {
-				return fmt.Errorf("error al escribir bloque %d: %v", blockNum, err)
-			}
-		} else if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-			blockNum := usersInode.I_block[i]
-			fileBlock := &structures.FileBlock{B_content: [64]byte{}}
-			err = fileBlock.Serialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*int32(partitionSuperblock.S_block_size)))
-			if err != nil {
-				return fmt.Errorf("error al limpiar bloque %d: %v", blockNum, err)
-			}
-			err = partitionSuperblock.UpdateBitmapBlock(partitionPath, blockNum)
-			if err != nil {
-				return fmt.Errorf("error al liberar bloque %d: %v", blockNum, err)
-			}
-			partitionSuperblock.S_free_blocks_count++
-			usersInode.I_block[i] = -1
-		}
+	// Escribir el contenido actualizado; los bloques compartidos se copian antes de modificarlos
+	err = partitionSuperblock.WriteFileContent(partitionPath, usersInode, []byte(updatedContent))
+	if err != nil {
+		return fmt.Errorf("error al escribir users.txt: %v", err)
 	}
-
-	usersInode.I_size = int32(len(contentBytes))
 	err = usersInode.Serialize(partitionPath, int64(partitionSuperblock.S_inode_start+usersInodeNum*partitionSuperblock.S_inode_size))
 	if err != nil {
 		return fmt.Errorf("error al actualizar inodo: %v", err)
 	}
 
-	err = partitionSuperblock.Serialize(partitionPath, int64(partitionSuperblock.S_bm_inode_start)-int64(binary.Size(partitionSuperblock)))
+	err = partitionSuperblock.Serialize(partitionPath, partitionSuperblock.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
@@ -230,30 +169,3 @@ func commandMkgrp(mkgrp *MKGRP) error {
 
 	return nil
 }
-
-// setBitmapBit actualiza un bit en el bitmap (0 = libre, 1 = ocupado)
-func setBitmapBit(path string, offset int64, bitIndex int, value byte) error {
-	file, err := os.OpenFile(path, os.O_RDWR, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	byteIndex := bitIndex / 8
-	bitOffset := bitIndex % 8
-
-	var currentByte [1]byte
-	_, err = file.ReadAt(currentByte[:], offset+int64(byteIndex))
-	if err != nil {
-		return err
-	}
-
-	if value == 1 {
-		currentByte[0] |= 1 << bitOffset
-	} else {
-		currentByte[0] &= ^(1 << bitOffset)
-	}
-
-	_, err = file.WriteAt(currentByte[:], offset+int64(byteIndex))
-	return err
-}
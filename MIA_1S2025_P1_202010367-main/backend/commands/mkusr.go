@@ -1,8 +1,6 @@
 package commands
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"os"
@@ -120,20 +118,11 @@ func commandMkusr(mkusr *MKUSR) error {
 		return errors.New("users.txt no es un archivo válido")
 	}
 
-	var content strings.Builder
-	for i, blockNum := range usersInode.I_block[:12] {
-		if blockNum == -1 {
-			break
-		}
-		fileBlock := &structures.FileBlock{}
-		err = fileBlock.Deserialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*partitionSuperblock.S_block_size))
-		if err != nil {
-			return fmt.Errorf("error al leer el bloque %d de users.txt: %v", blockNum, err)
-		}
-		content.Write(bytes.Trim(fileBlock.B_content[:], "\x00"))
-		fmt.Printf("DEBUG: Bloque %d leído: %s\n", i, strings.Trim(string(fileBlock.B_content[:]), "\x00"))
+	raw, err := partitionSuperblock.ReadFileContent(partitionPath, usersInode)
+	if err != nil {
+		return fmt.Errorf("error al leer users.txt: %v", err)
 	}
-	usersContent := strings.TrimSpace(content.String())
+	usersContent := strings.TrimSpace(string(raw))
 	fmt.Printf("DEBUG: Contenido actual de users.txt:\n%s\n", usersContent)
 
 	lines := strings.Split(usersContent, "\n")
@@ -166,69 +155,18 @@ func commandMkusr(mkusr *MKUSR) error {
 	updatedContent := usersContent + "\n" + newLine
 	fmt.Printf("DEBUG: Nuevo contenido de users.txt:\n%s\n", updatedContent)
 
-	blockSize := int(partitionSuperblock.S_block_size)
-	contentBytes := []byte(updatedContent)
-	numBlocksNeeded := (len(contentBytes) + blockSize - 1) / blockSize
-	if numBlocksNeeded > 12 {
-		return errors.New("el archivo users.txt excede el límite de bloques directos (12)")
-	}
-
-	for i := 0; i < 12; i++ {
-		if i < numBlocksNeeded {
-			start := i * blockSize
-			end := start + blockSize
-			if end > len(contentBytes) {
-				end = len(contentBytes)
-			}
-			blockContent := contentBytes[start:end]
-			fmt.Printf("DEBUG: Escribiendo bloque %d: %s\n", i, string(blockContent))
-
-			var blockNum int32
-			if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-				blockNum = usersInode.I_block[i]
-			} else {
-				blockNum, err = findFreeBlock(partitionSuperblock, partitionPath)
-				if err != nil {
-					return err
-				}
-				usersInode.I_block[i] = blockNum
-				err = partitionSuperblock.UpdateBitmapBlock(partitionPath, blockNum)
-				if err != nil {
-					return fmt.Errorf("error al actualizar bitmap de bloques: %v", err)
-				}
-				partitionSuperblock.S_free_blocks_count--
-			}
-
-			fileBlock := &structures.FileBlock{B_content: [64]byte{}}
-			copy(fileBlock.B_content[:], blockContent)
-			err = fileBlock.Serialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*int32(partitionSuperblock.S_block_size)))
-			if err != nil {
-				return fmt.Errorf("error al escribir bloque %d: %v", blockNum, err)
-			}
-		} else if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-			blockNum := usersInode.I_block[i]
-			fileBlock := &structures.FileBlock{B_content: [64]byte{}}
-			err = fileBlock.Serialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*int32(partitionSuperblock.S_block_size)))
-			if err != nil {
-				return fmt.Errorf("error al limpiar bloque %d: %v", blockNum, err)
-			}
-			err = partitionSuperblock.UpdateBitmapBlock(partitionPath, blockNum)
-			if err != nil {
-				return fmt.Errorf("error al liberar bloque %d: %v", blockNum, err)
-			}
-			partitionSuperblock.S_free_blocks_count++
-			usersInode.I_block[i] = -1
-		}
+	// Escribir el contenido actualizado; los bloques compartidos se copian antes de modificarlos
+	err = partitionSuperblock.WriteFileContent(partitionPath, usersInode, []byte(updatedContent))
+	if err != nil {
+		return fmt.Errorf("error al escribir users.txt: %v", err)
 	}
-
-	usersInode.I_size = int32(len(contentBytes))
 	err = usersInode.Serialize(partitionPath, int64(partitionSuperblock.S_inode_start+usersInodeNum*partitionSuperblock.S_inode_size))
 	if err != nil {
 		return fmt.Errorf("error al actualizar inodo: %v", err)
 	}
 
 	// Corregir serialización del superbloque
-	err = partitionSuperblock.Serialize(partitionPath, int64(partitionSuperblock.S_inode_start-int32(binary.Size(structures.SuperBlock{}))))
+	err = partitionSuperblock.Serialize(partitionPath, partitionSuperblock.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
@@ -241,29 +179,3 @@ func commandMkusr(mkusr *MKUSR) error {
 
 	return nil
 }
-
-func findFreeBlock(sb *structures.SuperBlock, path string) (int32, error) {
-	file, err := os.OpenFile(path, os.O_RDWR, 0644)
-	if err != nil {
-		return -1, err
-	}
-	defer file.Close()
-
-	_, err = file.Seek(int64(sb.S_bm_block_start), 0)
-	if err != nil {
-		return -1, err
-	}
-
-	bm := make([]byte, sb.S_blocks_count)
-	_, err = file.Read(bm)
-	if err != nil {
-		return -1, err
-	}
-
-	for i := int32(0); i < sb.S_blocks_count; i++ {
-		if bm[i] == '0' {
-			return i, nil
-		}
-	}
-	return -1, errors.New("no hay bloques libres disponibles")
-}
@@ -4,6 +4,7 @@ import (
 	"errors" // Paquete para manejar errores y crear nuevos errores con mensajes personalizados
 	"fmt"    // Paquete para formatear cadenas y realizar operaciones de entrada/salida
 	"os"     // Paquete para trabajar con expresiones regulares, útil para encontrar y manipular patrones en cadenas
+	"path"
 
 	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
 	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures" // Paquete que contiene las estructuras de datos necesarias para el manejo de discos y particiones
@@ -11,23 +12,31 @@ import (
 
 	// Paquete para convertir cadenas a otros tipos de datos, como enteros
 	"strings" // Paquete para manipular cadenas, como unir, dividir, y modificar contenido de cadenas
+	"time"
 )
 
 // MOUNT estructura que representa el comando mount con sus parámetros
 type MOUNT struct {
-	path string // Ruta del archivo del disco
-	name string // Nombre de la partición
+	path    string              // Ruta del archivo del disco
+	name    string              // Nombre de la partición
+	options stores.MountOptions // Opciones de montaje (-options=ro,noatime,sync,discard)
+	id      string              // Partición ya montada que se monta en una carpeta (-id)
+	at      string              // Carpeta de la sesión donde se monta la partición -id (-at)
 }
 
 // UNMOUNT estructura que representa el comando unmount con sus parámetros
 type UNMOUNT struct {
 	id string // ID de la partición a desmontar
+	at string // Carpeta de la que se desmonta la partición montada con -at
 }
 
 /*
 	mount -path=/home/Disco1.mia -name=Part1 #id=341a
 	mount -path=/home/Disco2.mia -name=Part1 #id=342a
 	mount -path=/home/Disco3.mia -name=Part2 #id=343a
+	mount -path=/home/Disco3.mia -name=Part3 -options=ro,noatime
+	mount -id=342A -at=/mnt/data
+	unmount -at=/mnt/data
 */
 
 // CommandMount parsea el comando mount y devuelve una instancia de MOUNT
@@ -53,11 +62,37 @@ func ParseMount(tokens []string) (string, error) {
 				return "", errors.New("el nombre no puede estar vacío")
 			}
 			cmd.name = value
+		case "-options":
+			options, err := stores.ParseMountOptions(value)
+			if err != nil {
+				return "", err
+			}
+			cmd.options = options
+		case "-id":
+			if value == "" {
+				return "", errors.New("el id no puede estar vacío")
+			}
+			cmd.id = strings.ToUpper(value)
+		case "-at":
+			if !strings.HasPrefix(value, "/") {
+				return "", errors.New("la carpeta de montaje debe ser una ruta absoluta")
+			}
+			cmd.at = value
 		default:
 			return "", fmt.Errorf("parámetro desconocido: %s", key)
 		}
 	}
 
+	if cmd.id != "" || cmd.at != "" {
+		if cmd.id == "" || cmd.at == "" || cmd.path != "" || cmd.name != "" {
+			return "", errors.New("-id y -at deben usarse juntos y sin -path ni -name")
+		}
+		if err := commandMountAt(cmd); err != nil {
+			return "", fmt.Errorf("error al montar la partición: %v", err)
+		}
+		return fmt.Sprintf("MOUNT: Partición %s montada en %s", cmd.id, stores.CleanPath(cmd.at)), nil
+	}
+
 	if cmd.path == "" {
 		return "", errors.New("faltan parámetros requeridos: -path")
 	}
@@ -65,12 +100,16 @@ func ParseMount(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -name")
 	}
 
-	id, err := commandMount(cmd)
+	id, warning, err := commandMount(cmd)
 	if err != nil {
 		return "", fmt.Errorf("error al montar la partición: %v", err)
 	}
 
-	return fmt.Sprintf("MOUNT: Partición %s montada correctamente con ID: %s", cmd.name, id), nil
+	output := fmt.Sprintf("MOUNT: Partición %s montada correctamente con ID: %s (%s)", cmd.name, id, cmd.options)
+	if warning != "" {
+		output += "\nAVISO: " + warning
+	}
+	return output, nil
 }
 
 // ParseUnmount parsea el comando unmount y devuelve una instancia de UNMOUNT
@@ -90,12 +129,28 @@ func ParseUnmount(tokens []string) (string, error) {
 			if value == "" {
 				return "", errors.New("el id no puede estar vacío")
 			}
-			cmd.id = value
+			cmd.id = strings.ToUpper(value)
+		case "-at":
+			if !strings.HasPrefix(value, "/") {
+				return "", errors.New("la carpeta de montaje debe ser una ruta absoluta")
+			}
+			cmd.at = value
 		default:
 			return "", fmt.Errorf("parámetro desconocido: %s", key)
 		}
 	}
 
+	if cmd.at != "" {
+		if cmd.id != "" {
+			return "", errors.New("-id y -at no pueden usarse juntos")
+		}
+		id, err := commandUnmountAt(cmd)
+		if err != nil {
+			return "", fmt.Errorf("error al desmontar la partición: %v", err)
+		}
+		return fmt.Sprintf("UNMOUNT: Partición %s desmontada de %s", id, stores.CleanPath(cmd.at)), nil
+	}
+
 	if cmd.id == "" {
 		return "", errors.New("faltan parámetros requeridos: -id")
 	}
@@ -108,94 +163,216 @@ func ParseUnmount(tokens []string) (string, error) {
 	return fmt.Sprintf("UNMOUNT: Partición con ID %s desmontada correctamente", cmd.id), nil
 }
 
-func commandMount(mount *MOUNT) (string, error) {
-	var mbr structures.MBR
-	if err := mbr.Deserialize(mount.path); err != nil {
-		return "", fmt.Errorf("error al deserializar MBR: %v", err)
+func commandMount(mount *MOUNT) (string, string, error) {
+	table, err := structures.ReadPartitionTable(mount.path)
+	if err != nil {
+		return "", "", fmt.Errorf("error al leer la tabla de particiones: %v", err)
 	}
 
 	// Verificar si la partición existe (primarias o extendidas)
-	partition, idx := mbr.GetPartitionByName(mount.name)
+	partition, _ := table.GetPartitionByName(mount.name)
 	if partition == nil {
 		// Buscar en lógicas
 		file, err := os.OpenFile(mount.path, os.O_RDWR, 0644)
 		if err != nil {
-			return "", fmt.Errorf("error al abrir disco: %v", err)
+			return "", "", fmt.Errorf("error al abrir disco: %v", err)
 		}
 		defer file.Close()
 
-		var extPartition *structures.Partition
-		for _, p := range mbr.Mbr_partitions {
-			if p.Part_type[0] == 'E' && p.Part_status[0] != 'N' {
-				extPartition = &p
-				break
-			}
-		}
-		if extPartition == nil {
-			return "", fmt.Errorf("la partición %s no existe en el disco", mount.name)
+		extPartition := table.ExtendedPartition()
+		if extPartition == nil || extPartition.Part_status[0] == 'N' {
+			return "", "", fmt.Errorf("la partición %s no existe en el disco", mount.name)
 		}
 
 		startExt := int64(extPartition.Part_start)
 		var currentEBR structures.EBR
 		err = currentEBR.Deserialize(file, startExt)
 		if err != nil || currentEBR.Part_status[0] == 0 || currentEBR.Part_status[0] == 'N' {
-			return "", fmt.Errorf("la partición %s no existe en el disco", mount.name)
+			return "", "", fmt.Errorf("la partición %s no existe en el disco", mount.name)
 		}
 
 		currentOffset := startExt
 		for {
 			ebName := strings.Trim(string(currentEBR.Part_name[:]), "\x00")
 			if ebName == mount.name {
-				if currentEBR.Part_status[0] == '1' {
-					return "", errors.New("la partición lógica ya está montada")
+				if currentEBR.Part_status[0] == '1' && isMountedID(string(currentEBR.Part_id[:]), mount.path) {
+					return "", "", errors.New("la partición lógica ya está montada")
 				}
 				// Generar ID usando utils
 				letter, correlative, err := utils.GetLetterAndPartitionCorrelative(mount.path)
 				if err != nil {
-					return "", err
+					return "", "", err
 				}
 				id := fmt.Sprintf("%s%d%s", stores.Carnet, correlative, letter)
 				currentEBR.Part_status = [1]byte{'1'}
 				copy(currentEBR.Part_id[:], id)
 				if err := currentEBR.Serialize(file, currentOffset); err != nil {
-					return "", fmt.Errorf("error al serializar EBR: %v", err)
+					return "", "", fmt.Errorf("error al serializar EBR: %v", err)
+				}
+				warning, err := stores.RecordMount(mount.path, mount.name, currentEBR.Part_start, mount.options.ReadOnly)
+				if err != nil {
+					return "", "", err
 				}
-				stores.MountedPartitions[id] = mount.path
-				return id, nil
+				if err := stores.AddMount(id, mount.path, mount.name, mount.options); err != nil {
+					return "", "", err
+				}
+				return id, warning, nil
 			}
 			if currentEBR.Part_next == -1 {
 				break
 			}
 			currentOffset = int64(currentEBR.Part_next)
 			if err := currentEBR.Deserialize(file, currentOffset); err != nil {
-				return "", fmt.Errorf("error al leer EBR: %v", err)
+				return "", "", fmt.Errorf("error al leer EBR: %v", err)
 			}
 		}
-		return "", fmt.Errorf("la partición %s no existe en el disco", mount.name)
+		return "", "", fmt.Errorf("la partición %s no existe en el disco", mount.name)
 	}
 
 	// Partición primaria encontrada
-	if partition.Part_status[0] == '1' {
-		return "", errors.New("la partición ya está montada")
+	if partition.Part_status[0] == '1' && isMountedID(string(partition.Part_id[:]), mount.path) {
+		return "", "", errors.New("la partición ya está montada")
 	}
 	if partition.Part_type[0] == 'E' {
-		return "", errors.New("no se pueden montar particiones extendidas")
+		return "", "", errors.New("no se pueden montar particiones extendidas")
 	}
 
 	// Generar ID usando utils
 	letter, correlative, err := utils.GetLetterAndPartitionCorrelative(mount.path)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	id := fmt.Sprintf("%s%d%s", stores.Carnet, correlative, letter)
 
 	partition.MountPartition(correlative, id)
-	mbr.Mbr_partitions[idx] = *partition
-	stores.MountedPartitions[id] = mount.path
-	if err := mbr.Serialize(mount.path); err != nil {
-		return "", fmt.Errorf("error al serializar MBR: %v", err)
+	if err := table.Write(mount.path); err != nil {
+		return "", "", fmt.Errorf("error al escribir la tabla de particiones: %v", err)
+	}
+	warning, err := stores.RecordMount(mount.path, mount.name, partition.Part_start, mount.options.ReadOnly)
+	if err != nil {
+		return "", "", err
+	}
+	if err := stores.AddMount(id, mount.path, mount.name, mount.options); err != nil {
+		return "", "", err
+	}
+	return id, warning, nil
+}
+
+// commandMountAt monta una partición ya montada en una carpeta del espacio de nombres de la
+// sesión. Las rutas que pasan por la carpeta continúan en la raíz de esa partición
+func commandMountAt(mount *MOUNT) error {
+	if stores.CurrentSession.ID == "" {
+		return errors.New("no hay sesión activa, inicie sesión primero")
+	}
+	sb, _, _, err := stores.GetMountedPartitionSuperblock(mount.id)
+	if err != nil {
+		return fmt.Errorf("la partición %s no está montada", mount.id)
+	}
+	if sb.S_magic != 0xEF53 {
+		return fmt.Errorf("la partición %s no tiene formato", mount.id)
+	}
+	if graft, exists := stores.GraftOf(mount.id); exists {
+		return fmt.Errorf("la partición %s ya está montada en %s:%s", mount.id, graft.Host, graft.Path)
+	}
+
+	target, err := resolveSessionPath(mount.at)
+	if err != nil {
+		return err
+	}
+	if target.path == "/" {
+		return fmt.Errorf("%s ya es la raíz de la partición %s", mount.at, target.id)
+	}
+	if stores.GraftReaches(mount.id, target.id) {
+		return fmt.Errorf("no se puede montar la partición %s dentro de sí misma", mount.id)
 	}
-	return id, nil
+	inodeNum, err := findInodeByPath(target.diskPath, target.sb, target.path)
+	if err != nil {
+		return fmt.Errorf("no se encontró la carpeta %s: %v", mount.at, err)
+	}
+	inode := &structures.Inode{}
+	if err := inode.Deserialize(target.diskPath, int64(target.sb.S_inode_start+inodeNum*target.sb.S_inode_size)); err != nil {
+		return fmt.Errorf("error al leer inodo %d: %v", inodeNum, err)
+	}
+	if inode.I_type[0] != '0' {
+		return fmt.Errorf("%s no es una carpeta", mount.at)
+	}
+
+	return stores.AddGraft(target.id, target.path, mount.id)
+}
+
+// commandUnmountAt desmonta la partición montada en una carpeta de la sesión. La partición
+// sigue montada con su ID
+func commandUnmountAt(unmount *UNMOUNT) (string, error) {
+	if stores.CurrentSession.ID == "" {
+		return "", errors.New("no hay sesión activa, inicie sesión primero")
+	}
+	// El punto de montaje se busca desde la carpeta padre; la carpeta en sí ya resuelve a la
+	// raíz de la partición montada
+	at := stores.CleanPath(unmount.at)
+	host, parent := stores.ResolvePath(stores.CurrentSession.ID, path.Dir(at))
+	point := path.Join(parent, path.Base(at))
+	id, exists := stores.GraftAt(host, point)
+	if !exists {
+		return "", fmt.Errorf("no hay ninguna partición montada en %s", at)
+	}
+	if points := stores.GraftsUnder(id, "/"); len(points) > 0 {
+		return "", fmt.Errorf("la partición %s tiene otras particiones montadas en %s", id, strings.Join(points, ", "))
+	}
+	return stores.RemoveGraft(host, point)
+}
+
+// resolvedPath es una ruta de la sesión traducida a la partición que la contiene
+type resolvedPath struct {
+	id        string                 // ID de la partición
+	sb        *structures.SuperBlock // Superbloque de la partición
+	partition *structures.Partition  // Partición en el disco
+	diskPath  string                 // Ruta del disco
+	path      string                 // Ruta dentro de la partición
+}
+
+// resolveSessionPath traduce una ruta de la sesión a la partición que la contiene, cruzando
+// las carpetas donde se montaron otras particiones con mount -at
+func resolveSessionPath(p string) (*resolvedPath, error) {
+	return resolvePath(stores.CurrentSession.ID, p)
+}
+
+// resolveEndpoint traduce un origen o destino de copy y move, que puede indicar la
+// partición con el formato ID:/ruta
+func resolveEndpoint(spec string) (*resolvedPath, error) {
+	return resolvePath(stores.SplitEndpoint(spec))
+}
+
+// resolvePath traduce una ruta del espacio de nombres de la partición id
+func resolvePath(id, p string) (*resolvedPath, error) {
+	id, rel := stores.ResolvePath(id, p)
+	sb, partition, diskPath, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		return nil, fmt.Errorf("error al obtener la partición montada %s: %v", id, err)
+	}
+	return &resolvedPath{id: id, sb: sb, partition: partition, diskPath: diskPath, path: rel}, nil
+}
+
+// recordUnmount actualiza la fecha de desmontaje del superbloque, salvo que la partición
+// no tenga formato o esté montada como solo lectura
+func recordUnmount(id, path string, partStart int32) error {
+	if stores.GetMountOptions(id).ReadOnly {
+		return nil
+	}
+	sb := &structures.SuperBlock{}
+	if err := sb.Deserialize(path, int64(partStart)); err != nil || sb.S_magic != 0xEF53 {
+		return nil
+	}
+	sb.S_umtime = float32(time.Now().Unix())
+	if err := sb.Serialize(path, int64(partStart)); err != nil {
+		return fmt.Errorf("error al actualizar el superbloque: %v", err)
+	}
+	return nil
+}
+
+// isMountedID indica si el ID guardado en el disco corresponde a un montaje activo. Un
+// estado '1' sin montaje activo quedó de una ejecución anterior y se puede volver a montar
+func isMountedID(diskID, path string) bool {
+	return stores.MountedPartitions[strings.Trim(diskID, "\x00")] == path
 }
 
 func commandUnmount(unmount *UNMOUNT) error {
@@ -204,6 +381,9 @@ func commandUnmount(unmount *UNMOUNT) error {
 	if !exists {
 		return fmt.Errorf("la partición con ID %s no está montada", unmount.id)
 	}
+	if points := stores.GraftsUnder(unmount.id, "/"); len(points) > 0 {
+		return fmt.Errorf("la partición %s tiene otras particiones montadas en %s", unmount.id, strings.Join(points, ", "))
+	}
 
 	// Abrir disco
 	file, err := os.OpenFile(path, os.O_RDWR, 0644)
@@ -212,38 +392,33 @@ func commandUnmount(unmount *UNMOUNT) error {
 	}
 	defer file.Close()
 
-	// Leer MBR
-	var mbr structures.MBR
-	if err := mbr.Deserialize(path); err != nil {
-		return fmt.Errorf("error al deserializar MBR: %v", err)
+	// Leer la tabla de particiones
+	table, err := structures.ReadPartitionTable(path)
+	if err != nil {
+		return fmt.Errorf("error al leer la tabla de particiones: %v", err)
 	}
 
 	// Buscar partición primaria
-	for i, p := range mbr.Mbr_partitions {
+	for _, p := range table.Partitions() {
 		if strings.Trim(string(p.Part_id[:]), "\x00") == unmount.id {
 			if p.Part_status[0] != '1' {
 				return fmt.Errorf("la partición con ID %s no está montada", unmount.id)
 			}
+			if err := recordUnmount(unmount.id, path, p.Part_start); err != nil {
+				return err
+			}
 			p.Part_status = [1]byte{'0'}
 			p.Part_id = [4]byte{}
-			mbr.Mbr_partitions[i] = p
-			if err := mbr.Serialize(path); err != nil {
-				return fmt.Errorf("error al serializar MBR: %v", err)
+			if err := table.Write(path); err != nil {
+				return fmt.Errorf("error al escribir la tabla de particiones: %v", err)
 			}
-			delete(stores.MountedPartitions, unmount.id)
-			return nil
+			return stores.RemoveMount(unmount.id)
 		}
 	}
 
 	// Buscar partición lógica
-	var extPartition *structures.Partition
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_type[0] == 'E' && p.Part_status[0] != 'N' {
-			extPartition = &p
-			break
-		}
-	}
-	if extPartition == nil {
+	extPartition := table.ExtendedPartition()
+	if extPartition == nil || extPartition.Part_status[0] == 'N' {
 		return fmt.Errorf("partición con ID %s no encontrada", unmount.id)
 	}
 
@@ -257,13 +432,15 @@ func commandUnmount(unmount *UNMOUNT) error {
 			if currentEBR.Part_status[0] != '1' {
 				return fmt.Errorf("la partición con ID %s no está montada", unmount.id)
 			}
+			if err := recordUnmount(unmount.id, path, currentEBR.Part_start); err != nil {
+				return err
+			}
 			currentEBR.Part_status = [1]byte{'0'}
 			currentEBR.Part_id = [4]byte{}
 			if err := currentEBR.Serialize(file, currentOffset); err != nil {
 				return fmt.Errorf("error al serializar EBR: %v", err)
 			}
-			delete(stores.MountedPartitions, unmount.id)
-			return nil
+			return stores.RemoveMount(unmount.id)
 		}
 		if currentEBR.Part_next == -1 {
 			break
@@ -273,3 +450,17 @@ func commandUnmount(unmount *UNMOUNT) error {
 
 	return fmt.Errorf("partición con ID %s no encontrada", unmount.id)
 }
+
+// touchAtime actualiza la fecha de último acceso de un inodo leído, salvo que la partición
+// id esté montada con ro o noatime
+func touchAtime(id string, sb *structures.SuperBlock, diskPath string, inodeNum int32, inode *structures.Inode) error {
+	options := stores.GetMountOptions(id)
+	if options.ReadOnly || options.NoAtime {
+		return nil
+	}
+	inode.I_atime = float32(time.Now().Unix())
+	if err := inode.Serialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size)); err != nil {
+		return fmt.Errorf("error al actualizar la fecha de acceso del inodo %d: %v", inodeNum, err)
+	}
+	return nil
+}
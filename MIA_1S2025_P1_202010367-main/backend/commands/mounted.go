@@ -33,8 +33,12 @@ func commandMounted() (string, error) {
 
 	var output strings.Builder
 	output.WriteString("MOUNTED: Particiones montadas:\n")
-	for id, path := range stores.MountedPartitions {
-		output.WriteString(fmt.Sprintf("  ID: %s  Path: %s\n", id, path))
+	for _, entry := range stores.MountEntries() {
+		output.WriteString(fmt.Sprintf("  ID: %s  Path: %s  Opciones: %s", entry.ID, entry.Path, entry.Options))
+		if graft, exists := stores.GraftOf(entry.ID); exists {
+			output.WriteString(fmt.Sprintf("  Montada en: %s:%s", graft.Host, graft.Path))
+		}
+		output.WriteString("\n")
 	}
 	return output.String(), nil
 }
@@ -13,10 +13,15 @@ import (
 )
 
 type MOVE struct {
-	path    string
-	destino string
+	path    string // Ruta origen (-path o -src, admite ID:/ruta)
+	destino string // Ruta destino completa (-destino o -dest, admite ID:/ruta)
 }
 
+/*
+   move -path=/home/a.txt -destino=/home/docs/a.txt
+   move -src=671A:/home/user -dest=672A:/user
+*/
+
 func ParseMove(tokens []string) (string, error) {
 	cmd := &MOVE{}
 
@@ -28,12 +33,12 @@ func ParseMove(tokens []string) (string, error) {
 		key := strings.ToLower(parts[0])
 		value := strings.Trim(parts[1], "\"")
 
-		if key == "-path" {
+		if key == "-path" || key == "-src" {
 			if value == "" {
 				return "", errors.New("la ruta origen no puede estar vacía")
 			}
 			cmd.path = value
-		} else if key == "-destino" {
+		} else if key == "-destino" || key == "-dest" {
 			if value == "" {
 				return "", errors.New("la ruta destino no puede estar vacía")
 			}
@@ -44,7 +49,7 @@ func ParseMove(tokens []string) (string, error) {
 	}
 
 	if cmd.path == "" || cmd.destino == "" {
-		return "", errors.New("faltan parámetros requeridos: -path, -destino")
+		return "", errors.New("faltan parámetros requeridos: -path (-src), -destino (-dest)")
 	}
 
 	err := commandMove(cmd)
@@ -60,10 +65,25 @@ func commandMove(move *MOVE) error {
 		return errors.New("no hay sesión activa, inicie sesión primero")
 	}
 
-	sb, mountedPartition, diskPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	// Las rutas pueden indicar la partición (ID:/ruta) o pasar por carpetas donde se
+	// montaron otras particiones
+	src, err := resolveEndpoint(move.path)
+	if err != nil {
+		return err
+	}
+	dest, err := resolveEndpoint(move.destino)
 	if err != nil {
-		return fmt.Errorf("error al obtener la partición montada: %v", err)
+		return err
+	}
+
+	// Un punto de montaje no se puede mover mientras tenga una partición montada
+	if points := stores.GraftsUnder(src.id, src.path); len(points) > 0 {
+		return fmt.Errorf("%s contiene puntos de montaje: %s", move.path, strings.Join(points, ", "))
+	}
+	if src.id != dest.id {
+		return transferAcross(src, dest, true)
 	}
+	sb, mountedPartition, diskPath := dest.sb, dest.partition, dest.diskPath
 
 	file, err := os.OpenFile(diskPath, os.O_RDWR, 0644)
 	if err != nil {
@@ -72,8 +92,8 @@ func commandMove(move *MOVE) error {
 	defer file.Close()
 
 	// Normalizar rutas
-	srcParentDirs, srcName := utils.GetParentDirectories(move.path)
-	destParentDirs, destName := utils.GetParentDirectories(move.destino)
+	srcParentDirs, srcName := utils.GetParentDirectories(src.path)
+	destParentDirs, destName := utils.GetParentDirectories(dest.path)
 	if srcName == "" || destName == "" {
 		return errors.New("no se puede mover o escribir en la raíz")
 	}
@@ -149,7 +169,7 @@ func commandMove(move *MOVE) error {
 
 	// Verificar que no se cree un ciclo si es una carpeta
 	if srcInode.I_type[0] == '0' {
-		isDescendant, err := isDescendant(sb, diskPath, srcInodeNum, destParentInodeNum)
+		isDescendant, err := isDescendant(sb, diskPath, destParentInodeNum, srcInodeNum)
 		if err != nil {
 			return fmt.Errorf("error al verificar ciclos: %v", err)
 		}
@@ -171,7 +191,7 @@ func commandMove(move *MOVE) error {
 	}
 
 	// Registrar en el Journal
-	err = AddJournalEntry(sb, diskPath, "move", move.path, move.destino)
+	err = AddJournalEntry(sb, diskPath, "move", src.path, dest.path)
 	if err != nil {
 		return fmt.Errorf("error al registrar en el Journal: %v", err)
 	}
@@ -179,22 +199,22 @@ func commandMove(move *MOVE) error {
 	return nil
 }
 
-// isDescendant verifica si destParentInodeNum es un descendiente de srcInodeNum
-func isDescendant(sb *structures.SuperBlock, diskPath string, srcInodeNum, destParentInodeNum int32) (bool, error) {
-	if srcInodeNum == destParentInodeNum {
+// isDescendant verifica si inodeNum es rootInodeNum o está dentro de su subárbol
+func isDescendant(sb *structures.SuperBlock, diskPath string, inodeNum, rootInodeNum int32) (bool, error) {
+	if inodeNum == rootInodeNum {
 		return true, nil
 	}
 
-	destInode := &structures.Inode{}
-	err := destInode.Deserialize(diskPath, int64(sb.S_inode_start+destParentInodeNum*sb.S_inode_size))
+	rootInode := &structures.Inode{}
+	err := rootInode.Deserialize(diskPath, int64(sb.S_inode_start+rootInodeNum*sb.S_inode_size))
 	if err != nil {
-		return false, fmt.Errorf("error al leer inodo destino %d: %v", destParentInodeNum, err)
+		return false, fmt.Errorf("error al leer inodo %d: %v", rootInodeNum, err)
 	}
-	if destInode.I_type[0] != '0' {
+	if rootInode.I_type[0] != '0' {
 		return false, nil // No es una carpeta, no puede ser descendiente
 	}
 
-	for _, blockNum := range destInode.I_block[:12] {
+	for _, blockNum := range rootInode.I_block[:12] {
 		if blockNum == -1 {
 			continue
 		}
@@ -208,7 +228,7 @@ func isDescendant(sb *structures.SuperBlock, diskPath string, srcInodeNum, destP
 			if name == "." || name == ".." || content.B_inodo == -1 {
 				continue
 			}
-			isDesc, err := isDescendant(sb, diskPath, srcInodeNum, content.B_inodo)
+			isDesc, err := isDescendant(sb, diskPath, inodeNum, content.B_inodo)
 			if err != nil {
 				return false, err
 			}
@@ -300,7 +320,14 @@ func moveInode(sb *structures.SuperBlock, diskPath string, srcInodeNum, srcParen
 				return fmt.Errorf("error al encontrar bloque libre: %v", err)
 			}
 			destParentInode.I_block[i] = newBlockNum
-			blockToUpdate = &structures.FolderBlock{}
+			blockToUpdate = &structures.FolderBlock{
+				B_content: [4]structures.FolderContent{
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+					{B_name: [12]byte{'-'}, B_inodo: -1},
+				},
+			}
 			targetBlockIndex = newBlockNum
 			contentIndex = 0
 
@@ -337,5 +364,40 @@ func moveInode(sb *structures.SuperBlock, diskPath string, srcInodeNum, srcParen
 		return fmt.Errorf("error al actualizar inodo padre destino %d: %v", destParentInodeNum, err)
 	}
 
+	// Si es una carpeta, su entrada ".." debe apuntar al nuevo padre
+	if srcInode.I_type[0] == '0' {
+		return setParentEntry(sb, diskPath, srcInodeNum, destParentInodeNum)
+	}
+
+	return nil
+}
+
+// setParentEntry hace que la entrada ".." de una carpeta apunte a parentInodeNum
+func setParentEntry(sb *structures.SuperBlock, diskPath string, inodeNum, parentInodeNum int32) error {
+	inode := &structures.Inode{}
+	err := inode.Deserialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size))
+	if err != nil {
+		return fmt.Errorf("error al leer inodo %d: %v", inodeNum, err)
+	}
+	for _, blockNum := range inode.I_block[:12] {
+		if blockNum == -1 {
+			continue
+		}
+		folderBlock := &structures.FolderBlock{}
+		err = folderBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
+		if err != nil {
+			return fmt.Errorf("error al leer bloque %d: %v", blockNum, err)
+		}
+		for i, content := range folderBlock.B_content {
+			if strings.Trim(string(content.B_name[:]), "\x00") == ".." {
+				folderBlock.B_content[i].B_inodo = parentInodeNum
+				err = folderBlock.Serialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
+				if err != nil {
+					return fmt.Errorf("error al actualizar bloque %d: %v", blockNum, err)
+				}
+				return nil
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,147 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+)
+
+// PTABLE estructura que representa el comando ptable con sus parámetros
+type PTABLE struct {
+	path    string // Ruta del disco
+	dump    bool   // Opción -dump
+	restore bool   // Opción -restore
+	file    string // Archivo JSON del anfitrión con el respaldo
+}
+
+/*
+   ptable -dump -path=/home/discos/Disco1.mia
+   ptable -dump -path=/home/discos/Disco1.mia -file=/home/respaldos/layout.json
+   ptable -restore -path=/home/discos/Disco1.mia -file=/home/respaldos/layout.json
+*/
+
+func ParsePtable(tokens []string) (string, error) {
+	cmd := &PTABLE{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		key := strings.ToLower(parts[0])
+
+		switch key {
+		case "-dump", "-restore":
+			if len(parts) != 1 {
+				return "", fmt.Errorf("formato inválido para %s: %s", key, token)
+			}
+			cmd.dump = cmd.dump || key == "-dump"
+			cmd.restore = cmd.restore || key == "-restore"
+		case "-path", "-file":
+			if len(parts) != 2 || strings.Trim(parts[1], "\"") == "" {
+				return "", fmt.Errorf("formato inválido para %s: %s", key, token)
+			}
+			if key == "-path" {
+				cmd.path = strings.Trim(parts[1], "\"")
+			} else {
+				cmd.file = strings.Trim(parts[1], "\"")
+			}
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.dump == cmd.restore {
+		return "", errors.New("debe indicar exactamente una opción: -dump o -restore")
+	}
+	if cmd.path == "" {
+		return "", errors.New("faltan parámetros requeridos: -path")
+	}
+
+	if cmd.dump {
+		output, err := commandPtableDump(cmd)
+		if err != nil {
+			return "", fmt.Errorf("error al respaldar la tabla de particiones: %v", err)
+		}
+		return output, nil
+	}
+
+	if cmd.file == "" {
+		return "", errors.New("faltan parámetros requeridos: -file")
+	}
+	layout, err := commandPtableRestore(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al restaurar la tabla de particiones: %v", err)
+	}
+	return fmt.Sprintf("PTABLE: tabla %s de %s restaurada desde %s (%d particiones, %d EBR)",
+		layout.Table, cmd.path, cmd.file, len(layout.Partitions), len(layout.Logical)), nil
+}
+
+// commandPtableDump devuelve el respaldo en JSON o, con -file, lo guarda en el anfitrión
+func commandPtableDump(ptable *PTABLE) (string, error) {
+	if _, err := os.Stat(ptable.path); os.IsNotExist(err) {
+		return "", fmt.Errorf("el disco en %s no existe", ptable.path)
+	}
+
+	layout, err := structures.DumpLayout(ptable.path)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(layout, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if ptable.file == "" {
+		return string(data), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ptable.file), os.ModePerm); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(ptable.file, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("error al escribir %s: %v", ptable.file, err)
+	}
+	return fmt.Sprintf("PTABLE: tabla %s de %s guardada en %s (%d particiones, %d EBR)",
+		layout.Table, ptable.path, ptable.file, len(layout.Partitions), len(layout.Logical)), nil
+}
+
+// commandPtableRestore escribe la tabla del respaldo en el disco. Las particiones quedan
+// desmontadas: el disco no puede tener particiones montadas mientras se restaura
+func commandPtableRestore(ptable *PTABLE) (*structures.LayoutDump, error) {
+	if _, err := os.Stat(ptable.path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("el disco en %s no existe", ptable.path)
+	}
+	for id, mountedPath := range stores.MountedPartitions {
+		if mountedPath == ptable.path {
+			return nil, fmt.Errorf("el disco en %s tiene una partición montada (ID: %s), desmonte primero", ptable.path, id)
+		}
+	}
+
+	data, err := os.ReadFile(ptable.file)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer %s: %v", ptable.file, err)
+	}
+	layout := &structures.LayoutDump{}
+	if err := json.Unmarshal(data, layout); err != nil {
+		return nil, fmt.Errorf("%s no es un respaldo válido: %v", ptable.file, err)
+	}
+
+	for i := range layout.Partitions {
+		if layout.Partitions[i].Status == "1" {
+			layout.Partitions[i].Status, layout.Partitions[i].ID = "0", ""
+		}
+	}
+	for i := range layout.Logical {
+		if layout.Logical[i].Status == "1" {
+			layout.Logical[i].Status, layout.Logical[i].ID = "0", ""
+		}
+	}
+
+	if err := structures.RestoreLayout(ptable.path, layout); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -69,7 +68,7 @@ func commandRecovery(recovery *RECOVERY) error {
 	}
 
 	// Actualizar el SuperBlock
-	err = superblock.Serialize(diskPath, int64(superblock.S_inode_start-int32(binary.Size(structures.SuperBlock{}))))
+	err = superblock.Serialize(diskPath, superblock.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
@@ -275,7 +274,7 @@ func reapplyMkdir(sb *structures.SuperBlock, diskPath, path string) error {
 			if err != nil {
 				return fmt.Errorf("error al escribir inodo padre: %v", err)
 			}
-			err = sb.Serialize(diskPath, int64(sb.S_inode_start-int32(binary.Size(structures.SuperBlock{}))))
+			err = sb.Serialize(diskPath, sb.Offset())
 			if err != nil {
 				return fmt.Errorf("error al actualizar superbloque: %v", err)
 			}
@@ -416,7 +415,7 @@ func reapplyMkfile(sb *structures.SuperBlock, diskPath, path, content string) er
 	if err != nil {
 		return fmt.Errorf("error al escribir inodo padre: %v", err)
 	}
-	err = sb.Serialize(diskPath, int64(sb.S_inode_start-int32(binary.Size(structures.SuperBlock{}))))
+	err = sb.Serialize(diskPath, sb.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
@@ -431,19 +430,11 @@ func reapplyChgrp(sb *structures.SuperBlock, diskPath, user, newGroup string) er
 		return fmt.Errorf("error al leer inodo de users.txt: %v", err)
 	}
 
-	var content strings.Builder
-	for _, blockNum := range usersInode.I_block[:12] {
-		if blockNum == -1 {
-			break
-		}
-		fileBlock := &structures.FileBlock{}
-		err = fileBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-		if err != nil {
-			return fmt.Errorf("error al leer bloque de users.txt: %v", err)
-		}
-		content.Write(bytes.Trim(fileBlock.B_content[:], "\x00"))
+	raw, err := sb.ReadFileContent(diskPath, usersInode)
+	if err != nil {
+		return fmt.Errorf("error al leer users.txt: %v", err)
 	}
-	usersContent := strings.TrimSpace(content.String())
+	usersContent := strings.TrimSpace(string(raw))
 
 	lines := strings.Split(usersContent, "\n")
 	userFound := false
@@ -480,61 +471,17 @@ func reapplyChgrp(sb *structures.SuperBlock, diskPath, user, newGroup string) er
 	}
 
 	updatedContent := strings.Join(lines, "\n")
-	contentBytes := []byte(updatedContent)
-	blockSize := int(sb.S_block_size)
-	numBlocksNeeded := (len(contentBytes) + blockSize - 1) / blockSize
-
-	for i := 0; i < 12; i++ {
-		if i < numBlocksNeeded {
-			start := i * blockSize
-			end := start + blockSize
-			if end > len(contentBytes) {
-				end = len(contentBytes)
-			}
-			blockContent := contentBytes[start:end]
-
-			var blockNum int32
-			if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-				blockNum = usersInode.I_block[i]
-			} else {
-				blockNum = sb.S_first_blo
-				sb.S_first_blo++
-				sb.S_free_blocks_count--
-				usersInode.I_block[i] = blockNum
-			}
-
-			fileBlock := &structures.FileBlock{}
-			for j := range fileBlock.B_content {
-				fileBlock.B_content[j] = 0
-			}
-			copy(fileBlock.B_content[:], blockContent)
-			err = fileBlock.Serialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-			if err != nil {
-				return fmt.Errorf("error al escribir bloque %d: %v", blockNum, err)
-			}
-		} else if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-			blockNum := usersInode.I_block[i]
-			fileBlock := &structures.FileBlock{}
-			for j := range fileBlock.B_content {
-				fileBlock.B_content[j] = 0
-			}
-			err = fileBlock.Serialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-			if err != nil {
-				return fmt.Errorf("error al limpiar bloque %d: %v", blockNum, err)
-			}
-			sb.S_free_blocks_count++
-			usersInode.I_block[i] = -1
-		}
+	err = sb.WriteFileContent(diskPath, usersInode, []byte(updatedContent))
+	if err != nil {
+		return fmt.Errorf("error al escribir users.txt: %v", err)
 	}
-
-	usersInode.I_size = int32(len(contentBytes))
 	usersInode.I_mtime = float32(time.Now().Unix())
 	err = usersInode.Serialize(diskPath, int64(sb.S_inode_start+sb.S_inode_size))
 	if err != nil {
 		return fmt.Errorf("error al actualizar inodo: %v", err)
 	}
 
-	err = sb.Serialize(diskPath, int64(sb.S_inode_start-int32(binary.Size(structures.SuperBlock{}))))
+	err = sb.Serialize(diskPath, sb.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
@@ -549,19 +496,11 @@ func reapplyMkusr(sb *structures.SuperBlock, diskPath, user, pass, group string)
 		return fmt.Errorf("error al leer inodo de users.txt: %v", err)
 	}
 
-	var content strings.Builder
-	for _, blockNum := range usersInode.I_block[:12] {
-		if blockNum == -1 {
-			break
-		}
-		fileBlock := &structures.FileBlock{}
-		err = fileBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-		if err != nil {
-			return fmt.Errorf("error al leer bloque de users.txt: %v", err)
-		}
-		content.Write(bytes.Trim(fileBlock.B_content[:], "\x00"))
+	raw, err := sb.ReadFileContent(diskPath, usersInode)
+	if err != nil {
+		return fmt.Errorf("error al leer users.txt: %v", err)
 	}
-	usersContent := strings.TrimSpace(content.String())
+	usersContent := strings.TrimSpace(string(raw))
 
 	lines := strings.Split(usersContent, "\n")
 	var maxID int
@@ -591,61 +530,17 @@ func reapplyMkusr(sb *structures.SuperBlock, diskPath, user, pass, group string)
 	newID := maxID + 1
 	newLine := fmt.Sprintf("%d,U,%s,%s,%s\n", newID, group, user, pass)
 	updatedContent := usersContent + "\n" + newLine
-	contentBytes := []byte(updatedContent)
-	blockSize := int(sb.S_block_size)
-	numBlocksNeeded := (len(contentBytes) + blockSize - 1) / blockSize
-
-	for i := 0; i < 12; i++ {
-		if i < numBlocksNeeded {
-			start := i * blockSize
-			end := start + blockSize
-			if end > len(contentBytes) {
-				end = len(contentBytes)
-			}
-			blockContent := contentBytes[start:end]
-
-			var blockNum int32
-			if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-				blockNum = usersInode.I_block[i]
-			} else {
-				blockNum = sb.S_first_blo
-				sb.S_first_blo++
-				sb.S_free_blocks_count--
-				usersInode.I_block[i] = blockNum
-			}
-
-			fileBlock := &structures.FileBlock{}
-			for j := range fileBlock.B_content {
-				fileBlock.B_content[j] = 0
-			}
-			copy(fileBlock.B_content[:], blockContent)
-			err = fileBlock.Serialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-			if err != nil {
-				return fmt.Errorf("error al escribir bloque %d: %v", blockNum, err)
-			}
-		} else if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-			blockNum := usersInode.I_block[i]
-			fileBlock := &structures.FileBlock{}
-			for j := range fileBlock.B_content {
-				fileBlock.B_content[j] = 0
-			}
-			err = fileBlock.Serialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-			if err != nil {
-				return fmt.Errorf("error al limpiar bloque %d: %v", blockNum, err)
-			}
-			sb.S_free_blocks_count++
-			usersInode.I_block[i] = -1
-		}
+	err = sb.WriteFileContent(diskPath, usersInode, []byte(updatedContent))
+	if err != nil {
+		return fmt.Errorf("error al escribir users.txt: %v", err)
 	}
-
-	usersInode.I_size = int32(len(contentBytes))
 	usersInode.I_mtime = float32(time.Now().Unix())
 	err = usersInode.Serialize(diskPath, int64(sb.S_inode_start+sb.S_inode_size))
 	if err != nil {
 		return fmt.Errorf("error al actualizar inodo: %v", err)
 	}
 
-	err = sb.Serialize(diskPath, int64(sb.S_inode_start-int32(binary.Size(structures.SuperBlock{}))))
+	err = sb.Serialize(diskPath, sb.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
@@ -660,19 +555,11 @@ func reapplyMkgrp(sb *structures.SuperBlock, diskPath, group string) error {
 		return fmt.Errorf("error al leer inodo de users.txt: %v", err)
 	}
 
-	var content strings.Builder
-	for _, blockNum := range usersInode.I_block[:12] {
-		if blockNum == -1 {
-			break
-		}
-		fileBlock := &structures.FileBlock{}
-		err = fileBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-		if err != nil {
-			return fmt.Errorf("error al leer bloque de users.txt: %v", err)
-		}
-		content.Write(bytes.Trim(fileBlock.B_content[:], "\x00"))
+	raw, err := sb.ReadFileContent(diskPath, usersInode)
+	if err != nil {
+		return fmt.Errorf("error al leer users.txt: %v", err)
 	}
-	usersContent := strings.TrimSpace(content.String())
+	usersContent := strings.TrimSpace(string(raw))
 
 	lines := strings.Split(usersContent, "\n")
 	var maxID int
@@ -694,61 +581,17 @@ func reapplyMkgrp(sb *structures.SuperBlock, diskPath, group string) error {
 	newID := maxID + 1
 	newLine := fmt.Sprintf("%d,G,%s\n", newID, group)
 	updatedContent := usersContent + "\n" + newLine
-	contentBytes := []byte(updatedContent)
-	blockSize := int(sb.S_block_size)
-	numBlocksNeeded := (len(contentBytes) + blockSize - 1) / blockSize
-
-	for i := 0; i < 12; i++ {
-		if i < numBlocksNeeded {
-			start := i * blockSize
-			end := start + blockSize
-			if end > len(contentBytes) {
-				end = len(contentBytes)
-			}
-			blockContent := contentBytes[start:end]
-
-			var blockNum int32
-			if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-				blockNum = usersInode.I_block[i]
-			} else {
-				blockNum = sb.S_first_blo
-				sb.S_first_blo++
-				sb.S_free_blocks_count--
-				usersInode.I_block[i] = blockNum
-			}
-
-			fileBlock := &structures.FileBlock{}
-			for j := range fileBlock.B_content {
-				fileBlock.B_content[j] = 0
-			}
-			copy(fileBlock.B_content[:], blockContent)
-			err = fileBlock.Serialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-			if err != nil {
-				return fmt.Errorf("error al escribir bloque %d: %v", blockNum, err)
-			}
-		} else if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-			blockNum := usersInode.I_block[i]
-			fileBlock := &structures.FileBlock{}
-			for j := range fileBlock.B_content {
-				fileBlock.B_content[j] = 0
-			}
-			err = fileBlock.Serialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-			if err != nil {
-				return fmt.Errorf("error al limpiar bloque %d: %v", blockNum, err)
-			}
-			sb.S_free_blocks_count++
-			usersInode.I_block[i] = -1
-		}
+	err = sb.WriteFileContent(diskPath, usersInode, []byte(updatedContent))
+	if err != nil {
+		return fmt.Errorf("error al escribir users.txt: %v", err)
 	}
-
-	usersInode.I_size = int32(len(contentBytes))
 	usersInode.I_mtime = float32(time.Now().Unix())
 	err = usersInode.Serialize(diskPath, int64(sb.S_inode_start+sb.S_inode_size))
 	if err != nil {
 		return fmt.Errorf("error al actualizar inodo: %v", err)
 	}
 
-	err = sb.Serialize(diskPath, int64(sb.S_inode_start-int32(binary.Size(structures.SuperBlock{}))))
+	err = sb.Serialize(diskPath, sb.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
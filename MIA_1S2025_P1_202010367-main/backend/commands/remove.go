@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"os"
@@ -43,34 +42,49 @@ func ParseRemove(tokens []string) (string, error) {
 		return "", errors.New("faltan parámetros requeridos: -path")
 	}
 
-	err := commandRemove(cmd)
+	trashed, err := commandRemove(cmd)
 	if err != nil {
 		return "", fmt.Errorf("error al eliminar: %v", err)
 	}
 
+	if trashed {
+		return fmt.Sprintf("REMOVE: %s movido a la papelera", cmd.path), nil
+	}
 	return fmt.Sprintf("REMOVE: %s eliminado exitosamente", cmd.path), nil
 }
 
-func commandRemove(remove *REMOVE) error {
+// commandRemove elimina la ruta indicada. Si la partición tiene papelera, la entrada se
+// mueve a /.trash y devuelve true; dentro de la papelera se elimina definitivamente
+func commandRemove(remove *REMOVE) (bool, error) {
 	if stores.CurrentSession.ID == "" {
-		return errors.New("no hay sesión activa, inicie sesión primero")
+		return false, errors.New("no hay sesión activa, inicie sesión primero")
 	}
 
-	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	// La ruta puede pasar por carpetas donde se montaron otras particiones con mount -at
+	target, err := resolveSessionPath(remove.path)
 	if err != nil {
-		return fmt.Errorf("error al obtener la partición montada: %v", err)
+		return false, err
+	}
+	partitionSuperblock, partitionPath := target.sb, target.diskPath
+
+	// Un punto de montaje no se puede eliminar mientras tenga una partición montada
+	if points := stores.GraftsUnder(target.id, target.path); len(points) > 0 {
+		return false, fmt.Errorf("%s contiene puntos de montaje: %s", remove.path, strings.Join(points, ", "))
 	}
 
 	file, err := os.OpenFile(partitionPath, os.O_RDWR, 0644)
 	if err != nil {
-		return fmt.Errorf("error al abrir disco: %v", err)
+		return false, fmt.Errorf("error al abrir disco: %v", err)
 	}
 	defer file.Close()
 
 	// Normalizar la ruta usando utils.GetParentDirectories
-	parentDirs, destDir := utils.GetParentDirectories(remove.path)
+	parentDirs, destDir := utils.GetParentDirectories(target.path)
 	if destDir == "" {
-		return errors.New("la ruta no puede ser la raíz")
+		return false, errors.New("la ruta no puede ser la raíz")
+	}
+	if len(parentDirs) == 1 && parentDirs[0] == trashFolder && destDir == trashIndex {
+		return false, errors.New("el índice de la papelera no se puede eliminar")
 	}
 
 	// Encontrar el inodo del archivo/carpeta
@@ -82,7 +96,7 @@ func commandRemove(remove *REMOVE) error {
 	currentInode := &structures.Inode{}
 	err = currentInode.Deserialize(partitionPath, int64(partitionSuperblock.S_inode_start))
 	if err != nil {
-		return fmt.Errorf("error al leer inodo raíz: %v", err)
+		return false, fmt.Errorf("error al leer inodo raíz: %v", err)
 	}
 
 	// Navegar los directorios padres
@@ -91,7 +105,7 @@ func commandRemove(remove *REMOVE) error {
 			continue
 		}
 		if currentInode.I_type[0] != '0' {
-			return fmt.Errorf("el inodo %d no es una carpeta", currentInodeNum)
+			return false, fmt.Errorf("el inodo %d no es una carpeta", currentInodeNum)
 		}
 
 		found := false
@@ -102,7 +116,7 @@ func commandRemove(remove *REMOVE) error {
 			folderBlock := &structures.FolderBlock{}
 			err = folderBlock.Deserialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*partitionSuperblock.S_block_size))
 			if err != nil {
-				return fmt.Errorf("error al leer bloque de carpeta %d: %v", blockNum, err)
+				return false, fmt.Errorf("error al leer bloque de carpeta %d: %v", blockNum, err)
 			}
 
 			for _, content := range folderBlock.B_content {
@@ -111,7 +125,7 @@ func commandRemove(remove *REMOVE) error {
 					currentInodeNum = content.B_inodo
 					err = currentInode.Deserialize(partitionPath, int64(partitionSuperblock.S_inode_start+content.B_inodo*partitionSuperblock.S_inode_size))
 					if err != nil {
-						return fmt.Errorf("error al leer inodo %d: %v", content.B_inodo, err)
+						return false, fmt.Errorf("error al leer inodo %d: %v", content.B_inodo, err)
 					}
 					found = true
 					break
@@ -122,13 +136,13 @@ func commandRemove(remove *REMOVE) error {
 			}
 		}
 		if !found {
-			return fmt.Errorf("no se encontró %s en la ruta %s", dir, remove.path)
+			return false, fmt.Errorf("no se encontró %s en la ruta %s", dir, remove.path)
 		}
 	}
 
 	// Encontrar el inodo objetivo
 	if currentInode.I_type[0] != '0' {
-		return fmt.Errorf("el inodo padre %d no es una carpeta", currentInodeNum)
+		return false, fmt.Errorf("el inodo padre %d no es una carpeta", currentInodeNum)
 	}
 	found := false
 	for _, blockNum := range currentInode.I_block[:12] {
@@ -138,7 +152,7 @@ func commandRemove(remove *REMOVE) error {
 		folderBlock := &structures.FolderBlock{}
 		err = folderBlock.Deserialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*partitionSuperblock.S_block_size))
 		if err != nil {
-			return fmt.Errorf("error al leer bloque de carpeta %d: %v", blockNum, err)
+			return false, fmt.Errorf("error al leer bloque de carpeta %d: %v", blockNum, err)
 		}
 
 		for _, content := range folderBlock.B_content {
@@ -155,44 +169,64 @@ func commandRemove(remove *REMOVE) error {
 		}
 	}
 	if !found {
-		return fmt.Errorf("no se encontró %s en la ruta %s", destDir, remove.path)
+		return false, fmt.Errorf("no se encontró %s en la ruta %s", destDir, remove.path)
 	}
 
 	// Leer el inodo objetivo
 	targetInode := &structures.Inode{}
 	err = targetInode.Deserialize(partitionPath, int64(partitionSuperblock.S_inode_start+targetInodeNum*partitionSuperblock.S_inode_size))
 	if err != nil {
-		return fmt.Errorf("error al leer inodo objetivo %d: %v", targetInodeNum, err)
+		return false, fmt.Errorf("error al leer inodo objetivo %d: %v", targetInodeNum, err)
 	}
 
 	// Verificar permisos de escritura
 	hasWritePermission := checkWritePermission(targetInode, stores.CurrentSession)
 	if !hasWritePermission {
-		return fmt.Errorf("no tiene permisos de escritura para %s", remove.path)
+		return false, fmt.Errorf("no tiene permisos de escritura para %s", remove.path)
 	}
 
 	// Si es una carpeta, verificar permisos recursivamente
 	if targetInode.I_type[0] == '0' {
 		canDelete, err := canDeleteFolder(partitionSuperblock, partitionPath, targetInodeNum, stores.CurrentSession)
 		if err != nil {
-			return fmt.Errorf("error al verificar permisos de la carpeta: %v", err)
+			return false, fmt.Errorf("error al verificar permisos de la carpeta: %v", err)
 		}
 		if !canDelete {
-			return fmt.Errorf("no se puede eliminar %s debido a falta de permisos en su contenido", remove.path)
+			return false, fmt.Errorf("no se puede eliminar %s debido a falta de permisos en su contenido", remove.path)
+		}
+	}
+
+	// Con la papelera habilitada, lo que está fuera de ella se mueve en lugar de eliminarse
+	if _, ok := trashEnabled(partitionSuperblock, partitionPath); ok && !isTrashPath(parentDirs, destDir) {
+		err = moveToTrash(partitionSuperblock, partitionPath, targetInodeNum, parentInodeNum, targetName, target.path)
+		if err != nil {
+			return false, err
+		}
+		err = AddJournalEntry(partitionSuperblock, partitionPath, "remove", target.path, "/"+trashFolder)
+		if err != nil {
+			return false, fmt.Errorf("error al registrar en el Journal: %v", err)
+		}
+		if _, err := purgeTrash(partitionSuperblock, partitionPath); err != nil {
+			return false, fmt.Errorf("error al purgar la papelera: %v", err)
+		}
+		err = partitionSuperblock.Serialize(partitionPath, partitionSuperblock.Offset())
+		if err != nil {
+			return false, fmt.Errorf("error al actualizar superbloque: %v", err)
 		}
+		return true, nil
 	}
 
 	// Eliminar recursivamente el contenido
-	err = deleteInode(partitionSuperblock, partitionPath, targetInodeNum)
+	err = deleteInode(partitionSuperblock, partitionPath, targetInodeNum, targetName)
 	if err != nil {
-		return fmt.Errorf("error al eliminar inodo %d: %v", targetInodeNum, err)
+		return false, fmt.Errorf("error al eliminar inodo %d: %v", targetInodeNum, err)
 	}
 
 	// Actualizar el inodo padre (eliminar la entrada)
 	parentInode := &structures.Inode{}
 	err = parentInode.Deserialize(partitionPath, int64(partitionSuperblock.S_inode_start+parentInodeNum*partitionSuperblock.S_inode_size))
 	if err != nil {
-		return fmt.Errorf("error al leer inodo padre %d: %v", parentInodeNum, err)
+		return false, fmt.Errorf("error al leer inodo padre %d: %v", parentInodeNum, err)
 	}
 
 	for _, blockNum := range parentInode.I_block[:12] {
@@ -202,7 +236,7 @@ func commandRemove(remove *REMOVE) error {
 		folderBlock := &structures.FolderBlock{}
 		err = folderBlock.Deserialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*partitionSuperblock.S_block_size))
 		if err != nil {
-			return fmt.Errorf("error al leer bloque de carpeta %d: %v", blockNum, err)
+			return false, fmt.Errorf("error al leer bloque de carpeta %d: %v", blockNum, err)
 		}
 
 		for i, content := range folderBlock.B_content {
@@ -211,7 +245,7 @@ func commandRemove(remove *REMOVE) error {
 				folderBlock.B_content[i] = structures.FolderContent{B_name: structures.ToByte12("-"), B_inodo: -1}
 				err = folderBlock.Serialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*partitionSuperblock.S_block_size))
 				if err != nil {
-					return fmt.Errorf("error al actualizar bloque de carpeta %d: %v", blockNum, err)
+					return false, fmt.Errorf("error al actualizar bloque de carpeta %d: %v", blockNum, err)
 				}
 				break
 			}
@@ -222,22 +256,29 @@ func commandRemove(remove *REMOVE) error {
 	parentInode.I_mtime = float32(time.Now().Unix())
 	err = parentInode.Serialize(partitionPath, int64(partitionSuperblock.S_inode_start+parentInodeNum*partitionSuperblock.S_inode_size))
 	if err != nil {
-		return fmt.Errorf("error al actualizar inodo padre: %v", err)
+		return false, fmt.Errorf("error al actualizar inodo padre: %v", err)
+	}
+
+	// Una entrada de primer nivel de la papelera también sale del índice
+	if len(parentDirs) == 1 && parentDirs[0] == trashFolder {
+		if err := dropTrashEntry(partitionSuperblock, partitionPath, targetName); err != nil {
+			return false, err
+		}
 	}
 
 	// Registrar en el Journal
-	err = AddJournalEntry(partitionSuperblock, partitionPath, "remove", remove.path, targetName)
+	err = AddJournalEntry(partitionSuperblock, partitionPath, "remove", target.path, targetName)
 	if err != nil {
-		return fmt.Errorf("error al registrar en el Journal: %v", err)
+		return false, fmt.Errorf("error al registrar en el Journal: %v", err)
 	}
 
 	// Actualizar el superbloque
-	err = partitionSuperblock.Serialize(partitionPath, int64(partitionSuperblock.S_bm_inode_start)-int64(binary.Size(structures.SuperBlock{})))
+	err = partitionSuperblock.Serialize(partitionPath, partitionSuperblock.Offset())
 	if err != nil {
-		return fmt.Errorf("error al actualizar superbloque: %v", err)
+		return false, fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
 
-	return nil
+	return false, nil
 }
 
 // checkWritePermission verifica si el usuario tiene permisos de escritura
@@ -311,8 +352,9 @@ func canDeleteFolder(sb *structures.SuperBlock, path string, inodeNum int32, ses
 	return true, nil
 }
 
-// deleteInode elimina un inodo y sus bloques recursivamente
-func deleteInode(sb *structures.SuperBlock, path string, inodeNum int32) error {
+// deleteInode elimina un inodo y sus bloques recursivamente. El inodo no se borra: queda
+// como lápida con su nombre para que undelete pueda recuperarlo
+func deleteInode(sb *structures.SuperBlock, path string, inodeNum int32, name string) error {
 	inode := &structures.Inode{}
 	err := inode.Deserialize(path, int64(sb.S_inode_start+inodeNum*sb.S_inode_size))
 	if err != nil {
@@ -332,49 +374,44 @@ func deleteInode(sb *structures.SuperBlock, path string, inodeNum int32) error {
 			}
 
 			for _, content := range folderBlock.B_content {
-				name := strings.Trim(string(content.B_name[:]), "\x00")
-				if name == "." || name == ".." || content.B_inodo == -1 {
+				childName := strings.Trim(string(content.B_name[:]), "\x00")
+				if childName == "." || childName == ".." || content.B_inodo == -1 {
 					continue
 				}
-				err = deleteInode(sb, path, content.B_inodo)
+				err = deleteInode(sb, path, content.B_inodo, childName)
 				if err != nil {
 					return err
 				}
 			}
 
 			// Limpiar el bloque
-			err = sb.UpdateBitmapBlock(path, blockNum)
+			_, err = sb.ReleaseBlock(path, blockNum)
 			if err != nil {
 				return fmt.Errorf("error al liberar bloque %d: %v", blockNum, err)
 			}
-			sb.S_free_blocks_count++
 		}
 	} else {
-		// Si es un archivo, liberar sus bloques
-		for _, blockNum := range inode.I_block[:12] {
-			if blockNum == -1 {
-				continue
-			}
-			err = sb.UpdateBitmapBlock(path, blockNum)
-			if err != nil {
-				return fmt.Errorf("error al liberar bloque %d: %v", blockNum, err)
-			}
-			sb.S_free_blocks_count++
+		// Si es un archivo, soltar su referencia a cada bloque, incluidos los de
+		// apuntadores; los bloques compartidos con otro dueño (por ejemplo, un snapshot)
+		// siguen ocupados
+		err = sb.ReleaseFileBlocks(path, inode)
+		if err != nil {
+			return err
 		}
 	}
 
 	// Liberar el inodo
-	err = sb.UpdateBitmapInode(path, inodeNum)
+	err = sb.FreeBitmapInode(path, inodeNum)
 	if err != nil {
 		return fmt.Errorf("error al liberar inodo %d: %v", inodeNum, err)
 	}
 	sb.S_free_inodes_count++
 
-	// Limpiar el inodo
-	inode = &structures.Inode{}
+	// Dejar la lápida
+	inode.SetTombstone(name)
 	err = inode.Serialize(path, int64(sb.S_inode_start+inodeNum*sb.S_inode_size))
 	if err != nil {
-		return fmt.Errorf("error al limpiar inodo %d: %v", inodeNum, err)
+		return fmt.Errorf("error al actualizar inodo %d: %v", inodeNum, err)
 	}
 
 	return nil
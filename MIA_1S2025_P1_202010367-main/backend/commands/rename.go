@@ -1,7 +1,6 @@
 package commands
 
 import (
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"os"
@@ -64,9 +63,16 @@ func commandRename(rename *RENAME) error {
 		return errors.New("no hay sesión activa, inicie sesión primero")
 	}
 
-	partitionSuperblock, _, partitionPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	// La ruta puede pasar por carpetas donde se montaron otras particiones con mount -at
+	target, err := resolveSessionPath(rename.path)
 	if err != nil {
-		return fmt.Errorf("error al obtener la partición montada: %v", err)
+		return err
+	}
+	partitionSuperblock, partitionPath := target.sb, target.diskPath
+
+	// Un punto de montaje no se puede renombrar mientras tenga una partición montada
+	if points := stores.GraftsUnder(target.id, target.path); len(points) > 0 {
+		return fmt.Errorf("%s contiene puntos de montaje: %s", rename.path, strings.Join(points, ", "))
 	}
 
 	file, err := os.OpenFile(partitionPath, os.O_RDWR, 0644)
@@ -76,7 +82,7 @@ func commandRename(rename *RENAME) error {
 	defer file.Close()
 
 	// Normalizar la ruta
-	parentDirs, targetName := utils.GetParentDirectories(rename.path)
+	parentDirs, targetName := utils.GetParentDirectories(target.path)
 	if targetName == "" {
 		return errors.New("no se puede renombrar la raíz")
 	}
@@ -214,13 +220,13 @@ func commandRename(rename *RENAME) error {
 	if len(truncatedName) > 64 {
 		truncatedName = truncatedName[:64]
 	}
-	err = AddJournalEntry(partitionSuperblock, partitionPath, "rename", rename.path, truncatedName)
+	err = AddJournalEntry(partitionSuperblock, partitionPath, "rename", target.path, truncatedName)
 	if err != nil {
 		return fmt.Errorf("error al registrar en el Journal: %v", err)
 	}
 
 	// Actualizar el superbloque
-	err = partitionSuperblock.Serialize(partitionPath, int64(partitionSuperblock.S_bm_inode_start)-int64(binary.Size(structures.SuperBlock{})))
+	err = partitionSuperblock.Serialize(partitionPath, partitionSuperblock.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
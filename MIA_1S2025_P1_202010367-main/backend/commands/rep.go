@@ -126,9 +126,9 @@ func commandRep(rep *REP) error {
 		}
 		return nil
 	case "tree":
-		dotContent, err = reports.ReportTree(mountedSb, mountedDiskPath)
+		dotContent, err = reports.ReportTree(mountedSb, mountedDiskPath, rep.id)
 	case "sb":
-		dotContent, err = reports.ReportSB(mountedSb)
+		dotContent, err = reports.ReportSB(mountedSb, stores.LastCheck(mountedDiskPath, stores.MountedName(rep.id)))
 	case "file":
 		dotContent, err = reports.ReportFile(mountedSb, mountedDiskPath, rep.path_file_ls)
 		if err != nil {
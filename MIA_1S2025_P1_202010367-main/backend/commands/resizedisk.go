@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+	utils "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/utils"
+)
+
+// RESIZEDISK estructura que representa el comando resizedisk con sus parámetros
+type RESIZEDISK struct {
+	path string // Ruta del disco
+	size int    // Nuevo tamaño del disco
+	unit string // Unidad de medida del tamaño (K o M)
+}
+
+/*
+   resizedisk -path=/home/discos/Disco1.mia -size=10 -unit=M
+*/
+
+func ParseResizedisk(tokens []string) (string, error) {
+	cmd := &RESIZEDISK{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key, value := strings.ToLower(parts[0]), strings.Trim(parts[1], "\"")
+
+		switch key {
+		case "-path":
+			cmd.path = value
+		case "-size":
+			size, err := strconv.Atoi(value)
+			if err != nil || size <= 0 {
+				return "", errors.New("el tamaño debe ser un número entero positivo")
+			}
+			cmd.size = size
+		case "-unit":
+			value = strings.ToUpper(value)
+			if value != "K" && value != "M" {
+				return "", errors.New("la unidad debe ser K o M")
+			}
+			cmd.unit = value
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.path == "" || cmd.size == 0 {
+		return "", errors.New("faltan parámetros requeridos: -path, -size")
+	}
+	if cmd.unit == "" {
+		cmd.unit = "M"
+	}
+
+	oldSize, newSize, err := commandResizedisk(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al redimensionar el disco: %v", err)
+	}
+	return fmt.Sprintf("RESIZEDISK: Disco %s redimensionado de %d a %d bytes", cmd.path, oldSize, newSize), nil
+}
+
+// commandResizedisk agranda o achica el archivo del disco y actualiza el tamaño en la tabla
+// de particiones. Al achicar, ninguna partición ni EBR puede quedar después del nuevo final
+func commandResizedisk(resize *RESIZEDISK) (int64, int64, error) {
+	sizeBytes, err := utils.ConvertToBytes(resize.size, resize.unit)
+	if err != nil {
+		return 0, 0, err
+	}
+	newSize := int64(sizeBytes)
+	if newSize > math.MaxInt32 {
+		return 0, 0, fmt.Errorf("el tamaño máximo de un disco es %d bytes", math.MaxInt32)
+	}
+
+	info, err := os.Stat(resize.path)
+	if os.IsNotExist(err) {
+		return 0, 0, fmt.Errorf("el disco en %s no existe", resize.path)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	oldSize := info.Size()
+	if newSize == oldSize {
+		return 0, 0, fmt.Errorf("el disco ya mide %d bytes", oldSize)
+	}
+
+	table, err := structures.ReadPartitionTable(resize.path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al leer la tabla de particiones: %v", err)
+	}
+	oldBackup, oldBackupSize := table.BackupArea()
+	if err := table.Resize(int32(newSize)); err != nil {
+		return 0, 0, err
+	}
+
+	if newSize < oldSize {
+		if err := checkDiskEnd(resize.path, table); err != nil {
+			return 0, 0, err
+		}
+		// La tabla se escribe antes de cortar el archivo: en GPT la copia de respaldo
+		// queda dentro del nuevo tamaño
+		if err := table.Write(resize.path); err != nil {
+			return 0, 0, fmt.Errorf("error al escribir la tabla de particiones: %v", err)
+		}
+		if err := os.Truncate(resize.path, newSize); err != nil {
+			return 0, 0, fmt.Errorf("error al achicar el disco: %v", err)
+		}
+		return oldSize, newSize, nil
+	}
+
+	// El espacio nuevo queda como un hueco del archivo, que se lee como ceros
+	if err := os.Truncate(resize.path, newSize); err != nil {
+		return 0, 0, fmt.Errorf("error al agrandar el disco: %v", err)
+	}
+	if err := table.Write(resize.path); err != nil {
+		return 0, 0, fmt.Errorf("error al escribir la tabla de particiones: %v", err)
+	}
+	// La copia de respaldo GPT anterior queda en medio del espacio libre
+	if err := structures.PunchHole(resize.path, oldBackup, oldBackupSize); err != nil {
+		return 0, 0, fmt.Errorf("error al borrar la copia de respaldo anterior: %v", err)
+	}
+	return oldSize, newSize, nil
+}
+
+// checkDiskEnd verifica que todas las particiones y los EBR de la extendida terminen antes
+// del final utilizable de la tabla ya redimensionada
+func checkDiskEnd(path string, table *structures.PartitionTable) error {
+	end := table.UsableEnd()
+	for _, p := range table.UsedPartitions() {
+		if partEnd := int64(p.Part_start) + int64(p.Part_size); partEnd > end {
+			return fmt.Errorf("la partición %s termina en el byte %d, después del nuevo final utilizable (%d)",
+				strings.Trim(string(p.Part_name[:]), "\x00"), partEnd, end)
+		}
+	}
+
+	extPartition := table.ExtendedPartition()
+	if extPartition == nil || extPartition.Part_status[0] == 'N' {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error al abrir disco: %v", err)
+	}
+	defer file.Close()
+	chain, err := readEBRChain(file, extPartition)
+	if err != nil {
+		return err
+	}
+	ebrSize := int64(binary.Size(structures.EBR{}))
+	for _, link := range chain {
+		name := strings.Trim(string(link.ebr.Part_name[:]), "\x00")
+		if link.offset+ebrSize > end {
+			return fmt.Errorf("el EBR de la partición lógica %s está en el byte %d, después del nuevo final utilizable (%d)", name, link.offset, end)
+		}
+		if partEnd := int64(link.ebr.Part_start) + int64(link.ebr.Part_size); partEnd > end {
+			return fmt.Errorf("la partición lógica %s termina en el byte %d, después del nuevo final utilizable (%d)", name, partEnd, end)
+		}
+	}
+	return nil
+}
@@ -1,8 +1,6 @@
 package commands
 
 import (
-	"bytes"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"os"
@@ -91,19 +89,11 @@ func commandRmgrp(rmgrp *RMGRP) error {
 	}
 
 	// Leer el contenido actual de users.txt
-	var content strings.Builder
-	for _, blockNum := range usersInode.I_block[:12] {
-		if blockNum == -1 {
-			break
-		}
-		fileBlock := &structures.FileBlock{}
-		err = fileBlock.Deserialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*partitionSuperblock.S_block_size))
-		if err != nil {
-			return fmt.Errorf("error al leer el bloque de users.txt: %v", err)
-		}
-		content.Write(bytes.Trim(fileBlock.B_content[:], "\x00"))
+	raw, err := partitionSuperblock.ReadFileContent(partitionPath, usersInode)
+	if err != nil {
+		return fmt.Errorf("error al leer users.txt: %v", err)
 	}
-	usersContent := strings.TrimSpace(content.String())
+	usersContent := strings.TrimSpace(string(raw))
 
 	// Procesar contenido y eliminar grupo
 	lines := strings.Split(usersContent, "\n")
@@ -130,83 +120,18 @@ func commandRmgrp(rmgrp *RMGRP) error {
 	updatedContent := strings.Join(lines, "\n")
 	fmt.Printf("DEBUG: Nuevo contenido de users.txt en RMGRP:\n%s\n", updatedContent)
 
-	// Escribir el contenido actualizado
-	blockSize := int(partitionSuperblock.S_block_size) // 64 bytes
-	contentBytes := []byte(updatedContent)
-	numBlocksNeeded := (len(contentBytes) + blockSize - 1) / blockSize
-
-	if numBlocksNeeded > 12 {
-		return errors.New("el archivo users.txt excede el límite de bloques directos (12)")
-	}
-
-	// Reutilizar o asignar bloques
-	for i := 0; i < 12; i++ {
-		if i < numBlocksNeeded {
-			start := i * blockSize
-			end := start + blockSize
-			if end > len(contentBytes) {
-				end = len(contentBytes)
-			}
-			blockContent := contentBytes[start:end]
-
-			var blockNum int32
-			if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-				blockNum = usersInode.I_block[i] // Reutilizar bloque existente
-			} else {
-				if partitionSuperblock.S_free_blocks_count <= 0 {
-					return errors.New("no hay bloques libres disponibles")
-				}
-				blockNum = partitionSuperblock.S_first_blo
-				partitionSuperblock.S_first_blo++
-				partitionSuperblock.S_free_blocks_count--
-				partitionSuperblock.S_blocks_count++
-				usersInode.I_block[i] = blockNum
-
-				err = setBitmapBit(partitionPath, int64(partitionSuperblock.S_bm_block_start), int(blockNum), 1)
-				if err != nil {
-					return fmt.Errorf("error al actualizar bitmap de bloques: %v", err)
-				}
-			}
-
-			fileBlock := &structures.FileBlock{}
-			// Limpiar el bloque completo antes de escribir
-			for j := range fileBlock.B_content {
-				fileBlock.B_content[j] = 0
-			}
-			copy(fileBlock.B_content[:], blockContent)
-			err = fileBlock.Serialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*int32(partitionSuperblock.S_block_size)))
-			if err != nil {
-				return fmt.Errorf("error al escribir bloque %d: %v", blockNum, err)
-			}
-		} else if i < len(usersInode.I_block) && usersInode.I_block[i] != -1 {
-			blockNum := usersInode.I_block[i]
-			fileBlock := &structures.FileBlock{}
-			// Limpiar el bloque completo
-			for j := range fileBlock.B_content {
-				fileBlock.B_content[j] = 0
-			}
-			err = fileBlock.Serialize(partitionPath, int64(partitionSuperblock.S_block_start+blockNum*int32(partitionSuperblock.S_block_size)))
-			if err != nil {
-				return fmt.Errorf("error al limpiar bloque %d: %v", blockNum, err)
-			}
-			err = setBitmapBit(partitionPath, int64(partitionSuperblock.S_bm_block_start), int(blockNum), 0)
-			if err != nil {
-				return fmt.Errorf("error al actualizar bitmap de bloques: %v", err)
-			}
-			partitionSuperblock.S_free_blocks_count++
-			usersInode.I_block[i] = -1
-		}
+	// Escribir el contenido actualizado; los bloques compartidos se copian antes de modificarlos
+	err = partitionSuperblock.WriteFileContent(partitionPath, usersInode, []byte(updatedContent))
+	if err != nil {
+		return fmt.Errorf("error al escribir users.txt: %v", err)
 	}
-
-	// Actualizar inodo
-	usersInode.I_size = int32(len(contentBytes))
 	err = usersInode.Serialize(partitionPath, int64(partitionSuperblock.S_inode_start+partitionSuperblock.S_inode_size))
 	if err != nil {
 		return fmt.Errorf("error al actualizar inodo: %v", err)
 	}
 
 	// Actualizar superbloque
-	err = partitionSuperblock.Serialize(partitionPath, int64(partitionSuperblock.S_inode_start-int32(binary.Size(structures.SuperBlock{}))))
+	err = partitionSuperblock.Serialize(partitionPath, partitionSuperblock.Offset())
 	if err != nil {
 		return fmt.Errorf("error al actualizar superbloque: %v", err)
 	}
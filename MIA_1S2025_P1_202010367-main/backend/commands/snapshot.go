@@ -0,0 +1,378 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+)
+
+// SNAPSHOT estructura que representa el comando snapshot con sus parámetros
+type SNAPSHOT struct {
+	id     string // ID de la partición
+	name   string // Nombre del snapshot
+	delete bool   // Opción -delete (elimina el snapshot)
+}
+
+// ROLLBACK estructura que representa el comando rollback con sus parámetros
+type ROLLBACK struct {
+	id   string // ID de la partición
+	name string // Nombre del snapshot a restaurar
+}
+
+// SNAPSHOTS estructura que representa el comando snapshots con sus parámetros
+type SNAPSHOTS struct {
+	id string // ID de la partición
+}
+
+var snapshotNameRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,16}$`)
+
+/*
+   snapshot -id=671A -name=before
+   snapshot -id=671A -name=before -delete
+   rollback -id=671A -name=before
+   snapshots -id=671A
+*/
+
+func ParseSnapshot(tokens []string) (string, error) {
+	cmd := &SNAPSHOT{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		key := strings.ToLower(parts[0])
+
+		switch key {
+		case "-id", "-name":
+			if len(parts) != 2 || parts[1] == "" {
+				return "", fmt.Errorf("formato inválido para %s: %s", key, token)
+			}
+			if key == "-id" {
+				cmd.id = strings.ToUpper(parts[1])
+			} else {
+				cmd.name = parts[1]
+			}
+		case "-delete":
+			if len(parts) != 1 {
+				return "", fmt.Errorf("formato inválido para -delete: %s", token)
+			}
+			cmd.delete = true
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.id == "" || cmd.name == "" {
+		return "", errors.New("faltan parámetros requeridos: -id, -name")
+	}
+	if !snapshotNameRegex.MatchString(cmd.name) {
+		return "", errors.New("el nombre del snapshot debe tener entre 1 y 16 caracteres (letras, números, '_' o '-')")
+	}
+
+	if cmd.delete {
+		if err := commandSnapshotDelete(cmd); err != nil {
+			return "", fmt.Errorf("error al eliminar el snapshot: %v", err)
+		}
+		return fmt.Sprintf("SNAPSHOT: Snapshot %s de la partición %s eliminado", cmd.name, cmd.id), nil
+	}
+
+	if err := commandSnapshot(cmd); err != nil {
+		return "", fmt.Errorf("error al crear el snapshot: %v", err)
+	}
+	return fmt.Sprintf("SNAPSHOT: Snapshot %s de la partición %s creado", cmd.name, cmd.id), nil
+}
+
+func ParseRollback(tokens []string) (string, error) {
+	cmd := &ROLLBACK{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key := strings.ToLower(parts[0])
+
+		switch key {
+		case "-id":
+			cmd.id = strings.ToUpper(parts[1])
+		case "-name":
+			cmd.name = parts[1]
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.id == "" || cmd.name == "" {
+		return "", errors.New("faltan parámetros requeridos: -id, -name")
+	}
+
+	if err := commandRollback(cmd); err != nil {
+		return "", fmt.Errorf("error al restaurar el snapshot: %v", err)
+	}
+	return fmt.Sprintf("ROLLBACK: Partición %s restaurada al snapshot %s", cmd.id, cmd.name), nil
+}
+
+func ParseSnapshots(tokens []string) (string, error) {
+	cmd := &SNAPSHOTS{}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+		key := strings.ToLower(parts[0])
+
+		if key == "-id" {
+			cmd.id = strings.ToUpper(parts[1])
+		} else {
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.id == "" {
+		return "", errors.New("faltan parámetros requeridos: -id")
+	}
+
+	output, err := commandSnapshots(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al listar snapshots: %v", err)
+	}
+	return output, nil
+}
+
+// snapshotPartition valida la sesión y devuelve el superbloque, la partición y el disco
+func snapshotPartition(id string, needRoot bool) (*structures.SuperBlock, *structures.Partition, string, error) {
+	if stores.CurrentSession.ID == "" {
+		return nil, nil, "", errors.New("no hay sesión activa, inicie sesión primero")
+	}
+	if needRoot && stores.CurrentSession.Username != "root" {
+		return nil, nil, "", errors.New("solo el usuario root puede administrar snapshots")
+	}
+
+	sb, partition, diskPath, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("error al obtener la partición montada: %v", err)
+	}
+	if sb.S_magic != 0xEF53 {
+		return nil, nil, "", fmt.Errorf("la partición %s no está formateada", id)
+	}
+	return sb, partition, diskPath, nil
+}
+
+// snapshotDir devuelve la carpeta del anfitrión donde se guardan los snapshots de una
+// partición: <carpeta del disco>/snapshots/<disco>/<partición>
+func snapshotDir(diskPath string, partition *structures.Partition) string {
+	diskName := strings.TrimSuffix(filepath.Base(diskPath), filepath.Ext(diskPath))
+	partName := strings.Trim(string(partition.Part_name[:]), "\x00")
+	return filepath.Join(filepath.Dir(diskPath), "snapshots", diskName, partName)
+}
+
+// loadSnapshots lee todos los snapshots válidos de una partición ordenados por fecha
+func loadSnapshots(dir string, partition *structures.Partition) ([]*structures.Snapshot, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*structures.Snapshot
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".snap" {
+			continue
+		}
+		snap := &structures.Snapshot{}
+		if err := snap.Deserialize(filepath.Join(dir, entry.Name())); err != nil {
+			return nil, err
+		}
+		// Un snapshot de una partición anterior con el mismo nombre no cuenta
+		if snap.Header.PartStart != partition.Part_start {
+			continue
+		}
+		snapshots = append(snapshots, snap)
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].Header.Date < snapshots[j].Header.Date
+	})
+	return snapshots, nil
+}
+
+// rebaseSnapshots actualiza los snapshots de una partición cuyos datos se movieron delta
+// bytes dentro del disco. Se llama antes de cambiar Part_start
+func rebaseSnapshots(diskPath string, partition *structures.Partition, delta int64) error {
+	dir := snapshotDir(diskPath, partition)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".snap" {
+			continue
+		}
+		snapPath := filepath.Join(dir, entry.Name())
+		snap := &structures.Snapshot{}
+		if err := snap.Deserialize(snapPath); err != nil {
+			return err
+		}
+		if snap.Header.PartStart != partition.Part_start {
+			continue
+		}
+		snap.Header.PartStart += int32(delta)
+		snap.SuperBlock.Rebase(delta)
+		if err := snap.Serialize(snapPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func commandSnapshot(snapshot *SNAPSHOT) error {
+	sb, partition, diskPath, err := snapshotPartition(snapshot.id, true)
+	if err != nil {
+		return err
+	}
+
+	dir := snapshotDir(diskPath, partition)
+	snapPath := filepath.Join(dir, snapshot.name+".snap")
+	if _, err := os.Stat(snapPath); err == nil {
+		return fmt.Errorf("ya existe un snapshot llamado %s", snapshot.name)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error al crear la carpeta de snapshots: %v", err)
+	}
+
+	// Registrar en el Journal antes de copiarlo, así el snapshot incluye su propia entrada
+	err = AddJournalEntry(sb, diskPath, "snapshot", "/", snapshot.name)
+	if err != nil {
+		return fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+
+	snap, err := sb.TakeSnapshot(diskPath, snapshot.name, partition.Part_start)
+	if err != nil {
+		return err
+	}
+	if err := snap.Serialize(snapPath); err != nil {
+		return fmt.Errorf("error al guardar el snapshot: %v", err)
+	}
+
+	// Reservar los bloques de archivo que ahora comparte con la partición
+	if err := sb.HoldSnapshot(diskPath, snap); err != nil {
+		os.Remove(snapPath)
+		return fmt.Errorf("error al reservar los bloques del snapshot: %v", err)
+	}
+	return nil
+}
+
+func commandSnapshotDelete(snapshot *SNAPSHOT) error {
+	sb, partition, diskPath, err := snapshotPartition(snapshot.id, true)
+	if err != nil {
+		return err
+	}
+
+	snapPath := filepath.Join(snapshotDir(diskPath, partition), snapshot.name+".snap")
+	snap := &structures.Snapshot{}
+	if err := snap.Deserialize(snapPath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no existe el snapshot %s", snapshot.name)
+		}
+		return err
+	}
+
+	if snap.Header.PartStart == partition.Part_start {
+		if err := sb.ReleaseSnapshot(diskPath, snap); err != nil {
+			return fmt.Errorf("error al liberar los bloques del snapshot: %v", err)
+		}
+		if err := sb.Serialize(diskPath, sb.Offset()); err != nil {
+			return fmt.Errorf("error al actualizar superbloque: %v", err)
+		}
+	}
+	return os.Remove(snapPath)
+}
+
+func commandRollback(rollback *ROLLBACK) error {
+	sb, partition, diskPath, err := snapshotPartition(rollback.id, true)
+	if err != nil {
+		return err
+	}
+
+	snapshots, err := loadSnapshots(snapshotDir(diskPath, partition), partition)
+	if err != nil {
+		return err
+	}
+
+	var target *structures.Snapshot
+	var others []*structures.Snapshot
+	for _, snap := range snapshots {
+		if snap.GetName() == rollback.name {
+			target = snap
+		} else {
+			others = append(others, snap)
+		}
+	}
+	if target == nil {
+		return fmt.Errorf("no existe el snapshot %s", rollback.name)
+	}
+
+	if err := sb.RestoreSnapshot(diskPath, target, others); err != nil {
+		return err
+	}
+
+	err = AddJournalEntry(sb, diskPath, "rollback", "/", rollback.name)
+	if err != nil {
+		return fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	return nil
+}
+
+func commandSnapshots(snapshots *SNAPSHOTS) (string, error) {
+	sb, partition, diskPath, err := snapshotPartition(snapshots.id, false)
+	if err != nil {
+		return "", err
+	}
+
+	dir := snapshotDir(diskPath, partition)
+	list, err := loadSnapshots(dir, partition)
+	if err != nil {
+		return "", err
+	}
+	if len(list) == 0 {
+		return fmt.Sprintf("SNAPSHOTS: La partición %s no tiene snapshots", snapshots.id), nil
+	}
+
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("SNAPSHOTS: Snapshots de la partición %s:\n", snapshots.id))
+	for _, snap := range list {
+		info, err := os.Stat(filepath.Join(dir, snap.GetName()+".snap"))
+		if err != nil {
+			return "", err
+		}
+
+		// Los bloques que solo el snapshot mantiene son el espacio que libera al eliminarlo
+		exclusive := int32(0)
+		for _, blockNum := range snap.SharedBlocks {
+			refs, err := sb.GetBlockRefs(diskPath, blockNum)
+			if err != nil {
+				return "", err
+			}
+			if refs == 1 {
+				exclusive++
+			}
+		}
+
+		date := time.Unix(int64(snap.Header.Date), 0).Format("2006-01-02 15:04:05")
+		output.WriteString(fmt.Sprintf("  %s  Fecha: %s  Metadatos: %d bytes  Bloques compartidos: %d  Bloques exclusivos: %d (%d bytes)\n",
+			snap.GetName(), date, info.Size(), len(snap.SharedBlocks), exclusive, exclusive*sb.S_block_size))
+	}
+	return output.String(), nil
+}
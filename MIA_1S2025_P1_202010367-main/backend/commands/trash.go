@@ -0,0 +1,518 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+	"github.com/MarceJua/MIA_1S2025_P1_202010367/backend/utils"
+)
+
+const (
+	trashFolder = ".trash" // Carpeta de la papelera en la raíz de la partición
+	trashIndex  = ".index" // Archivo con la ruta original y la fecha de cada entrada
+	// Porcentaje mínimo de bloques libres; por debajo se purgan las entradas más antiguas
+	trashMinFreePercent = 10
+)
+
+// TRASH estructura que representa el comando trash con sus parámetros
+type TRASH struct {
+	enable  bool   // Opción -enable (crea la papelera)
+	list    bool   // Opción -list
+	restore string // Nombre de la entrada a restaurar
+	empty   bool   // Opción -empty
+}
+
+// trashEntry es una línea del índice de la papelera
+type trashEntry struct {
+	name string  // Nombre dentro de /.trash
+	date float32 // Fecha de eliminación
+	path string  // Ruta original
+}
+
+/*
+   trash -enable
+   trash -list
+   trash -restore=a.txt
+   trash -empty
+*/
+
+func ParseTrash(tokens []string) (string, error) {
+	cmd := &TRASH{}
+	options := 0
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		key := strings.ToLower(parts[0])
+
+		switch key {
+		case "-enable", "-list", "-empty":
+			if len(parts) != 1 {
+				return "", fmt.Errorf("formato inválido para %s: %s", key, token)
+			}
+			switch key {
+			case "-enable":
+				cmd.enable = true
+			case "-list":
+				cmd.list = true
+			default:
+				cmd.empty = true
+			}
+		case "-restore":
+			if len(parts) != 2 || strings.Trim(parts[1], "\"") == "" {
+				return "", fmt.Errorf("formato inválido para -restore: %s", token)
+			}
+			cmd.restore = strings.Trim(parts[1], "\"")
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+		options++
+	}
+
+	if options != 1 {
+		return "", errors.New("debe indicar exactamente una opción: -enable, -list, -restore o -empty")
+	}
+
+	switch {
+	case cmd.enable:
+		if err := commandTrashEnable(); err != nil {
+			return "", fmt.Errorf("error al habilitar la papelera: %v", err)
+		}
+		return "TRASH: Papelera habilitada en /" + trashFolder, nil
+	case cmd.list:
+		output, err := commandTrashList()
+		if err != nil {
+			return "", fmt.Errorf("error al listar la papelera: %v", err)
+		}
+		return output, nil
+	case cmd.empty:
+		count, err := commandTrashEmpty()
+		if err != nil {
+			return "", fmt.Errorf("error al vaciar la papelera: %v", err)
+		}
+		return fmt.Sprintf("TRASH: %d elementos eliminados definitivamente", count), nil
+	default:
+		original, err := commandTrashRestore(cmd.restore)
+		if err != nil {
+			return "", fmt.Errorf("error al restaurar %s: %v", cmd.restore, err)
+		}
+		return fmt.Sprintf("TRASH: %s restaurado en %s", cmd.restore, original), nil
+	}
+}
+
+// trashPartition valida la sesión y devuelve el superbloque y el disco de la partición
+func trashPartition(needRoot bool) (*structures.SuperBlock, string, error) {
+	if stores.CurrentSession.ID == "" {
+		return nil, "", errors.New("no hay sesión activa, inicie sesión primero")
+	}
+	if needRoot && stores.CurrentSession.Username != "root" {
+		return nil, "", errors.New("solo el usuario root puede realizar esta operación")
+	}
+
+	sb, _, diskPath, err := stores.GetMountedPartitionSuperblock(stores.CurrentSession.ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("error al obtener la partición montada: %v", err)
+	}
+	return sb, diskPath, nil
+}
+
+// isTrashPath indica si una ruta es la papelera o está dentro de ella
+func isTrashPath(parentDirs []string, name string) bool {
+	if len(parentDirs) == 0 {
+		return name == trashFolder
+	}
+	return parentDirs[0] == trashFolder
+}
+
+// trashEnabled indica si la partición tiene papelera y devuelve el inodo de /.trash
+func trashEnabled(sb *structures.SuperBlock, diskPath string) (int32, bool) {
+	inodeNum, err := findInodeByPath(diskPath, sb, "/"+trashFolder)
+	if err != nil {
+		return -1, false
+	}
+	return inodeNum, true
+}
+
+// readTrashIndex lee las entradas del índice de la papelera
+func readTrashIndex(sb *structures.SuperBlock, diskPath string) ([]trashEntry, error) {
+	content, err := readFile(sb, diskPath, "/"+trashFolder+"/"+trashIndex)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer el índice de la papelera: %v", err)
+	}
+
+	// Cada línea es fecha,nombre,ruta. El nombre y la ruta pueden tener comas, pero el
+	// nombre no tiene '/' y la ruta empieza con '/': el primer ",/" los separa
+	var entries []trashEntry
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), ",", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		date, err := strconv.ParseFloat(fields[0], 32)
+		if err != nil {
+			continue
+		}
+		sep := strings.Index(fields[1], ",/")
+		if sep == -1 {
+			continue
+		}
+		entries = append(entries, trashEntry{name: fields[1][:sep], date: float32(date), path: fields[1][sep+1:]})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].date < entries[j].date
+	})
+	return entries, nil
+}
+
+// writeTrashIndex reescribe el índice de la papelera. Si las entradas no caben en el
+// archivo se eliminan definitivamente las más antiguas
+func writeTrashIndex(sb *structures.SuperBlock, diskPath string, entries []trashEntry) ([]trashEntry, error) {
+	indexInodeNum, err := findInodeByPath(diskPath, sb, "/"+trashFolder+"/"+trashIndex)
+	if err != nil {
+		return entries, fmt.Errorf("no se encontró el índice de la papelera: %v", err)
+	}
+	indexInode := &structures.Inode{}
+	err = indexInode.Deserialize(diskPath, int64(sb.S_inode_start+indexInodeNum*sb.S_inode_size))
+	if err != nil {
+		return entries, fmt.Errorf("error al leer el índice de la papelera: %v", err)
+	}
+
+	maxSize := 12 * int(sb.S_block_size)
+	for {
+		var content strings.Builder
+		for _, entry := range entries {
+			content.WriteString(fmt.Sprintf("%d,%s,%s\n", int64(entry.date), entry.name, entry.path))
+		}
+		if content.Len() <= maxSize || len(entries) == 0 {
+			err = sb.WriteFileContent(diskPath, indexInode, []byte(content.String()))
+			break
+		}
+		if err := deleteTrashEntry(sb, diskPath, entries[0].name); err != nil {
+			return entries, err
+		}
+		entries = entries[1:]
+	}
+	if err != nil {
+		return entries, fmt.Errorf("error al escribir el índice de la papelera: %v", err)
+	}
+
+	indexInode.I_mtime = float32(time.Now().Unix())
+	err = indexInode.Serialize(diskPath, int64(sb.S_inode_start+indexInodeNum*sb.S_inode_size))
+	if err != nil {
+		return entries, fmt.Errorf("error al actualizar el índice de la papelera: %v", err)
+	}
+	return entries, nil
+}
+
+// deleteTrashEntry elimina definitivamente una entrada de /.trash
+func deleteTrashEntry(sb *structures.SuperBlock, diskPath string, name string) error {
+	trashInodeNum, ok := trashEnabled(sb, diskPath)
+	if !ok {
+		return errors.New("la papelera no está habilitada")
+	}
+	inodeNum, err := findInodeByPath(diskPath, sb, "/"+trashFolder+"/"+name)
+	if err != nil {
+		// La entrada ya no existe (por ejemplo, se eliminó a mano); solo se quita del índice
+		return nil
+	}
+	if err := deleteInode(sb, diskPath, inodeNum, name); err != nil {
+		return fmt.Errorf("error al eliminar %s de la papelera: %v", name, err)
+	}
+	return unlinkEntry(sb, diskPath, trashInodeNum, name, inodeNum)
+}
+
+// unlinkEntry quita la entrada name del directorio parentInodeNum
+func unlinkEntry(sb *structures.SuperBlock, diskPath string, parentInodeNum int32, name string, inodeNum int32) error {
+	parentInode := &structures.Inode{}
+	err := parentInode.Deserialize(diskPath, int64(sb.S_inode_start+parentInodeNum*sb.S_inode_size))
+	if err != nil {
+		return fmt.Errorf("error al leer inodo padre %d: %v", parentInodeNum, err)
+	}
+	for _, blockNum := range parentInode.I_block[:12] {
+		if blockNum == -1 {
+			continue
+		}
+		folderBlock := &structures.FolderBlock{}
+		err = folderBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
+		if err != nil {
+			return fmt.Errorf("error al leer bloque de carpeta %d: %v", blockNum, err)
+		}
+		for i, content := range folderBlock.B_content {
+			if strings.Trim(string(content.B_name[:]), "\x00") == name && content.B_inodo == inodeNum {
+				folderBlock.B_content[i] = structures.FolderContent{B_name: structures.ToByte12("-"), B_inodo: -1}
+				err = folderBlock.Serialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
+				if err != nil {
+					return fmt.Errorf("error al actualizar bloque de carpeta %d: %v", blockNum, err)
+				}
+				parentInode.I_mtime = float32(time.Now().Unix())
+				return parentInode.Serialize(diskPath, int64(sb.S_inode_start+parentInodeNum*sb.S_inode_size))
+			}
+		}
+	}
+	return nil
+}
+
+// trashName busca un nombre libre dentro de /.trash para la entrada eliminada
+func trashName(sb *structures.SuperBlock, diskPath string, name string) (string, error) {
+	for n := 0; n < 1000; n++ {
+		candidate := name
+		if n > 0 {
+			candidate = fmt.Sprintf("%d_%s", n, name)
+		}
+		if len(candidate) > 12 {
+			candidate = candidate[:12]
+		}
+		if candidate == trashIndex {
+			continue
+		}
+		if !checkParentExists(sb, diskPath, []string{trashFolder, candidate}) {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no hay nombres libres en la papelera para %s", name)
+}
+
+// moveToTrash mueve una entrada a /.trash y registra su ruta original en el índice
+func moveToTrash(sb *structures.SuperBlock, diskPath string, inodeNum, parentInodeNum int32, name, originalPath string) error {
+	trashInodeNum, ok := trashEnabled(sb, diskPath)
+	if !ok {
+		return errors.New("la papelera no está habilitada")
+	}
+
+	entries, err := readTrashIndex(sb, diskPath)
+	if err != nil {
+		return err
+	}
+	newName, err := trashName(sb, diskPath, name)
+	if err != nil {
+		return err
+	}
+
+	err = moveInode(sb, diskPath, inodeNum, parentInodeNum, name, trashInodeNum, newName)
+	if err != nil {
+		return fmt.Errorf("error al mover %s a la papelera: %v", originalPath, err)
+	}
+
+	originalPath = "/" + strings.Trim(path.Clean(originalPath), "/")
+	entries = append(entries, trashEntry{name: newName, date: float32(time.Now().Unix()), path: originalPath})
+	_, err = writeTrashIndex(sb, diskPath, entries)
+	return err
+}
+
+// dropTrashEntry quita del índice una entrada de primer nivel de la papelera que se
+// eliminó definitivamente con remove
+func dropTrashEntry(sb *structures.SuperBlock, diskPath string, name string) error {
+	if name == trashIndex {
+		return nil
+	}
+	entries, err := readTrashIndex(sb, diskPath)
+	if err != nil {
+		return err
+	}
+	for i, entry := range entries {
+		if entry.name == name {
+			_, err = writeTrashIndex(sb, diskPath, append(entries[:i], entries[i+1:]...))
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeTrash elimina definitivamente las entradas más antiguas de la papelera mientras
+// los bloques libres estén por debajo del mínimo. Devuelve las entradas purgadas
+func purgeTrash(sb *structures.SuperBlock, diskPath string) (int, error) {
+	if _, ok := trashEnabled(sb, diskPath); !ok {
+		return 0, nil
+	}
+	minFree := sb.S_blocks_count * trashMinFreePercent / 100
+	if sb.S_free_blocks_count >= minFree {
+		return 0, nil
+	}
+
+	entries, err := readTrashIndex(sb, diskPath)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for len(entries) > 0 && sb.S_free_blocks_count < minFree {
+		if err := deleteTrashEntry(sb, diskPath, entries[0].name); err != nil {
+			return purged, err
+		}
+		entries = entries[1:]
+		purged++
+	}
+	if purged == 0 {
+		return 0, nil
+	}
+	if _, err := writeTrashIndex(sb, diskPath, entries); err != nil {
+		return purged, err
+	}
+
+	err = AddJournalEntry(sb, diskPath, "purge", "/"+trashFolder, fmt.Sprintf("%d elementos", purged))
+	if err != nil {
+		return purged, fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	return purged, sb.Serialize(diskPath, sb.Offset())
+}
+
+func commandTrashEnable() error {
+	sb, diskPath, err := trashPartition(true)
+	if err != nil {
+		return err
+	}
+	if _, ok := trashEnabled(sb, diskPath); ok {
+		return errors.New("la papelera ya está habilitada")
+	}
+
+	if err := sb.CreateFolder(diskPath, []string{}, trashFolder); err != nil {
+		return fmt.Errorf("error al crear /%s: %v", trashFolder, err)
+	}
+	if err := createFile(sb, diskPath, []string{trashFolder}, trashIndex, ""); err != nil {
+		return fmt.Errorf("error al crear el índice de la papelera: %v", err)
+	}
+
+	if err := sb.Serialize(diskPath, sb.Offset()); err != nil {
+		return fmt.Errorf("error al actualizar superbloque: %v", err)
+	}
+	err = AddJournalEntry(sb, diskPath, "mkdir", "/"+trashFolder, "-")
+	if err != nil {
+		return fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	return nil
+}
+
+func commandTrashList() (string, error) {
+	sb, diskPath, err := trashPartition(false)
+	if err != nil {
+		return "", err
+	}
+	if _, ok := trashEnabled(sb, diskPath); !ok {
+		return "", errors.New("la papelera no está habilitada, use trash -enable")
+	}
+
+	entries, err := readTrashIndex(sb, diskPath)
+	if err != nil {
+		return "", err
+	}
+	if len(entries) == 0 {
+		return "TRASH: La papelera está vacía", nil
+	}
+
+	var output strings.Builder
+	output.WriteString("TRASH: Contenido de la papelera:\n")
+	for _, entry := range entries {
+		date := time.Unix(int64(entry.date), 0).Format("2006-01-02 15:04:05")
+		output.WriteString(fmt.Sprintf("  %s  Ruta original: %s  Eliminado: %s\n", entry.name, entry.path, date))
+	}
+	return output.String(), nil
+}
+
+func commandTrashRestore(name string) (string, error) {
+	sb, diskPath, err := trashPartition(false)
+	if err != nil {
+		return "", err
+	}
+	trashInodeNum, ok := trashEnabled(sb, diskPath)
+	if !ok {
+		return "", errors.New("la papelera no está habilitada, use trash -enable")
+	}
+
+	entries, err := readTrashIndex(sb, diskPath)
+	if err != nil {
+		return "", err
+	}
+	index := -1
+	for i, entry := range entries {
+		if entry.name == name {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return "", fmt.Errorf("no existe %s en la papelera", name)
+	}
+	entry := entries[index]
+
+	inodeNum, err := findInodeByPath(diskPath, sb, "/"+trashFolder+"/"+name)
+	if err != nil {
+		return "", fmt.Errorf("no se encontró %s en /%s: %v", name, trashFolder, err)
+	}
+	inode := &structures.Inode{}
+	err = inode.Deserialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size))
+	if err != nil {
+		return "", fmt.Errorf("error al leer inodo %d: %v", inodeNum, err)
+	}
+	if !checkWritePermission(inode, stores.CurrentSession) {
+		return "", fmt.Errorf("no tiene permisos de escritura para %s", name)
+	}
+
+	// Volver a crear las carpetas de la ruta original si ya no existen
+	parentDirs, originalName := utils.GetParentDirectories(entry.path)
+	if len(parentDirs) > 0 {
+		if err := createParentFolders(sb, diskPath, parentDirs); err != nil {
+			return "", fmt.Errorf("error al crear directorios padres para %s: %v", entry.path, err)
+		}
+	}
+	if checkParentExists(sb, diskPath, append(parentDirs, originalName)) {
+		return "", fmt.Errorf("ya existe %s", entry.path)
+	}
+	parentInodeNum, err := findInodeByPath(diskPath, sb, "/"+strings.Join(parentDirs, "/"))
+	if err != nil {
+		return "", fmt.Errorf("error al encontrar directorio padre de %s: %v", entry.path, err)
+	}
+
+	err = moveInode(sb, diskPath, inodeNum, trashInodeNum, name, parentInodeNum, originalName)
+	if err != nil {
+		return "", fmt.Errorf("error al mover %s: %v", name, err)
+	}
+	if _, err := writeTrashIndex(sb, diskPath, append(entries[:index], entries[index+1:]...)); err != nil {
+		return "", err
+	}
+
+	if err := sb.Serialize(diskPath, sb.Offset()); err != nil {
+		return "", fmt.Errorf("error al actualizar superbloque: %v", err)
+	}
+	err = AddJournalEntry(sb, diskPath, "restore", entry.path, name)
+	if err != nil {
+		return "", fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	return entry.path, nil
+}
+
+func commandTrashEmpty() (int, error) {
+	sb, diskPath, err := trashPartition(true)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := trashEnabled(sb, diskPath); !ok {
+		return 0, errors.New("la papelera no está habilitada, use trash -enable")
+	}
+
+	entries, err := readTrashIndex(sb, diskPath)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if err := deleteTrashEntry(sb, diskPath, entry.name); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := writeTrashIndex(sb, diskPath, nil); err != nil {
+		return 0, err
+	}
+
+	if err := sb.Serialize(diskPath, sb.Offset()); err != nil {
+		return 0, fmt.Errorf("error al actualizar superbloque: %v", err)
+	}
+	err = AddJournalEntry(sb, diskPath, "empty", "/"+trashFolder, fmt.Sprintf("%d elementos", len(entries)))
+	if err != nil {
+		return 0, fmt.Errorf("error al registrar en el Journal: %v", err)
+	}
+	return len(entries), nil
+}
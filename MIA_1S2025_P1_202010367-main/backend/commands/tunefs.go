@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+)
+
+// TUNEFS estructura que representa el comando tunefs con sus parámetros
+type TUNEFS struct {
+	maxMount int32  // Montajes antes de requerir fsck (-1 = sin cambio)
+	interval int32  // Días antes de requerir fsck (-1 = sin cambio)
+	id       string // Partición a marcar como verificada
+	reset    bool   // Reiniciar el contador de montajes y la fecha de verificación
+}
+
+/*
+   tunefs
+   tunefs -maxmount=30 -interval=90
+   tunefs -id=671A -reset
+*/
+
+func ParseTunefs(tokens []string) (string, error) {
+	cmd := &TUNEFS{maxMount: -1, interval: -1}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		key := strings.ToLower(parts[0])
+		if key == "-reset" && len(parts) == 1 {
+			cmd.reset = true
+			continue
+		}
+		if len(parts) != 2 || parts[1] == "" {
+			return "", fmt.Errorf("formato inválido: %s", token)
+		}
+
+		switch key {
+		case "-maxmount", "-interval":
+			value, err := strconv.Atoi(parts[1])
+			if err != nil || value < 0 {
+				return "", fmt.Errorf("%s debe ser un entero no negativo (0 = sin límite)", key)
+			}
+			if key == "-maxmount" {
+				cmd.maxMount = int32(value)
+			} else {
+				cmd.interval = int32(value)
+			}
+		case "-id":
+			cmd.id = parts[1]
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.reset != (cmd.id != "") {
+		return "", errors.New("-reset y -id deben usarse juntos")
+	}
+
+	return commandTunefs(cmd)
+}
+
+// commandTunefs cambia la política de fsck o marca una partición como verificada, como
+// tune2fs -c, -i y -C 0
+func commandTunefs(tunefs *TUNEFS) (string, error) {
+	var output strings.Builder
+	if tunefs.maxMount >= 0 {
+		stores.MaxMountCount = tunefs.maxMount
+	}
+	if tunefs.interval >= 0 {
+		stores.CheckInterval = tunefs.interval
+	}
+
+	if tunefs.reset {
+		sb, _, diskPath, err := stores.GetMountedPartitionSuperblock(tunefs.id)
+		if err != nil {
+			return "", fmt.Errorf("error al obtener la partición montada: %v", err)
+		}
+		sb.S_mnt_count = 0
+		if err := sb.Serialize(diskPath, sb.Offset()); err != nil {
+			return "", fmt.Errorf("error al actualizar superbloque: %v", err)
+		}
+		if err := stores.MarkChecked(diskPath, stores.MountedName(tunefs.id)); err != nil {
+			return "", err
+		}
+		output.WriteString(fmt.Sprintf("TUNEFS: Partición %s marcada como verificada\n", tunefs.id))
+	} else if err := stores.SaveMountTable(); err != nil {
+		return "", err
+	}
+
+	output.WriteString(fmt.Sprintf("TUNEFS: fsck cada %s montajes o %s días", stores.PolicyLimit(stores.MaxMountCount), stores.PolicyLimit(stores.CheckInterval)))
+	return output.String(), nil
+}
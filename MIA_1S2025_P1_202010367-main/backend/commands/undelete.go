@@ -0,0 +1,564 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+)
+
+const lostFoundFolder = "lost+found" // Carpeta donde se dejan los inodos recuperados
+
+// UNDELETE estructura que representa el comando undelete con sus parámetros
+type UNDELETE struct {
+	id      string // ID de la partición
+	name    string // Patrón del nombre (opcional)
+	inode   int32  // Inodo a recuperar (opcional)
+	recover bool   // Opción -recover (recupera todos los candidatos)
+}
+
+// undeleteCandidate es un inodo liberado que todavía conserva su contenido
+type undeleteCandidate struct {
+	inodeNum    int32
+	inode       structures.Inode
+	name        string
+	recoverable bool
+}
+
+/*
+   undelete -id=671A
+   undelete -id=671A -name=*.txt
+   undelete -id=671A -inode=5
+   undelete -id=671A -name=*.txt -recover
+*/
+
+func ParseUndelete(tokens []string) (string, error) {
+	cmd := &UNDELETE{inode: -1}
+
+	for _, token := range tokens {
+		parts := strings.SplitN(token, "=", 2)
+		key := strings.ToLower(parts[0])
+
+		switch key {
+		case "-id", "-name", "-inode":
+			if len(parts) != 2 || strings.Trim(parts[1], "\"") == "" {
+				return "", fmt.Errorf("formato inválido para %s: %s", key, token)
+			}
+			value := strings.Trim(parts[1], "\"")
+			switch key {
+			case "-id":
+				cmd.id = strings.ToUpper(value)
+			case "-name":
+				if _, err := filepath.Match(value, ""); err != nil {
+					return "", fmt.Errorf("patrón inválido: %s", value)
+				}
+				cmd.name = value
+			default:
+				inodeNum, err := strconv.Atoi(value)
+				if err != nil || inodeNum < 0 {
+					return "", fmt.Errorf("el inodo debe ser un número no negativo: %s", value)
+				}
+				cmd.inode = int32(inodeNum)
+			}
+		case "-recover":
+			if len(parts) != 1 {
+				return "", fmt.Errorf("formato inválido para -recover: %s", token)
+			}
+			cmd.recover = true
+		default:
+			return "", fmt.Errorf("parámetro desconocido: %s", key)
+		}
+	}
+
+	if cmd.id == "" {
+		return "", errors.New("faltan parámetros requeridos: -id")
+	}
+	if cmd.recover && cmd.inode != -1 {
+		return "", errors.New("use -inode o -recover, no ambos")
+	}
+
+	if cmd.inode == -1 && !cmd.recover {
+		output, err := commandUndeleteList(cmd)
+		if err != nil {
+			return "", fmt.Errorf("error al buscar inodos eliminados: %v", err)
+		}
+		return output, nil
+	}
+
+	recovered, err := commandUndelete(cmd)
+	if err != nil {
+		return "", fmt.Errorf("error al recuperar: %v", err)
+	}
+	if len(recovered) == 0 {
+		return "UNDELETE: No hay candidatos recuperables", nil
+	}
+	return fmt.Sprintf("UNDELETE: Recuperados en /%s: %s", lostFoundFolder, strings.Join(recovered, ", ")), nil
+}
+
+// undeletePartition valida la sesión y devuelve el superbloque y el disco de la partición
+func undeletePartition(id string) (*structures.SuperBlock, string, error) {
+	if stores.CurrentSession.ID == "" {
+		return nil, "", errors.New("no hay sesión activa, inicie sesión primero")
+	}
+	if stores.CurrentSession.Username != "root" {
+		return nil, "", errors.New("solo el usuario root puede recuperar inodos eliminados")
+	}
+
+	sb, _, diskPath, err := stores.GetMountedPartitionSuperblock(id)
+	if err != nil {
+		return nil, "", fmt.Errorf("error al obtener la partición montada: %v", err)
+	}
+	if sb.S_magic != 0xEF53 {
+		return nil, "", fmt.Errorf("la partición %s no está formateada", id)
+	}
+	return sb, diskPath, nil
+}
+
+// plausibleTombstone indica si un inodo libre conserva datos coherentes de un archivo o
+// carpeta eliminado
+func plausibleTombstone(sb *structures.SuperBlock, inode *structures.Inode) bool {
+	if inode.I_type[0] != '0' && inode.I_type[0] != '1' {
+		return false
+	}
+	for _, p := range inode.I_perm {
+		if p < '0' || p > '7' {
+			return false
+		}
+	}
+	if inode.I_atime <= 0 || inode.I_size < 0 || inode.I_size > structures.MaxFileBlocks*sb.S_block_size {
+		return false
+	}
+
+	blocks := int32(0)
+	for _, blockNum := range inode.I_block[:12] {
+		if blockNum == -1 {
+			continue
+		}
+		if blockNum < 0 || blockNum >= sb.S_blocks_count {
+			return false
+		}
+		blocks++
+	}
+	if inode.I_type[0] == '0' {
+		return inode.I_block[0] != -1
+	}
+	if inode.TombstoneUsesIndirect(sb.S_block_size) {
+		for _, blockNum := range inode.I_block[12:] {
+			if blockNum < -1 || blockNum >= sb.S_blocks_count {
+				return false
+			}
+		}
+		return blocks == 12 && inode.I_block[12] != -1
+	}
+	return blocks == (inode.I_size+sb.S_block_size-1)/sb.S_block_size
+}
+
+// tombstoneBlocks devuelve las referencias que el inodo eliminado tenía a cada bloque,
+// incluidos los de apuntadores; un archivo deduplicado puede repetir un bloque. Devuelve
+// false si los apuntadores ya no son coherentes con el tamaño, por ejemplo porque uno de
+// los bloques de apuntadores se reutilizó
+func tombstoneBlocks(sb *structures.SuperBlock, diskPath string, inode *structures.Inode) (map[int32]int32, bool) {
+	refs := make(map[int32]int32)
+	if !inode.TombstoneUsesIndirect(sb.S_block_size) {
+		for _, blockNum := range inode.I_block[:12] {
+			if blockNum != -1 {
+				refs[blockNum]++
+			}
+		}
+		return refs, true
+	}
+
+	data, pointers, err := sb.FileBlocks(diskPath, inode)
+	if err != nil || int32(len(data)) != (inode.I_size+sb.S_block_size-1)/sb.S_block_size {
+		return nil, false
+	}
+	for _, blockNum := range data {
+		refs[blockNum]++
+	}
+	for _, blockNum := range pointers {
+		if refs[blockNum] != 0 {
+			return nil, false
+		}
+		refs[blockNum] = 1
+	}
+	return refs, true
+}
+
+// tombstoneBlocksFree indica si ningún bloque del inodo fue asignado de nuevo
+func tombstoneBlocksFree(sb *structures.SuperBlock, diskPath string, inode *structures.Inode) (bool, error) {
+	blocks, ok := tombstoneBlocks(sb, diskPath, inode)
+	if !ok {
+		return false, nil
+	}
+	for blockNum := range blocks {
+		refs, err := sb.GetBlockRefs(diskPath, blockNum)
+		if err != nil {
+			return false, err
+		}
+		if refs != 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// findUndeleteCandidates recorre la tabla de inodos buscando lápidas que coincidan con el
+// patrón, de la eliminación más reciente a la más antigua
+func findUndeleteCandidates(sb *structures.SuperBlock, diskPath string, pattern string) ([]undeleteCandidate, error) {
+	inodes, err := sb.ReadInodeTable(diskPath)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer tabla de inodos: %v", err)
+	}
+	inodeBitmap, err := sb.ReadInodeBitmap(diskPath)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+
+	var candidates []undeleteCandidate
+	for i := range inodes {
+		inode := &inodes[i]
+		if inodeBitmap[i] == '1' || !plausibleTombstone(sb, inode) {
+			continue
+		}
+		name := inode.TombstoneName(sb.S_block_size)
+		if pattern != "" {
+			if matched, _ := filepath.Match(pattern, name); !matched {
+				continue
+			}
+		}
+		recoverable, err := tombstoneBlocksFree(sb, diskPath, inode)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, undeleteCandidate{
+			inodeNum:    int32(i),
+			inode:       *inode,
+			name:        name,
+			recoverable: recoverable,
+		})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].inode.I_atime > candidates[j].inode.I_atime
+	})
+	return candidates, nil
+}
+
+// recoverInode vuelve a ocupar un inodo eliminado y sus bloques. En una carpeta recupera
+// también los hijos que sigan intactos y quita las entradas de los que no
+func recoverInode(sb *structures.SuperBlock, diskPath string, inodeNum int32) error {
+	inode := &structures.Inode{}
+	err := inode.Deserialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size))
+	if err != nil {
+		return fmt.Errorf("error al leer inodo %d: %v", inodeNum, err)
+	}
+
+	blocks, ok := tombstoneBlocks(sb, diskPath, inode)
+	if !ok {
+		return fmt.Errorf("los apuntadores del inodo %d ya no son válidos", inodeNum)
+	}
+	for blockNum, refs := range blocks {
+		if err := sb.SetBlockRefs(diskPath, blockNum, refs); err != nil {
+			return fmt.Errorf("error al ocupar bloque %d: %v", blockNum, err)
+		}
+		sb.S_free_blocks_count--
+	}
+	if err := sb.UpdateBitmapInode(diskPath, inodeNum); err != nil {
+		return fmt.Errorf("error al ocupar inodo %d: %v", inodeNum, err)
+	}
+	sb.S_free_inodes_count--
+
+	inode.ClearTombstone(sb.S_block_size)
+	inode.I_atime = float32(time.Now().Unix())
+	err = inode.Serialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size))
+	if err != nil {
+		return fmt.Errorf("error al actualizar inodo %d: %v", inodeNum, err)
+	}
+	if inode.I_type[0] != '0' {
+		return nil
+	}
+
+	for _, blockNum := range inode.I_block[:12] {
+		if blockNum == -1 {
+			continue
+		}
+		folderBlock := &structures.FolderBlock{}
+		err = folderBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
+		if err != nil {
+			return fmt.Errorf("error al leer bloque de carpeta %d: %v", blockNum, err)
+		}
+
+		changed := false
+		for i, content := range folderBlock.B_content {
+			name := strings.Trim(string(content.B_name[:]), "\x00")
+			if name == "." || name == ".." || content.B_inodo == -1 {
+				continue
+			}
+
+			intact, err := tombstoneIntact(sb, diskPath, content.B_inodo, name)
+			if err != nil {
+				return err
+			}
+			if intact {
+				if err := recoverInode(sb, diskPath, content.B_inodo); err != nil {
+					return err
+				}
+				continue
+			}
+			folderBlock.B_content[i] = structures.FolderContent{B_name: structures.ToByte12("-"), B_inodo: -1}
+			changed = true
+		}
+		if changed {
+			err = folderBlock.Serialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
+			if err != nil {
+				return fmt.Errorf("error al actualizar bloque de carpeta %d: %v", blockNum, err)
+			}
+		}
+	}
+	return nil
+}
+
+// tombstoneIntact indica si el hijo de una carpeta eliminada sigue siendo la misma lápida
+// y puede recuperarse con ella. Una lápida sin nombre solo se valida por su contenido
+func tombstoneIntact(sb *structures.SuperBlock, diskPath string, inodeNum int32, name string) (bool, error) {
+	if inodeNum < 0 || inodeNum >= sb.S_inodes_count {
+		return false, nil
+	}
+	inodeBitmap, err := sb.ReadInodeBitmap(diskPath)
+	if err != nil {
+		return false, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+	if inodeBitmap[inodeNum] == '1' {
+		return false, nil
+	}
+
+	inode := &structures.Inode{}
+	err = inode.Deserialize(diskPath, int64(sb.S_inode_start+inodeNum*sb.S_inode_size))
+	if err != nil {
+		return false, fmt.Errorf("error al leer inodo %d: %v", inodeNum, err)
+	}
+	if !plausibleTombstone(sb, inode) {
+		return false, nil
+	}
+	if tombstoneName := inode.TombstoneName(sb.S_block_size); tombstoneName != "" && tombstoneName != name {
+		return false, nil
+	}
+	return tombstoneBlocksFree(sb, diskPath, inode)
+}
+
+func commandUndeleteList(undelete *UNDELETE) (string, error) {
+	sb, diskPath, err := undeletePartition(undelete.id)
+	if err != nil {
+		return "", err
+	}
+
+	candidates, err := findUndeleteCandidates(sb, diskPath, undelete.name)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return fmt.Sprintf("UNDELETE: No se encontraron inodos eliminados en la partición %s", undelete.id), nil
+	}
+
+	format := func(t float32) string {
+		return time.Unix(int64(t), 0).Format("2006-01-02 15:04:05")
+	}
+	var output strings.Builder
+	output.WriteString(fmt.Sprintf("UNDELETE: Inodos eliminados en la partición %s:\n", undelete.id))
+	for _, c := range candidates {
+		kind := "archivo"
+		if c.inode.I_type[0] == '0' {
+			kind = "carpeta"
+		}
+		name := c.name
+		if name == "" {
+			name = "(sin nombre)"
+		}
+		state := "recuperable"
+		if !c.recoverable {
+			state = "no recuperable (bloques reutilizados)"
+		}
+		output.WriteString(fmt.Sprintf("  Inodo %d  %s  %s  %d bytes  Creado: %s  Modificado: %s  Eliminado: %s  %s\n",
+			c.inodeNum, name, kind, c.inode.I_size, format(c.inode.I_ctime), format(c.inode.I_mtime), format(c.inode.I_atime), state))
+	}
+	return output.String(), nil
+}
+
+// commandUndelete recupera el inodo indicado o todos los candidatos en /lost+found y
+// devuelve los nombres con los que quedaron
+func commandUndelete(undelete *UNDELETE) ([]string, error) {
+	sb, diskPath, err := undeletePartition(undelete.id)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := findUndeleteCandidates(sb, diskPath, undelete.name)
+	if err != nil {
+		return nil, err
+	}
+	if undelete.inode != -1 {
+		var selected []undeleteCandidate
+		for _, c := range candidates {
+			if c.inodeNum == undelete.inode {
+				selected = append(selected, c)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("el inodo %d no es un inodo eliminado recuperable", undelete.inode)
+		}
+		if !selected[0].recoverable {
+			return nil, fmt.Errorf("los bloques del inodo %d ya fueron reutilizados", undelete.inode)
+		}
+		candidates = selected
+	}
+
+	var recovered []string
+	var lostFoundNum int32 = -1
+	for _, c := range candidates {
+		if !c.recoverable {
+			continue
+		}
+		// Un candidato pudo recuperarse junto con su carpeta
+		intact, err := tombstoneIntact(sb, diskPath, c.inodeNum, c.name)
+		if err != nil {
+			return recovered, err
+		}
+		if !intact {
+			continue
+		}
+
+		if lostFoundNum == -1 {
+			lostFoundNum, err = lostFoundInode(sb, diskPath)
+			if err != nil {
+				return recovered, err
+			}
+		}
+		if err := recoverInode(sb, diskPath, c.inodeNum); err != nil {
+			return recovered, err
+		}
+
+		name := c.name
+		if name == "" || checkParentExists(sb, diskPath, []string{lostFoundFolder, name}) {
+			name = fmt.Sprintf("#%d", c.inodeNum)
+		}
+		if checkParentExists(sb, diskPath, []string{lostFoundFolder, name}) {
+			return recovered, fmt.Errorf("ya existe /%s/%s", lostFoundFolder, name)
+		}
+
+		if err := sb.LinkInode(diskPath, lostFoundNum, name, c.inodeNum); err != nil {
+			return recovered, fmt.Errorf("error al enlazar %s en /%s: %v", name, lostFoundFolder, err)
+		}
+		if c.inode.I_type[0] == '0' {
+			if err := setParentEntry(sb, diskPath, c.inodeNum, lostFoundNum); err != nil {
+				return recovered, err
+			}
+		}
+		recovered = append(recovered, name)
+
+		err = AddJournalEntry(sb, diskPath, "undelete", "/"+lostFoundFolder+"/"+name, strconv.Itoa(int(c.inodeNum)))
+		if err != nil {
+			return recovered, fmt.Errorf("error al registrar en el Journal: %v", err)
+		}
+	}
+
+	if err := sb.Serialize(diskPath, sb.Offset()); err != nil {
+		return recovered, fmt.Errorf("error al actualizar superbloque: %v", err)
+	}
+	return recovered, nil
+}
+
+// lostFoundInode devuelve el inodo de /lost+found. Si no existe la crea con un inodo y un
+// bloque que no pertenezcan a ninguna lápida, para no pisar lo que se quiere recuperar
+func lostFoundInode(sb *structures.SuperBlock, diskPath string) (int32, error) {
+	if inodeNum, err := findInodeByPath(diskPath, sb, "/"+lostFoundFolder); err == nil {
+		return inodeNum, nil
+	}
+
+	tombstones, err := findUndeleteCandidates(sb, diskPath, "")
+	if err != nil {
+		return -1, err
+	}
+	usedInodes := make(map[int32]bool)
+	usedBlocks := make(map[int32]bool)
+	for _, c := range tombstones {
+		usedInodes[c.inodeNum] = true
+		blocks, _ := tombstoneBlocks(sb, diskPath, &c.inode)
+		for blockNum := range blocks {
+			usedBlocks[blockNum] = true
+		}
+	}
+
+	inodeBitmap, err := sb.ReadInodeBitmap(diskPath)
+	if err != nil {
+		return -1, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+	var newInodeNum int32 = -1
+	for i := int32(0); i < sb.S_inodes_count; i++ {
+		if inodeBitmap[i] != '1' && !usedInodes[i] {
+			newInodeNum = i
+			break
+		}
+	}
+	var newBlockNum int32 = -1
+	for i := int32(0); i < sb.S_blocks_count && newInodeNum != -1; i++ {
+		refs, err := sb.GetBlockRefs(diskPath, i)
+		if err != nil {
+			return -1, err
+		}
+		if refs == 0 && !usedBlocks[i] {
+			newBlockNum = i
+			break
+		}
+	}
+	if newInodeNum == -1 || newBlockNum == -1 {
+		return -1, fmt.Errorf("no hay espacio libre para crear /%s sin sobrescribir inodos eliminados", lostFoundFolder)
+	}
+
+	now := float32(time.Now().Unix())
+	inode := &structures.Inode{
+		I_uid:   1,
+		I_gid:   1,
+		I_size:  0,
+		I_atime: now,
+		I_ctime: now,
+		I_mtime: now,
+		I_block: [15]int32{newBlockNum, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1, -1},
+		I_type:  [1]byte{'0'},
+		I_perm:  [3]byte{'7', '0', '0'},
+	}
+	err = inode.Serialize(diskPath, int64(sb.S_inode_start+newInodeNum*sb.S_inode_size))
+	if err != nil {
+		return -1, fmt.Errorf("error al crear inodo de /%s: %v", lostFoundFolder, err)
+	}
+	if err := sb.UpdateBitmapInode(diskPath, newInodeNum); err != nil {
+		return -1, err
+	}
+	sb.S_free_inodes_count--
+
+	folderBlock := &structures.FolderBlock{
+		B_content: [4]structures.FolderContent{
+			{B_name: structures.ToByte12("."), B_inodo: newInodeNum},
+			{B_name: structures.ToByte12(".."), B_inodo: 0},
+			{B_name: structures.ToByte12("-"), B_inodo: -1},
+			{B_name: structures.ToByte12("-"), B_inodo: -1},
+		},
+	}
+	err = folderBlock.Serialize(diskPath, int64(sb.S_block_start+newBlockNum*sb.S_block_size))
+	if err != nil {
+		return -1, fmt.Errorf("error al crear bloque de /%s: %v", lostFoundFolder, err)
+	}
+	if err := sb.UpdateBitmapBlock(diskPath, newBlockNum); err != nil {
+		return -1, err
+	}
+	sb.S_free_blocks_count--
+
+	if err := sb.LinkInode(diskPath, 0, lostFoundFolder, newInodeNum); err != nil {
+		return -1, fmt.Errorf("error al enlazar /%s: %v", lostFoundFolder, err)
+	}
+	return newInodeNum, nil
+}
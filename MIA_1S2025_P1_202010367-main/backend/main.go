@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/binary"
 	"fmt"
 	"os"
@@ -11,6 +12,7 @@ import (
 	analyzer "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/analyzer"
 	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
 	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+	utils "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/utils"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -39,12 +41,13 @@ type Disk struct {
 }
 
 type Partition struct {
-	ID     string  `json:"id"`
-	Path   string  `json:"path"`
-	Name   string  `json:"name"`
-	SizeKB float64 `json:"sizeKB"`
-	Fit    string  `json:"fit"`
-	Status string  `json:"status"`
+	ID      string  `json:"id"`
+	Path    string  `json:"path"`
+	Name    string  `json:"name"`
+	SizeKB  float64 `json:"sizeKB"`
+	Fit     string  `json:"fit"`
+	Status  string  `json:"status"`
+	Options string  `json:"options"` // Opciones de montaje, p. ej. "ro,noatime"
 }
 
 type FileSystemEntry struct {
@@ -52,6 +55,7 @@ type FileSystemEntry struct {
 	Type     string  `json:"type"` // "folder" o "file"
 	Size     int32   `json:"size"`
 	Content  string  `json:"content"`
+	Encoding string  `json:"encoding,omitempty"` // "base64" si Content está codificado
 	Perm     string  `json:"perm"`
 	UID      int32   `json:"uid"`
 	GID      int32   `json:"gid"`
@@ -85,16 +89,32 @@ type PartitionsResponse struct {
 
 // Comandos que no requieren sesión activa
 var noSessionCommands = map[string]bool{
-	"mkdisk":  true,
-	"rmdisk":  true,
-	"fdisk":   true,
-	"mount":   true,
-	"unmount": true,
-	"mounted": true,
-	"mkfs":    true,
+	"mkdisk":      true,
+	"rmdisk":      true,
+	"fdisk":       true,
+	"mount":       true,
+	"unmount":     true,
+	"mounted":     true,
+	"automount":   true,
+	"tunefs":      true,
+	"mkfs":        true,
+	"clonedisk":   true,
+	"clonepart":   true,
+	"ptable":      true,
+	"compactdisk": true,
+	"resizedisk":  true,
 }
 
 func main() {
+	// Volver a montar las particiones que estaban montadas antes de reiniciar
+	messages, err := stores.RestoreMountTable()
+	if err != nil {
+		fmt.Printf("Error al restaurar la tabla de montaje: %v\n", err)
+	}
+	for _, message := range messages {
+		fmt.Printf("MOUNT: %s\n", message)
+	}
+
 	app := fiber.New()
 
 	app.Use(cors.New(cors.Config{}))
@@ -187,25 +207,25 @@ func main() {
 				return err
 			}
 			if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".mia") {
-				// Leer el MBR para obtener el tamaño del disco
-				var mbr structures.MBR
-				if err := mbr.Deserialize(path); err != nil {
+				// Leer la tabla de particiones (MBR o GPT) para obtener el tamaño del disco
+				table, err := structures.ReadPartitionTable(path)
+				if err != nil {
 					return err
 				}
 
 				// Tamaño del disco en MB
-				sizeMB := float64(mbr.Mbr_size) / (1024 * 1024)
+				sizeMB := float64(table.DiskSize()) / (1024 * 1024)
 
 				// Buscar particiones montadas asociadas a este disco
 				var mounted []string
 				var fit string
 				for id := range stores.MountedPartitions {
-					mbr, _, _, err := stores.GetMountedPartitionRep(id)
+					table, _, _, err := stores.GetMountedPartitionRep(id)
 					if err != nil {
 						continue
 					}
 					// Verificar particiones primarias
-					for _, p := range mbr.Mbr_partitions {
+					for _, p := range table.Partitions() {
 						if strings.Trim(string(p.Part_id[:]), "\x00") == id && stores.MountedPartitions[id] == path {
 							mounted = append(mounted, id)
 							if fit == "" {
@@ -229,14 +249,8 @@ func main() {
 					}
 					defer file.Close()
 
-					var extPartition *structures.Partition
-					for _, p := range mbr.Mbr_partitions {
-						if p.Part_type[0] == 'E' && p.Part_status[0] != 'N' {
-							extPartition = &p
-							break
-						}
-					}
-					if extPartition == nil {
+					extPartition := table.ExtendedPartition()
+					if extPartition == nil || extPartition.Part_status[0] == 'N' {
 						continue
 					}
 
@@ -316,13 +330,13 @@ func main() {
 				continue
 			}
 
-			mbr, _, _, err := stores.GetMountedPartitionRep(id)
+			table, _, _, err := stores.GetMountedPartitionRep(id)
 			if err != nil {
 				continue
 			}
 
 			// Buscar partición primaria
-			for _, p := range mbr.Mbr_partitions {
+			for _, p := range table.Partitions() {
 				if strings.Trim(string(p.Part_id[:]), "\x00") == id {
 					fit := string(p.Part_fit[0])
 					fitText := "Unknown"
@@ -341,12 +355,13 @@ func main() {
 					}
 
 					partitions = append(partitions, Partition{
-						ID:     id,
-						Path:   diskPath,
-						Name:   strings.Trim(string(p.Part_name[:]), "\x00"),
-						SizeKB: float64(p.Part_size) / 1024,
-						Fit:    fitText,
-						Status: statusText,
+						ID:      id,
+						Path:    diskPath,
+						Name:    strings.Trim(string(p.Part_name[:]), "\x00"),
+						SizeKB:  float64(p.Part_size) / 1024,
+						Fit:     fitText,
+						Status:  statusText,
+						Options: stores.GetMountOptions(id).String(),
 					})
 					break
 				}
@@ -359,14 +374,8 @@ func main() {
 			}
 			defer file.Close()
 
-			var extPartition *structures.Partition
-			for _, p := range mbr.Mbr_partitions {
-				if p.Part_type[0] == 'E' && p.Part_status[0] != 'N' {
-					extPartition = &p
-					break
-				}
-			}
-			if extPartition == nil {
+			extPartition := table.ExtendedPartition()
+			if extPartition == nil || extPartition.Part_status[0] == 'N' {
 				continue
 			}
 
@@ -400,12 +409,13 @@ func main() {
 					}
 
 					partitions = append(partitions, Partition{
-						ID:     id,
-						Path:   diskPath,
-						Name:   strings.Trim(string(currentEBR.Part_name[:]), "\x00"),
-						SizeKB: float64(currentEBR.Part_size) / 1024,
-						Fit:    fitText,
-						Status: statusText,
+						ID:      id,
+						Path:    diskPath,
+						Name:    strings.Trim(string(currentEBR.Part_name[:]), "\x00"),
+						SizeKB:  float64(currentEBR.Part_size) / 1024,
+						Fit:     fitText,
+						Status:  statusText,
+						Options: stores.GetMountOptions(id).String(),
 					})
 					break
 				}
@@ -424,6 +434,7 @@ func main() {
 	app.Get("/filesystem", func(c *fiber.Ctx) error {
 		partitionID := c.Query("id")
 		path := c.Query("path")
+		encoding := c.Query("encoding") // "base64" para codificar el contenido que no es texto
 
 		if partitionID == "" {
 			return c.Status(400).JSON(CommandResponse{
@@ -539,22 +550,19 @@ func main() {
 
 				entryType := "folder"
 				contentStr := ""
+				contentEncoding := ""
 				if entryInode.I_type[0] == '1' { // Archivo
 					entryType = "file"
-					// Leer el contenido del archivo
-					var contentBuilder strings.Builder
-					for _, fileBlockIndex := range entryInode.I_block {
-						if fileBlockIndex == -1 {
-							break
-						}
-						fileBlock := &structures.FileBlock{}
-						err = fileBlock.Deserialize(diskPath, int64(sb.S_block_start+fileBlockIndex*sb.S_block_size))
-						if err != nil {
-							continue
-						}
-						contentBuilder.WriteString(strings.Trim(string(fileBlock.B_content[:]), "\x00"))
+					// Leer el contenido del archivo; su longitud es I_size
+					fileContent, err := sb.ReadFileContent(diskPath, entryInode)
+					if err != nil {
+						continue // Saltar archivos con bloques corruptos
+					}
+					contentStr = string(fileContent)
+					if encoding == "base64" && !utils.IsText(fileContent) {
+						contentStr = base64.StdEncoding.EncodeToString(fileContent)
+						contentEncoding = "base64"
 					}
-					contentStr = contentBuilder.String()
 				}
 
 				// Construir la entrada
@@ -564,6 +572,7 @@ func main() {
 					Type:     entryType,
 					Size:     entryInode.I_size,
 					Content:  contentStr,
+					Encoding: contentEncoding,
 					Perm:     perm,
 					UID:      entryInode.I_uid,
 					GID:      entryInode.I_gid,
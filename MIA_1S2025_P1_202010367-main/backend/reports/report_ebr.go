@@ -8,16 +8,10 @@ import (
 	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
 )
 
-func ReportEBR(mbr *structures.MBR, diskPath string) (string, error) {
-	// Buscar la partición extendida en el MBR proporcionado
-	var extendedPartition *structures.Partition
-	for i := 0; i < 4; i++ {
-		if mbr.Mbr_partitions[i].Part_type[0] == 'E' && mbr.Mbr_partitions[i].Part_status[0] != 'N' {
-			extendedPartition = &mbr.Mbr_partitions[i]
-			break
-		}
-	}
-	if extendedPartition == nil {
+func ReportEBR(table *structures.PartitionTable, diskPath string) (string, error) {
+	// Buscar la partición extendida en la tabla proporcionada (GPT no tiene)
+	extendedPartition := table.ExtendedPartition()
+	if extendedPartition == nil || extendedPartition.Part_status[0] == 'N' {
 		return "", fmt.Errorf("no se encontró una partición extendida en %s", diskPath)
 	}
 
@@ -64,23 +64,14 @@ func ReportFile(sb *structures.SuperBlock, diskPath string, filePath string) (st
 		return "", fmt.Errorf("%s no es un archivo", filePath)
 	}
 
-	// Leer el contenido del archivo
-	var content strings.Builder
-	for _, blockNum := range fileInode.I_block[:12] {
-		if blockNum == -1 {
-			break
-		}
-		fileBlock := &structures.FileBlock{}
-		err = fileBlock.Deserialize(diskPath, int64(sb.S_block_start+blockNum*sb.S_block_size))
-		if err != nil {
-			return "", fmt.Errorf("error deserializando bloque de archivo %d: %v", blockNum, err)
-		}
-		blockContent := strings.TrimRight(string(fileBlock.B_content[:]), "\x00")
-		content.WriteString(blockContent)
+	// Leer el contenido del archivo; I_size indica su longitud, incluidos los bytes nulos
+	content, err := sb.ReadFileContent(diskPath, fileInode)
+	if err != nil {
+		return "", fmt.Errorf("error leyendo contenido del archivo %d: %v", currentInode, err)
 	}
 
 	// Si el archivo está vacío, devolver ceros
-	outputContent := content.String()
+	outputContent := string(content)
 	if fileInode.I_size == 0 {
 		outputContent = "0000000000000000000000000000000000000000000000000000000000000000" // 64 ceros
 	}
@@ -9,18 +9,24 @@ import (
 	//utils "github.com/MarceJua/MIA_1S2025_P1_202010367/utils"
 )
 
-// ReportMBR genera un reporte del MBR y lo guarda en la ruta especificada
-func ReportMBR(mbr *structures.MBR) (string, error) {
+// ReportMBR genera un reporte de la tabla de particiones del disco (MBR o GPT)
+func ReportMBR(table *structures.PartitionTable) (string, error) {
 	var sb strings.Builder
 	sb.WriteString("digraph G {\n")
 	sb.WriteString("  node [shape=plaintext]\n")
 	sb.WriteString("  tbl [label=<<TABLE BORDER=\"0\" CELLBORDER=\"1\" CELLSPACING=\"0\">\n")
-	sb.WriteString("    <TR><TD COLSPAN=\"2\">REPORTE MBR</TD></TR>\n")
-	sb.WriteString(fmt.Sprintf("    <TR><TD>mbr_tamano</TD><TD>%d</TD></TR>\n", mbr.Mbr_size))
-	sb.WriteString(fmt.Sprintf("    <TR><TD>mrb_fecha_creacion</TD><TD>%s</TD></TR>\n", time.Unix(int64(mbr.Mbr_creation_date), 0).Format(time.RFC3339)))
-	sb.WriteString(fmt.Sprintf("    <TR><TD>mbr_disk_signature</TD><TD>%d</TD></TR>\n", mbr.Mbr_disk_signature))
+	sb.WriteString(fmt.Sprintf("    <TR><TD COLSPAN=\"2\">REPORTE %s</TD></TR>\n", table.TypeName()))
+	sb.WriteString(fmt.Sprintf("    <TR><TD>mbr_tamano</TD><TD>%d</TD></TR>\n", table.DiskSize()))
+	sb.WriteString(fmt.Sprintf("    <TR><TD>mrb_fecha_creacion</TD><TD>%s</TD></TR>\n", time.Unix(int64(table.CreationDate()), 0).Format(time.RFC3339)))
+	sb.WriteString(fmt.Sprintf("    <TR><TD>mbr_disk_signature</TD><TD>%d</TD></TR>\n", table.DiskSignature()))
+	if gpt := table.GPT; gpt != nil {
+		sb.WriteString(fmt.Sprintf("    <TR><TD>gpt_disk_guid</TD><TD>%X</TD></TR>\n", gpt.Header.DiskGUID))
+		sb.WriteString(fmt.Sprintf("    <TR><TD>gpt_first_usable_lba</TD><TD>%d</TD></TR>\n", gpt.Header.FirstUsableLBA))
+		sb.WriteString(fmt.Sprintf("    <TR><TD>gpt_last_usable_lba</TD><TD>%d</TD></TR>\n", gpt.Header.LastUsableLBA))
+		sb.WriteString(fmt.Sprintf("    <TR><TD>gpt_backup_lba</TD><TD>%d</TD></TR>\n", gpt.Header.AlternateLBA))
+	}
 
-	for i, part := range mbr.Mbr_partitions {
+	for i, part := range table.Partitions() {
 		if part.Part_size <= 0 || part.Part_status[0] == 'N' {
 			continue // Omitir particiones no asignadas
 		}
@@ -5,10 +5,13 @@ import (
 	"strings"
 	"time"
 
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
 	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
 )
 
-func ReportSB(sb *structures.SuperBlock) (string, error) {
+// ReportSB genera el reporte del superbloque junto con el estado de la política de fsck;
+// lastCheck es la fecha de la última verificación de la partición
+func ReportSB(sb *structures.SuperBlock, lastCheck float32) (string, error) {
 	var sbBuilder strings.Builder
 
 	// Formatear tiempos correctamente
@@ -22,6 +25,11 @@ func ReportSB(sb *structures.SuperBlock) (string, error) {
 		umtime = time.Unix(int64(sb.S_umtime), 0).Format(time.RFC3339)
 	}
 
+	fsckState := "Limpio"
+	if reason := stores.FsckReason(sb, lastCheck); reason != "" {
+		fsckState = "Requiere fsck: " + reason
+	}
+
 	sbBuilder.WriteString("digraph G {\n")
 	sbBuilder.WriteString("  node [shape=plaintext]\n")
 	sbBuilder.WriteString("  tbl [label=<<TABLE BORDER=\"0\" CELLBORDER=\"1\" CELLSPACING=\"0\">\n")
@@ -34,6 +42,10 @@ func ReportSB(sb *structures.SuperBlock) (string, error) {
 	sbBuilder.WriteString(fmt.Sprintf("    <TR><TD>S_mtime</TD><TD>%s</TD></TR>\n", mtime))
 	sbBuilder.WriteString(fmt.Sprintf("    <TR><TD>S_umtime</TD><TD>%s</TD></TR>\n", umtime))
 	sbBuilder.WriteString(fmt.Sprintf("    <TR><TD>S_mnt_count</TD><TD>%d</TD></TR>\n", sb.S_mnt_count))
+	sbBuilder.WriteString(fmt.Sprintf("    <TR><TD>Máximo de montajes</TD><TD>%s</TD></TR>\n", stores.PolicyLimit(stores.MaxMountCount)))
+	sbBuilder.WriteString(fmt.Sprintf("    <TR><TD>Última verificación</TD><TD>%s</TD></TR>\n", time.Unix(int64(lastCheck), 0).Format(time.RFC3339)))
+	sbBuilder.WriteString(fmt.Sprintf("    <TR><TD>Intervalo de verificación</TD><TD>%s</TD></TR>\n", stores.PolicyLimit(stores.CheckInterval)+" días"))
+	sbBuilder.WriteString(fmt.Sprintf("    <TR><TD>Estado</TD><TD>%s</TD></TR>\n", fsckState))
 	sbBuilder.WriteString(fmt.Sprintf("    <TR><TD>S_magic</TD><TD>%d</TD></TR>\n", sb.S_magic))
 	sbBuilder.WriteString(fmt.Sprintf("    <TR><TD>S_inode_size</TD><TD>%d</TD></TR>\n", sb.S_inode_size))
 	sbBuilder.WriteString(fmt.Sprintf("    <TR><TD>S_block_size</TD><TD>%d</TD></TR>\n", sb.S_block_size))
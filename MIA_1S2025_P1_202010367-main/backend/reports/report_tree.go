@@ -3,12 +3,16 @@ package reports
 import (
 	"fmt"
 	"os"
+	"path"
 	"strings"
 
+	stores "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/stores"
 	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
 )
 
-func ReportTree(sb *structures.SuperBlock, diskPath string) (string, error) {
+// ReportTree genera el árbol de la partición id. Las carpetas donde se montó otra partición
+// con mount -at continúan con el árbol de esa partición
+func ReportTree(sb *structures.SuperBlock, diskPath string, id string) (string, error) {
 	file, err := os.Open(diskPath)
 	if err != nil {
 		return "", fmt.Errorf("error al abrir disco: %v", err)
@@ -19,16 +23,17 @@ func ReportTree(sb *structures.SuperBlock, diskPath string) (string, error) {
 	sbBuilder.WriteString("digraph Tree {\n")
 	sbBuilder.WriteString("  node [shape=box]\n")
 
-	inodeSize := int(sb.S_inode_size)
-	blockSize := int(sb.S_block_size)
-	processedInodes := make(map[int32]bool)
+	processedInodes := make(map[string]bool)
 
-	var buildTree func(inodoNum int32, parentPath string) error
-	buildTree = func(inodoNum int32, parentPath string) error {
-		if processedInodes[inodoNum] {
+	var buildTree func(id string, sb *structures.SuperBlock, diskPath string, inodoNum int32, parentPath, relPath string) error
+	buildTree = func(id string, sb *structures.SuperBlock, diskPath string, inodoNum int32, parentPath, relPath string) error {
+		inodeKey := fmt.Sprintf("%s:%d", id, inodoNum)
+		if processedInodes[inodeKey] {
 			return nil
 		}
-		processedInodes[inodoNum] = true
+		processedInodes[inodeKey] = true
+		inodeSize := int(sb.S_inode_size)
+		blockSize := int(sb.S_block_size)
 
 		inode := &structures.Inode{}
 		err := inode.Deserialize(diskPath, int64(sb.S_inode_start+(inodoNum*int32(inodeSize))))
@@ -62,7 +67,19 @@ func ReportTree(sb *structures.SuperBlock, diskPath string) (string, error) {
 						}
 						childName := fmt.Sprintf("\"%s\"", childPath)
 						sbBuilder.WriteString(fmt.Sprintf("  %s -> %s\n", currentPath, childName))
-						err = buildTree(childInodo, childPath)
+						childRel := path.Join(relPath, name)
+						if graftID, mounted := stores.GraftAt(id, childRel); mounted {
+							graftSb, _, graftDisk, err := stores.GetMountedPartitionSuperblock(graftID)
+							if err != nil {
+								return fmt.Errorf("error al obtener la partición montada %s: %v", graftID, err)
+							}
+							err = buildTree(graftID, graftSb, graftDisk, 0, childPath, "/")
+							if err != nil {
+								return err
+							}
+							continue
+						}
+						err = buildTree(id, sb, diskPath, childInodo, childPath, childRel)
 						if err != nil {
 							return err
 						}
@@ -88,7 +105,7 @@ func ReportTree(sb *structures.SuperBlock, diskPath string) (string, error) {
 		return nil
 	}
 
-	err = buildTree(0, "")
+	err = buildTree(id, sb, diskPath, 0, "", "/")
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,139 @@
+package stores
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+)
+
+// MaxMountCount es la cantidad de montajes tras la cual una partición requiere fsck (0 = sin límite)
+var MaxMountCount int32 = 20
+
+// CheckInterval es la cantidad de días tras la cual una partición requiere fsck (0 = sin límite)
+var CheckInterval int32 = 180
+
+// lastChecks guarda la fecha de la última verificación de cada partición, por disco y nombre.
+// Se guarda en la tabla de montaje porque el superbloque no tiene un campo para ella
+var lastChecks = make(map[string]float32)
+
+/*
+   Líneas de la tabla de montaje:
+   maxmount=20
+   checkinterval=180
+   lastcheck /home/discos/Disco1.mia Part1 1743465600
+*/
+
+// checkKey identifica una partición en lastChecks
+func checkKey(path, name string) string {
+	return path + " " + name
+}
+
+// LastCheck devuelve la fecha de la última verificación de la partición. Si no hay registro,
+// se toma la fecha actual como la primera
+func LastCheck(path, name string) float32 {
+	last, exists := lastChecks[checkKey(path, name)]
+	if !exists {
+		last = float32(time.Now().Unix())
+		lastChecks[checkKey(path, name)] = last
+	}
+	return last
+}
+
+// MarkChecked registra que la partición se acaba de verificar o formatear
+func MarkChecked(path, name string) error {
+	lastChecks[checkKey(path, name)] = float32(time.Now().Unix())
+	return SaveMountTable()
+}
+
+// FsckReason indica por qué la partición requiere fsck según la política de montaje, o
+// devuelve una cadena vacía si no lo requiere
+func FsckReason(sb *structures.SuperBlock, lastCheck float32) string {
+	if MaxMountCount > 0 && sb.S_mnt_count >= MaxMountCount {
+		return fmt.Sprintf("se montó %d veces (máximo %d)", sb.S_mnt_count, MaxMountCount)
+	}
+	if CheckInterval > 0 {
+		days := int32((float32(time.Now().Unix()) - lastCheck) / 86400)
+		if days >= CheckInterval {
+			return fmt.Sprintf("pasaron %d días desde la última verificación (máximo %d)", days, CheckInterval)
+		}
+	}
+	return ""
+}
+
+// PolicyLimit muestra un límite de la política de fsck; 0 significa sin límite
+func PolicyLimit(value int32) string {
+	if value == 0 {
+		return "∞"
+	}
+	return strconv.Itoa(int(value))
+}
+
+// RecordMount incrementa el contador de montajes y actualiza la fecha de montaje del
+// superbloque de la partición que empieza en partStart. Devuelve un aviso si la política de
+// montaje indica que la partición requiere fsck. Las particiones sin formato o montadas como
+// solo lectura no se modifican
+func RecordMount(path, name string, partStart int32, readOnly bool) (string, error) {
+	sb := &structures.SuperBlock{}
+	if err := sb.Deserialize(path, int64(partStart)); err != nil || sb.S_magic != 0xEF53 {
+		return "", nil
+	}
+	if !readOnly {
+		sb.S_mnt_count++
+		sb.S_mtime = float32(time.Now().Unix())
+		if err := sb.Serialize(path, int64(partStart)); err != nil {
+			return "", fmt.Errorf("error al actualizar el superbloque: %v", err)
+		}
+	}
+	if reason := FsckReason(sb, LastCheck(path, name)); reason != "" {
+		return fmt.Sprintf("la partición requiere fsck: %s", reason), nil
+	}
+	return "", nil
+}
+
+// parseFsckPolicy interpreta una línea de política de la tabla de montaje. Devuelve false
+// si la línea no es de política
+func parseFsckPolicy(fields []string) (bool, error) {
+	if len(fields) == 4 && fields[0] == "lastcheck" {
+		last, err := strconv.ParseFloat(fields[3], 32)
+		if err != nil {
+			return true, fmt.Errorf("fecha de verificación inválida: %s", fields[3])
+		}
+		lastChecks[checkKey(fields[1], fields[2])] = float32(last)
+		return true, nil
+	}
+	if len(fields) != 1 {
+		return false, nil
+	}
+	for key, target := range map[string]*int32{"maxmount=": &MaxMountCount, "checkinterval=": &CheckInterval} {
+		if value, ok := strings.CutPrefix(fields[0], key); ok {
+			value, err := strconv.Atoi(value)
+			if err != nil || value < 0 {
+				return true, fmt.Errorf("valor inválido: %s", fields[0])
+			}
+			*target = int32(value)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fsckPolicyLines devuelve las líneas de política para guardar en la tabla de montaje
+func fsckPolicyLines() []string {
+	lines := []string{
+		fmt.Sprintf("maxmount=%d", MaxMountCount),
+		fmt.Sprintf("checkinterval=%d", CheckInterval),
+	}
+	keys := make([]string, 0, len(lastChecks))
+	for key := range lastChecks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("lastcheck %s %d", key, int64(lastChecks[key])))
+	}
+	return lines
+}
@@ -0,0 +1,373 @@
+package stores
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	structures "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/structures"
+	utils "github.com/MarceJua/MIA_1S2025_P1_202010367/backend/utils"
+)
+
+// MountTablePath es el archivo donde se guarda la tabla de montaje (similar a /etc/fstab)
+var MountTablePath = "fstab.mia"
+
+// Automount indica si al iniciar el servidor se vuelven a montar las particiones de la tabla
+var Automount = true
+
+// mountedNames guarda el nombre de la partición de cada ID montado
+var mountedNames = make(map[string]string)
+
+// mountOptions guarda las opciones con que se montó cada ID
+var mountOptions = make(map[string]MountOptions)
+
+// MountOptions son las opciones de montaje de una partición
+type MountOptions struct {
+	ReadOnly bool // ro: se rechaza cualquier comando que modifique la partición
+	NoAtime  bool // noatime: las lecturas no actualizan I_atime
+	Sync     bool // sync: el disco se sincroniza (fsync) después de cada comando
+	Discard  bool // discard: los bloques liberados se descartan en el anfitrión (TRIM)
+}
+
+// ParseMountOptions interpreta una lista de opciones separadas por comas
+func ParseMountOptions(value string) (MountOptions, error) {
+	var options MountOptions
+	for _, option := range strings.Split(strings.ToLower(value), ",") {
+		switch strings.TrimSpace(option) {
+		case "ro":
+			options.ReadOnly = true
+		case "rw", "defaults":
+			options.ReadOnly = false
+		case "noatime":
+			options.NoAtime = true
+		case "atime":
+			options.NoAtime = false
+		case "sync":
+			options.Sync = true
+		case "async":
+			options.Sync = false
+		case "discard":
+			options.Discard = true
+		case "nodiscard":
+			options.Discard = false
+		default:
+			return options, fmt.Errorf("opción de montaje desconocida: %s", option)
+		}
+	}
+	return options, nil
+}
+
+// String devuelve las opciones en el formato de -options
+func (o MountOptions) String() string {
+	options := []string{"rw"}
+	if o.ReadOnly {
+		options[0] = "ro"
+	}
+	if o.NoAtime {
+		options = append(options, "noatime")
+	}
+	if o.Sync {
+		options = append(options, "sync")
+	}
+	if o.Discard {
+		options = append(options, "discard")
+	}
+	return strings.Join(options, ",")
+}
+
+// GetMountOptions devuelve las opciones de montaje de una partición
+func GetMountOptions(id string) MountOptions {
+	return mountOptions[id]
+}
+
+// SyncMountedDisks sincroniza con fsync los discos que tienen particiones montadas con la
+// opción sync
+func SyncMountedDisks() error {
+	synced := make(map[string]bool)
+	for id, path := range MountedPartitions {
+		if !mountOptions[id].Sync || synced[path] {
+			continue
+		}
+		synced[path] = true
+		file, err := os.OpenFile(path, os.O_RDWR, 0644)
+		if err != nil {
+			return fmt.Errorf("error al abrir %s para sincronizar: %v", path, err)
+		}
+		err = file.Sync()
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("error al sincronizar %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// MountEntry es una línea de la tabla de montaje
+type MountEntry struct {
+	ID      string       // ID asignado al montar
+	Path    string       // Ruta del disco
+	Name    string       // Nombre de la partición
+	Options MountOptions // Opciones de montaje
+}
+
+/*
+   Formato del archivo:
+   # id  disco  partición  opciones
+   automount=on
+   671A /home/discos/Disco1.mia Part1 rw
+   672A /home/discos/Disco1.mia Part2 ro,noatime
+   graft 671A /mnt/data 672A
+*/
+
+// AddMount registra una partición montada y guarda la tabla de montaje
+func AddMount(id, path, name string, options MountOptions) error {
+	MountedPartitions[id] = path
+	mountedNames[id] = name
+	mountOptions[id] = options
+	return SaveMountTable()
+}
+
+// RemoveMount quita una partición de la tabla de montaje y la guarda
+func RemoveMount(id string) error {
+	delete(MountedPartitions, id)
+	delete(mountedNames, id)
+	delete(mountOptions, id)
+	pruneGrafts()
+	return SaveMountTable()
+}
+
+// MountedName devuelve el nombre de la partición montada con el ID indicado
+func MountedName(id string) string {
+	return mountedNames[id]
+}
+
+// MountEntries devuelve las particiones montadas ordenadas por ID
+func MountEntries() []MountEntry {
+	entries := make([]MountEntry, 0, len(MountedPartitions))
+	for id, path := range MountedPartitions {
+		entries = append(entries, MountEntry{ID: id, Path: path, Name: mountedNames[id], Options: mountOptions[id]})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries
+}
+
+// SaveMountTable escribe la tabla de montaje con las particiones montadas actualmente
+func SaveMountTable() error {
+	var sb strings.Builder
+	sb.WriteString("# id  disco  partición  opciones\n")
+	if Automount {
+		sb.WriteString("automount=on\n")
+	} else {
+		sb.WriteString("automount=off\n")
+	}
+	for _, line := range fsckPolicyLines() {
+		sb.WriteString(line + "\n")
+	}
+	for _, entry := range MountEntries() {
+		sb.WriteString(fmt.Sprintf("%s %s %s %s\n", entry.ID, entry.Path, entry.Name, entry.Options))
+	}
+	for _, line := range graftLines() {
+		sb.WriteString(line + "\n")
+	}
+	if err := os.WriteFile(MountTablePath, []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("error al guardar la tabla de montaje %s: %v", MountTablePath, err)
+	}
+	return nil
+}
+
+// readMountTable lee la tabla de montaje. Si el archivo no existe devuelve una tabla vacía
+func readMountTable() ([]MountEntry, error) {
+	file, err := os.Open(MountTablePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []MountEntry
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "automount="); ok {
+			Automount = value != "off"
+			continue
+		}
+		fields := strings.Fields(line)
+		if policy, err := parseFsckPolicy(fields); policy {
+			if err != nil {
+				return nil, fmt.Errorf("línea %d inválida en %s: %v", lineNum, MountTablePath, err)
+			}
+			continue
+		}
+		if parseGraft(fields) {
+			continue
+		}
+		if len(fields) != 3 && len(fields) != 4 {
+			return nil, fmt.Errorf("línea %d inválida en %s: %s", lineNum, MountTablePath, line)
+		}
+		entry := MountEntry{ID: fields[0], Path: fields[1], Name: fields[2]}
+		if len(fields) == 4 {
+			if entry.Options, err = ParseMountOptions(fields[3]); err != nil {
+				return nil, fmt.Errorf("línea %d inválida en %s: %v", lineNum, MountTablePath, err)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// RestoreMountTable concilia la tabla de montaje con el estado de los discos al iniciar el
+// servidor. Con automount activo vuelve a montar cada partición de la tabla, usando su ID;
+// si no, marca como desmontadas en el disco las que quedaron montadas. Las entradas cuyo
+// disco o partición ya no existen se descartan. Devuelve un mensaje por cada entrada
+func RestoreMountTable() ([]string, error) {
+	entries, err := readMountTable()
+	if err != nil {
+		return nil, err
+	}
+
+	var messages []string
+	for _, entry := range entries {
+		if _, exists := MountedPartitions[entry.ID]; exists {
+			messages = append(messages, fmt.Sprintf("%s: ID repetido en la tabla de montaje, se omite", entry.ID))
+			continue
+		}
+		if _, err := os.Stat(entry.Path); err != nil {
+			messages = append(messages, fmt.Sprintf("%s: el disco %s ya no existe", entry.ID, entry.Path))
+			continue
+		}
+
+		var message string
+		var partStart int32
+		found, err := updatePartitionMount(entry.Path, entry.Name, func(start int32, status *[1]byte, id *[4]byte) bool {
+			partStart = start
+			diskID := strings.Trim(string(id[:]), "\x00")
+			if !Automount {
+				if status[0] != '1' {
+					message = fmt.Sprintf("%s: no se monta (automount desactivado)", entry.ID)
+					return false
+				}
+				status[0] = '0'
+				*id = [4]byte{}
+				message = fmt.Sprintf("%s: desmontada en %s (automount desactivado)", entry.ID, entry.Path)
+				return true
+			}
+			if status[0] == '1' && diskID != entry.ID {
+				message = fmt.Sprintf("%s: el disco indica el ID %s, se conserva la tabla de montaje", entry.ID, diskID)
+			} else {
+				message = fmt.Sprintf("%s: %s montada desde %s (%s)", entry.ID, entry.Name, entry.Path, entry.Options)
+			}
+			changed := status[0] != '1' || diskID != entry.ID
+			status[0] = '1'
+			*id = [4]byte{}
+			copy(id[:], entry.ID)
+			return changed
+		})
+		if err != nil {
+			messages = append(messages, fmt.Sprintf("%s: %v", entry.ID, err))
+			continue
+		}
+		if !found {
+			messages = append(messages, fmt.Sprintf("%s: la partición %s ya no existe en %s", entry.ID, entry.Name, entry.Path))
+			continue
+		}
+		messages = append(messages, message)
+
+		if Automount {
+			MountedPartitions[entry.ID] = entry.Path
+			mountedNames[entry.ID] = entry.Name
+			mountOptions[entry.ID] = entry.Options
+			restoreMountID(entry.ID, entry.Path)
+
+			// Volver a montar cuenta como un montaje más para la política de fsck
+			warning, err := RecordMount(entry.Path, entry.Name, partStart, entry.Options.ReadOnly)
+			if err != nil {
+				messages = append(messages, fmt.Sprintf("%s: %v", entry.ID, err))
+			} else if warning != "" {
+				messages = append(messages, fmt.Sprintf("%s: AVISO: %s", entry.ID, warning))
+			}
+		}
+	}
+
+	messages = append(messages, pruneGrafts()...)
+	return messages, SaveMountTable()
+}
+
+// parseMountID separa un ID de montaje (carnet, correlativo y letra del disco)
+func parseMountID(id string) (int, string, bool) {
+	rest := strings.TrimPrefix(id, Carnet)
+	end := len(rest)
+	for end > 0 && (rest[end-1] < '0' || rest[end-1] > '9') {
+		end--
+	}
+	correlative, err := strconv.Atoi(rest[:end])
+	if err != nil || end == len(rest) {
+		return 0, "", false
+	}
+	return correlative, rest[end:], true
+}
+
+// restoreMountID reserva la letra y el correlativo de un ID para que los siguientes
+// montajes del mismo disco no lo repitan
+func restoreMountID(id, path string) {
+	if correlative, letter, ok := parseMountID(id); ok {
+		utils.RestoreLetterAndPartitionCorrelative(path, letter, correlative)
+	}
+}
+
+// updatePartitionMount busca la partición name (primaria o lógica) en el disco y llama a
+// update con su inicio, su estado y su ID. Si update devuelve true, los cambios se escriben en el disco
+func updatePartitionMount(path, name string, update func(start int32, status *[1]byte, id *[4]byte) bool) (bool, error) {
+	table, err := structures.ReadPartitionTable(path)
+	if err != nil {
+		return false, fmt.Errorf("error al leer la tabla de particiones: %v", err)
+	}
+
+	if partition, _ := table.GetPartitionByName(name); partition != nil {
+		if !update(partition.Part_start, &partition.Part_status, &partition.Part_id) {
+			return true, nil
+		}
+		if partition.Part_status[0] == '1' {
+			if correlative, _, ok := parseMountID(strings.Trim(string(partition.Part_id[:]), "\x00")); ok {
+				partition.Part_correlative = int32(correlative)
+			}
+		}
+		return true, table.Write(path)
+	}
+
+	extPartition := table.ExtendedPartition()
+	if extPartition == nil || extPartition.Part_status[0] == 'N' {
+		return false, nil
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return false, fmt.Errorf("error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	var ebr structures.EBR
+	offset := int64(extPartition.Part_start)
+	for {
+		if err := ebr.Deserialize(file, offset); err != nil {
+			return false, fmt.Errorf("error al leer EBR: %v", err)
+		}
+		if ebr.Part_status[0] != 'N' && strings.Trim(string(ebr.Part_name[:]), "\x00") == name {
+			if !update(ebr.Part_start, &ebr.Part_status, &ebr.Part_id) {
+				return true, nil
+			}
+			return true, ebr.Serialize(file, offset)
+		}
+		if ebr.Part_next == -1 {
+			return false, nil
+		}
+		offset = int64(ebr.Part_next)
+	}
+}
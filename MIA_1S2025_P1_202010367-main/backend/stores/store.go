@@ -26,26 +26,28 @@ var CurrentSession Session
 // Declaración de variables globales
 var MountedPartitions = make(map[string]string)
 
-func GetMountedPartitionRep(id string) (*structures.MBR, *structures.SuperBlock, string, error) {
+// GetMountedPartitionRep devuelve la tabla de particiones (MBR o GPT) del disco, el superbloque
+// de la partición montada si está formateada y la ruta del disco
+func GetMountedPartitionRep(id string) (*structures.PartitionTable, *structures.SuperBlock, string, error) {
 	path, exists := MountedPartitions[id]
 	if !exists {
 		return nil, nil, "", errors.New("partición no montada")
 	}
 
-	var mbr structures.MBR
-	if err := mbr.Deserialize(path); err != nil {
-		return nil, nil, "", fmt.Errorf("error deserializando MBR: %v", err)
+	table, err := structures.ReadPartitionTable(path)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("error leyendo la tabla de particiones: %v", err)
 	}
 
 	// Buscar partición primaria
-	for _, p := range mbr.Mbr_partitions {
+	for _, p := range table.Partitions() {
 		if strings.Trim(string(p.Part_id[:]), "\x00") == id {
 			var sb structures.SuperBlock
 			err := sb.Deserialize(path, int64(p.Part_start))
 			if err != nil {
-				return &mbr, nil, path, nil // Devolver sin superbloque si falla (para mbr/disk)
+				return table, nil, path, nil // Devolver sin superbloque si falla (para mbr/disk)
 			}
-			return &mbr, &sb, path, nil
+			return table, &sb, path, nil
 		}
 	}
 
@@ -56,15 +58,9 @@ func GetMountedPartitionRep(id string) (*structures.MBR, *structures.SuperBlock,
 	}
 	defer file.Close()
 
-	var extPartition *structures.Partition
-	for _, p := range mbr.Mbr_partitions {
-		if p.Part_type[0] == 'E' && p.Part_status[0] != 'N' {
-			extPartition = &p
-			break
-		}
-	}
-	if extPartition == nil {
-		return &mbr, nil, path, nil // Sin extendida, devolvemos solo MBR
+	extPartition := table.ExtendedPartition()
+	if extPartition == nil || extPartition.Part_status[0] == 'N' {
+		return table, nil, path, nil // Sin extendida, devolvemos solo MBR
 	}
 
 	var currentEBR structures.EBR
@@ -83,9 +79,9 @@ func GetMountedPartitionRep(id string) (*structures.MBR, *structures.SuperBlock,
 			var sb structures.SuperBlock
 			err := sb.Deserialize(path, int64(currentEBR.Part_start))
 			if err != nil {
-				return &mbr, nil, path, nil // Sin superbloque si falla
+				return table, nil, path, nil // Sin superbloque si falla
 			}
-			return &mbr, &sb, path, nil
+			return table, &sb, path, nil
 		}
 		if currentEBR.Part_next == -1 {
 			break
@@ -93,7 +89,7 @@ func GetMountedPartitionRep(id string) (*structures.MBR, *structures.SuperBlock,
 		currentOffset = int64(currentEBR.Part_next)
 	}
 
-	return &mbr, nil, path, nil // Si no se encuentra, devolvemos solo MBR
+	return table, nil, path, nil // Si no se encuentra, devolvemos solo MBR
 }
 
 // GetMountedPartitionSuperblock obtiene el SuperBlock de la partición montada con el id especificado
@@ -102,12 +98,11 @@ func GetMountedPartitionSuperblock(id string) (*structures.SuperBlock, *structur
 	if path == "" {
 		return nil, nil, "", errors.New("la partición no está montada")
 	}
-	var mbr structures.MBR
-	err := mbr.Deserialize(path)
+	table, err := structures.ReadPartitionTable(path)
 	if err != nil {
 		return nil, nil, "", err
 	}
-	partition, err := mbr.GetPartitionByID(id)
+	partition, err := table.GetPartitionByID(id)
 	if partition == nil {
 		return nil, nil, "", err
 	}
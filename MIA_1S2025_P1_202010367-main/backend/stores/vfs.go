@@ -0,0 +1,192 @@
+package stores
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Graft es una partición montada en una carpeta de otra partición (mount -id -at)
+type Graft struct {
+	Host string // ID de la partición que contiene la carpeta
+	Path string // Ruta de la carpeta dentro de Host
+	ID   string // ID de la partición montada en la carpeta
+}
+
+// grafts guarda los puntos de montaje de cada partición, por ID de la partición anfitriona
+// y ruta de la carpeta
+var grafts = make(map[string]map[string]string)
+
+/*
+   Línea de la tabla de montaje:
+   graft 671A /mnt/data 672A
+*/
+
+// CleanPath normaliza una ruta absoluta de una partición
+func CleanPath(p string) string {
+	return path.Clean("/" + strings.Trim(p, "/"))
+}
+
+// AddGraft monta la partición id en la carpeta dir de la partición host y guarda la tabla
+func AddGraft(host, dir, id string) error {
+	dir = CleanPath(dir)
+	if grafts[host] == nil {
+		grafts[host] = make(map[string]string)
+	}
+	if current, exists := grafts[host][dir]; exists {
+		return fmt.Errorf("%s ya tiene montada la partición %s", dir, current)
+	}
+	grafts[host][dir] = id
+	return SaveMountTable()
+}
+
+// RemoveGraft desmonta la partición montada en la carpeta dir de host y devuelve su ID
+func RemoveGraft(host, dir string) (string, error) {
+	dir = CleanPath(dir)
+	id, exists := grafts[host][dir]
+	if !exists {
+		return "", fmt.Errorf("no hay ninguna partición montada en %s", dir)
+	}
+	delete(grafts[host], dir)
+	if len(grafts[host]) == 0 {
+		delete(grafts, host)
+	}
+	return id, SaveMountTable()
+}
+
+// GraftAt devuelve el ID de la partición montada en la carpeta dir de host
+func GraftAt(host, dir string) (string, bool) {
+	id, exists := grafts[host][CleanPath(dir)]
+	return id, exists
+}
+
+// GraftOf devuelve el punto de montaje de la partición id, si está montada en una carpeta
+func GraftOf(id string) (Graft, bool) {
+	for _, graft := range Grafts() {
+		if graft.ID == id {
+			return graft, true
+		}
+	}
+	return Graft{}, false
+}
+
+// GraftsUnder devuelve los puntos de montaje de host que están en dir o debajo de ella
+func GraftsUnder(host, dir string) []string {
+	dir = CleanPath(dir)
+	var points []string
+	for point := range grafts[host] {
+		if isUnder(point, dir) {
+			points = append(points, point)
+		}
+	}
+	sort.Strings(points)
+	return points
+}
+
+// GraftReaches indica si desde la partición from se llega a la partición to siguiendo los
+// puntos de montaje. Sirve para no crear ciclos en el espacio de nombres
+func GraftReaches(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, id := range grafts[from] {
+		if GraftReaches(id, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// Grafts devuelve todos los puntos de montaje ordenados por partición anfitriona y ruta
+func Grafts() []Graft {
+	var list []Graft
+	for host, points := range grafts {
+		for dir, id := range points {
+			list = append(list, Graft{Host: host, Path: dir, ID: id})
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Host != list[j].Host {
+			return list[i].Host < list[j].Host
+		}
+		return list[i].Path < list[j].Path
+	})
+	return list
+}
+
+// ResolvePath traduce una ruta del espacio de nombres de la partición id a la partición que
+// la contiene y la ruta dentro de ella, cruzando los puntos de montaje
+func ResolvePath(id, p string) (string, string) {
+	p = CleanPath(p)
+	for {
+		point := ""
+		for dir := range grafts[id] {
+			if isUnder(p, dir) && len(dir) > len(point) {
+				point = dir
+			}
+		}
+		if point == "" {
+			return id, p
+		}
+		id, p = grafts[id][point], CleanPath(strings.TrimPrefix(p, point))
+	}
+}
+
+// SplitEndpoint separa un origen o destino de copy y move con el formato ID:/ruta. Sin ID
+// la ruta es de la partición de la sesión
+func SplitEndpoint(spec string) (string, string) {
+	if id, p, found := strings.Cut(spec, ":"); found && !strings.Contains(id, "/") {
+		return id, p
+	}
+	return CurrentSession.ID, spec
+}
+
+// isUnder indica si la ruta p es dir o está debajo de ella
+func isUnder(p, dir string) bool {
+	return p == dir || dir == "/" || strings.HasPrefix(p, dir+"/")
+}
+
+// parseGraft interpreta una línea graft de la tabla de montaje. Devuelve false si la línea
+// no es un punto de montaje
+func parseGraft(fields []string) bool {
+	if len(fields) != 4 || fields[0] != "graft" {
+		return false
+	}
+	if CleanPath(fields[2]) == "/" {
+		return true // La raíz no puede ser un punto de montaje
+	}
+	if grafts[fields[1]] == nil {
+		grafts[fields[1]] = make(map[string]string)
+	}
+	grafts[fields[1]][CleanPath(fields[2])] = fields[3]
+	return true
+}
+
+// pruneGrafts descarta los puntos de montaje cuya partición anfitriona o montada ya no
+// está montada y devuelve un mensaje por cada uno
+func pruneGrafts() []string {
+	var messages []string
+	for _, graft := range Grafts() {
+		_, hostMounted := MountedPartitions[graft.Host]
+		_, idMounted := MountedPartitions[graft.ID]
+		if hostMounted && idMounted {
+			continue
+		}
+		delete(grafts[graft.Host], graft.Path)
+		if len(grafts[graft.Host]) == 0 {
+			delete(grafts, graft.Host)
+		}
+		messages = append(messages, fmt.Sprintf("%s: se descarta el punto de montaje %s:%s", graft.ID, graft.Host, graft.Path))
+	}
+	return messages
+}
+
+// graftLines devuelve las líneas de puntos de montaje para guardar en la tabla de montaje
+func graftLines() []string {
+	var lines []string
+	for _, graft := range Grafts() {
+		lines = append(lines, fmt.Sprintf("graft %s %s %s", graft.Host, graft.Path, graft.ID))
+	}
+	return lines
+}
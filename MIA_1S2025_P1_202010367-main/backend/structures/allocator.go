@@ -0,0 +1,247 @@
+package structures
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// allocator guarda en memoria los bitmaps de una partición para no leerlos completos del
+// disco en cada búsqueda. Se carga en la primera búsqueda de un comando y se mantiene al
+// día con cada escritura de un byte del bitmap (UpdateBitmapBlock, SetBlockRefs, ...).
+// Las escrituras del bitmap completo lo descartan, y ResetAllocators descarta todos al
+// terminar cada comando, porque mkdisk, fdisk o loss también cambian el disco por otras vías
+type allocator struct {
+	inodes    []byte // Bitmap de inodos
+	blocks    []byte // Bitmap de bloques
+	nextInode int32  // Inodo siguiente al último reservado; las búsquedas sin meta empiezan ahí
+	nextBlock int32  // Bloque siguiente al último reservado
+}
+
+// allocatorKey identifica una partición por el disco y el inicio de sus bitmaps
+type allocatorKey struct {
+	path  string
+	start int32
+}
+
+var allocators = make(map[allocatorKey]*allocator)
+
+// ResetAllocators descarta los bitmaps en memoria de todas las particiones
+func ResetAllocators() {
+	allocators = make(map[allocatorKey]*allocator)
+}
+
+// forgetAllocators descarta los bitmaps en memoria de las particiones del disco path,
+// después de escribir un bitmap completo. La opción discard revisa entonces el bitmap entero
+func forgetAllocators(path string) {
+	rewrittenBitmaps[path] = true
+	for key := range allocators {
+		if key.path == path {
+			delete(allocators, key)
+		}
+	}
+}
+
+// cachedAllocator devuelve los bitmaps en memoria de la partición, si ya se cargaron
+func (sb *SuperBlock) cachedAllocator(path string) *allocator {
+	a := allocators[allocatorKey{path, sb.S_bm_inode_start}]
+	if a == nil || len(a.inodes) != int(sb.S_inodes_count) || len(a.blocks) != int(sb.S_blocks_count) {
+		return nil
+	}
+	return a
+}
+
+// loadAllocator devuelve los bitmaps en memoria de la partición, leyéndolos si hace falta
+func (sb *SuperBlock) loadAllocator(path string) (*allocator, error) {
+	if a := sb.cachedAllocator(path); a != nil {
+		return a, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	a := &allocator{
+		inodes: make([]byte, sb.S_inodes_count),
+		blocks: make([]byte, sb.S_blocks_count),
+	}
+	if _, err := file.ReadAt(a.inodes, int64(sb.S_bm_inode_start)); err != nil {
+		return nil, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+	if _, err := file.ReadAt(a.blocks, int64(sb.S_bm_block_start)); err != nil {
+		return nil, fmt.Errorf("error al leer bitmap de bloques: %v", err)
+	}
+	allocators[allocatorKey{path, sb.S_bm_inode_start}] = a
+	return a, nil
+}
+
+// markInode actualiza el bitmap de inodos en memoria después de escribir el byte en disco
+func (sb *SuperBlock) markInode(path string, inodeIndex int32, value byte) {
+	if a := sb.cachedAllocator(path); a != nil {
+		a.inodes[inodeIndex] = value
+		if value != '0' {
+			a.nextInode = inodeIndex + 1
+		}
+	}
+}
+
+// markBlock actualiza el bitmap de bloques en memoria después de escribir el byte en disco
+func (sb *SuperBlock) markBlock(path string, blockIndex int32, value byte) {
+	if a := sb.cachedAllocator(path); a != nil {
+		if a.blocks[blockIndex] == '0' && value != '0' {
+			a.nextBlock = blockIndex + 1
+		}
+		a.blocks[blockIndex] = value
+	}
+}
+
+// findFree devuelve el primer índice libre del bitmap desde goal, volviendo al inicio al
+// llegar al final, o -1 si no hay ninguno
+func findFree(bitmap []byte, goal int32) int32 {
+	n := int32(len(bitmap))
+	if goal < 0 || goal >= n {
+		goal = 0
+	}
+	if i := bytes.IndexByte(bitmap[goal:], '0'); i != -1 {
+		return goal + int32(i)
+	}
+	if i := bytes.IndexByte(bitmap[:goal], '0'); i != -1 {
+		return int32(i)
+	}
+	return -1
+}
+
+// BlockGoal devuelve el bloque siguiente al último bloque directo del inodo, o -1 si no
+// tiene bloques. Es la meta para ubicar los bloques nuevos de una carpeta o de su contenido
+// junto a los que ya tiene, como hace ext2
+func BlockGoal(inode *Inode) int32 {
+	goal := int32(-1)
+	for _, blockNum := range inode.I_block[:12] {
+		if blockNum != -1 {
+			goal = max(goal, blockNum+1)
+		}
+	}
+	return goal
+}
+
+// SetBlockGoal hace que la próxima búsqueda de bloques sin meta empiece en goal. Se usa
+// antes de escribir el contenido de un archivo nuevo para dejarlo junto a su carpeta
+func (sb *SuperBlock) SetBlockGoal(path string, goal int32) error {
+	if goal < 0 {
+		return nil
+	}
+	a, err := sb.loadAllocator(path)
+	if err != nil {
+		return err
+	}
+	a.nextBlock = goal
+	return nil
+}
+
+// FindFreeInodeNear busca el primer inodo libre desde goal, normalmente el de la carpeta
+// padre. Con goal -1 empieza después del último inodo reservado en el comando
+func (sb *SuperBlock) FindFreeInodeNear(path string, goal int32) (int32, error) {
+	if sb.S_free_inodes_count <= 0 {
+		return -1, errors.New("no hay inodos libres disponibles")
+	}
+	a, err := sb.loadAllocator(path)
+	if err != nil {
+		return -1, err
+	}
+	if goal < 0 {
+		goal = a.nextInode
+	}
+	if i := findFree(a.inodes, goal); i != -1 {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no se encontraron inodos libres, pero S_free_inodes_count es %d", sb.S_free_inodes_count)
+}
+
+// FindFreeBlockNear busca el primer bloque libre desde goal. Con goal -1 empieza después
+// del último bloque reservado en el comando, así los bloques pedidos uno por uno quedan
+// seguidos mientras haya espacio
+func (sb *SuperBlock) FindFreeBlockNear(path string, goal int32) (int32, error) {
+	if sb.S_free_blocks_count <= 0 {
+		return -1, errors.New("no hay bloques libres disponibles")
+	}
+	a, err := sb.loadAllocator(path)
+	if err != nil {
+		return -1, err
+	}
+	if goal < 0 {
+		goal = a.nextBlock
+	}
+	if i := findFree(a.blocks, goal); i != -1 {
+		return i, nil
+	}
+	return -1, fmt.Errorf("no se encontraron bloques libres, pero S_free_blocks_count es %d", sb.S_free_blocks_count)
+}
+
+// AllocateBlocks reserva n bloques con una referencia cada uno y descuenta S_free_blocks_count.
+// Usa el primer tramo de n bloques libres seguidos desde goal (o desde el inicio del
+// bitmap si no hay uno después); si ninguno alcanza, toma los primeros libres desde goal.
+// Con goal -1 empieza después del último bloque reservado en el comando. Cada tramo del
+// bitmap se escribe de una vez
+func (sb *SuperBlock) AllocateBlocks(path string, n int32, goal int32) ([]int32, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if n > sb.S_free_blocks_count {
+		return nil, errors.New("no hay suficientes bloques libres disponibles")
+	}
+	a, err := sb.loadAllocator(path)
+	if err != nil {
+		return nil, err
+	}
+	if goal < 0 || goal >= sb.S_blocks_count {
+		goal = a.nextBlock % sb.S_blocks_count
+	}
+
+	start := findFreeRun(a.blocks[goal:], n)
+	if start != -1 {
+		start += goal
+	} else {
+		start = findFreeRun(a.blocks, n)
+	}
+	blocks := make([]int32, 0, n)
+	if start != -1 {
+		for i := start; i < start+n; i++ {
+			blocks = append(blocks, i)
+		}
+	} else {
+		for i := int32(0); i < sb.S_blocks_count && int32(len(blocks)) < n; i++ {
+			if blockNum := (goal + i) % sb.S_blocks_count; a.blocks[blockNum] == '0' {
+				blocks = append(blocks, blockNum)
+			}
+		}
+		if int32(len(blocks)) < n {
+			return nil, fmt.Errorf("no se encontraron %d bloques libres, pero S_free_blocks_count es %d", n, sb.S_free_blocks_count)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	for i := 0; i < len(blocks); {
+		end := i + 1
+		for end < len(blocks) && blocks[end] == blocks[end-1]+1 {
+			end++
+		}
+		run := a.blocks[blocks[i] : blocks[end-1]+1]
+		for j := range run {
+			run[j] = '1'
+		}
+		if _, err := file.WriteAt(run, int64(sb.S_bm_block_start)+int64(blocks[i])); err != nil {
+			forgetAllocators(path)
+			return nil, err
+		}
+		i = end
+	}
+	a.nextBlock = blocks[len(blocks)-1] + 1
+	sb.S_free_blocks_count -= n
+	return blocks, nil
+}
@@ -50,6 +50,7 @@ func (sb *SuperBlock) CreateBitMaps(file *os.File) error {
 		return err
 	}
 
+	forgetAllocators(file.Name())
 	return nil
 }
 
@@ -75,6 +76,7 @@ func (sb *SuperBlock) UpdateBitmapInode(path string, inodeIndex int32) error {
 		return err
 	}
 
+	sb.markInode(path, inodeIndex, '1')
 	return nil
 }
 
@@ -100,5 +102,121 @@ func (sb *SuperBlock) UpdateBitmapBlock(path string, blockIndex int32) error {
 		return err
 	}
 
+	sb.markBlock(path, blockIndex, '1')
 	return nil
 }
+
+// Cada byte del bitmap de bloques guarda '0' + número de referencias del bloque:
+// '0' es un bloque libre, '1' un bloque con un único dueño y '2', '3', ... un
+// bloque de archivo compartido (por ejemplo, con un snapshot).
+const MaxBlockRefs = 255 - '0'
+
+// GetBlockRefs devuelve el número de referencias de un bloque según el bitmap
+func (sb *SuperBlock) GetBlockRefs(path string, blockIndex int32) (int32, error) {
+	if blockIndex < 0 || blockIndex >= sb.S_blocks_count {
+		return 0, fmt.Errorf("índice de bloque fuera de rango: %d", blockIndex)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var b [1]byte
+	_, err = file.ReadAt(b[:], int64(sb.S_bm_block_start)+int64(blockIndex))
+	if err != nil {
+		return 0, err
+	}
+
+	// Un bitmap sobrescrito con ceros binarios (loss) se interpreta como libre
+	if b[0] < '0' {
+		return 0, nil
+	}
+	return int32(b[0] - '0'), nil
+}
+
+// SetBlockRefs escribe el número de referencias de un bloque en el bitmap
+func (sb *SuperBlock) SetBlockRefs(path string, blockIndex int32, refs int32) error {
+	if blockIndex < 0 || blockIndex >= sb.S_blocks_count {
+		return fmt.Errorf("índice de bloque fuera de rango: %d", blockIndex)
+	}
+	if refs < 0 || refs > MaxBlockRefs {
+		return fmt.Errorf("contador de referencias inválido para el bloque %d: %d", blockIndex, refs)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteAt([]byte{byte('0' + refs)}, int64(sb.S_bm_block_start)+int64(blockIndex)); err != nil {
+		return err
+	}
+	sb.markBlock(path, blockIndex, byte('0'+refs))
+	if refs == 0 {
+		sb.recordRelease(path, blockIndex)
+	}
+	return nil
+}
+
+// IncBlockRefs añade una referencia a un bloque que ya está ocupado
+func (sb *SuperBlock) IncBlockRefs(path string, blockIndex int32) error {
+	refs, err := sb.GetBlockRefs(path, blockIndex)
+	if err != nil {
+		return err
+	}
+	if refs == 0 {
+		return fmt.Errorf("el bloque %d está libre, no se puede compartir", blockIndex)
+	}
+	return sb.SetBlockRefs(path, blockIndex, refs+1)
+}
+
+// ReleaseBlock quita una referencia a un bloque y lo libera cuando ya no le quedan dueños.
+// Devuelve true si el bloque quedó libre.
+func (sb *SuperBlock) ReleaseBlock(path string, blockIndex int32) (bool, error) {
+	refs, err := sb.GetBlockRefs(path, blockIndex)
+	if err != nil {
+		return false, err
+	}
+	if refs == 0 {
+		return false, nil // Ya estaba libre
+	}
+	if err := sb.SetBlockRefs(path, blockIndex, refs-1); err != nil {
+		return false, err
+	}
+	if refs == 1 {
+		sb.S_free_blocks_count++
+		return true, nil
+	}
+	return false, nil
+}
+
+// FreeBitmapInode marca un inodo como libre en el bitmap
+func (sb *SuperBlock) FreeBitmapInode(path string, inodeIndex int32) error {
+	if inodeIndex < 0 || inodeIndex >= sb.S_inodes_count {
+		return fmt.Errorf("índice de inodo fuera de rango: %d", inodeIndex)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.WriteAt([]byte{'0'}, int64(sb.S_bm_inode_start)+int64(inodeIndex)); err != nil {
+		return err
+	}
+	sb.markInode(path, inodeIndex, '0')
+	return nil
+}
+
+// ReadInodeBitmap lee el bitmap de inodos completo
+func (sb *SuperBlock) ReadInodeBitmap(path string) ([]byte, error) {
+	bitmap := make([]byte, sb.S_inodes_count)
+	if err := readAt(path, bitmap, int64(sb.S_bm_inode_start)); err != nil {
+		return nil, err
+	}
+	return bitmap, nil
+}
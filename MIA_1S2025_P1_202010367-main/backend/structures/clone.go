@@ -0,0 +1,123 @@
+package structures
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// CloneTo copia el sistema de archivos de sb (en srcPath) a la partición descrita por dest
+// (en destPath), como partclone: solo se copian los inodos y bloques marcados como usados
+// en los bitmaps. dest trae la geometría de la partición destino (inicio de cada área y
+// cantidad de inodos y bloques), que puede empezar en otra posición y ser más grande; los
+// números de inodo y de bloque se conservan, así que los apuntadores no cambian.
+// Devuelve la cantidad de inodos y bloques copiados
+func (sb *SuperBlock) CloneTo(srcPath string, dest *SuperBlock, destPath string) (int32, int32, error) {
+	if dest.S_inodes_count < sb.S_inodes_count || dest.S_blocks_count < sb.S_blocks_count {
+		return 0, 0, fmt.Errorf("el destino admite %d inodos y %d bloques, el origen usa %d y %d",
+			dest.S_inodes_count, dest.S_blocks_count, sb.S_inodes_count, sb.S_blocks_count)
+	}
+	if dest.S_filesystem_type != sb.S_filesystem_type || dest.journalSize() != sb.journalSize() {
+		return 0, 0, errors.New("el destino debe tener el mismo tipo de sistema de archivos que el origen")
+	}
+
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer src.Close()
+	out, err := os.OpenFile(destPath, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer out.Close()
+
+	// El Journal se copia completo
+	journal := make([]byte, sb.journalSize())
+	if _, err := src.ReadAt(journal, int64(sb.S_journal_start)); err != nil && len(journal) > 0 {
+		return 0, 0, fmt.Errorf("error al leer Journal: %v", err)
+	}
+	if _, err := out.WriteAt(journal, int64(dest.S_journal_start)); err != nil {
+		return 0, 0, fmt.Errorf("error al escribir Journal: %v", err)
+	}
+
+	// Bitmaps: los del origen y, si el destino es más grande, el resto libre
+	inodeBitmap := make([]byte, dest.S_inodes_count)
+	blockBitmap := make([]byte, dest.S_blocks_count)
+	for _, bitmap := range [][]byte{inodeBitmap, blockBitmap} {
+		for i := range bitmap {
+			bitmap[i] = '0'
+		}
+	}
+	if _, err := src.ReadAt(inodeBitmap[:sb.S_inodes_count], int64(sb.S_bm_inode_start)); err != nil {
+		return 0, 0, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+	if _, err := src.ReadAt(blockBitmap[:sb.S_blocks_count], int64(sb.S_bm_block_start)); err != nil {
+		return 0, 0, fmt.Errorf("error al leer bitmap de bloques: %v", err)
+	}
+	if _, err := out.WriteAt(inodeBitmap, int64(dest.S_bm_inode_start)); err != nil {
+		return 0, 0, fmt.Errorf("error al escribir bitmap de inodos: %v", err)
+	}
+	if _, err := out.WriteAt(blockBitmap, int64(dest.S_bm_block_start)); err != nil {
+		return 0, 0, fmt.Errorf("error al escribir bitmap de bloques: %v", err)
+	}
+	forgetAllocators(destPath)
+
+	// Tabla de inodos: los libres quedan en cero para no dejar restos del contenido
+	// anterior de la partición destino
+	inodes := int32(0)
+	table := make([]byte, int64(dest.S_inodes_count)*int64(dest.S_inode_size))
+	for i := int32(0); i < sb.S_inodes_count; i++ {
+		if inodeBitmap[i] == '0' {
+			continue
+		}
+		start := int64(i) * int64(sb.S_inode_size)
+		if _, err := src.ReadAt(table[start:start+int64(sb.S_inode_size)], int64(sb.S_inode_start)+start); err != nil {
+			return 0, 0, fmt.Errorf("error al leer inodo %d: %v", i, err)
+		}
+		inodes++
+	}
+	if _, err := out.WriteAt(table, int64(dest.S_inode_start)); err != nil {
+		return 0, 0, fmt.Errorf("error al escribir la tabla de inodos: %v", err)
+	}
+
+	// Bloques usados, copiando juntos los que están seguidos
+	blocks := int32(0)
+	blockSize := int64(sb.S_block_size)
+	for i := int32(0); i < sb.S_blocks_count; {
+		if blockBitmap[i] == '0' {
+			i++
+			continue
+		}
+		end := i
+		for end < sb.S_blocks_count && blockBitmap[end] != '0' {
+			end++
+		}
+		buffer := make([]byte, int64(end-i)*blockSize)
+		if _, err := src.ReadAt(buffer, int64(sb.S_block_start)+int64(i)*blockSize); err != nil {
+			return 0, 0, fmt.Errorf("error al leer bloques %d-%d: %v", i, end-1, err)
+		}
+		if _, err := out.WriteAt(buffer, int64(dest.S_block_start)+int64(i)*blockSize); err != nil {
+			return 0, 0, fmt.Errorf("error al escribir bloques %d-%d: %v", i, end-1, err)
+		}
+		blocks += end - i
+		i = end
+	}
+
+	// Superbloque: contadores del origen más el espacio adicional del destino
+	cloned := *sb
+	cloned.S_inodes_count = dest.S_inodes_count
+	cloned.S_blocks_count = dest.S_blocks_count
+	cloned.S_free_inodes_count = sb.S_free_inodes_count + dest.S_inodes_count - sb.S_inodes_count
+	cloned.S_free_blocks_count = sb.S_free_blocks_count + dest.S_blocks_count - sb.S_blocks_count
+	cloned.S_bm_inode_start = dest.S_bm_inode_start
+	cloned.S_bm_block_start = dest.S_bm_block_start
+	cloned.S_inode_start = dest.S_inode_start
+	cloned.S_block_start = dest.S_block_start
+	cloned.S_journal_start = dest.S_journal_start
+	*dest = cloned
+	if err := dest.Serialize(destPath, dest.Offset()); err != nil {
+		return 0, 0, fmt.Errorf("error al escribir el superbloque: %v", err)
+	}
+	return inodes, blocks, nil
+}
@@ -0,0 +1,257 @@
+package structures
+
+import "fmt"
+
+// DefragResult resume una desfragmentación
+type DefragResult struct {
+	Inodes int32   // Inodos revisados
+	Moved  int32   // Inodos que se movieron a bloques seguidos
+	Blocks int32   // Bloques movidos
+	Shared int32   // Inodos fragmentados que no se movieron por tener bloques compartidos
+	Full   int32   // Inodos fragmentados que no se movieron por falta de bloques libres seguidos
+	Before float64 // Fragmentación antes de empezar
+	After  float64 // Fragmentación al terminar
+}
+
+// inodeLayout devuelve los bloques de un inodo en el orden en que deben quedar en el disco:
+// los de datos en orden y después los de apuntadores que los indexan, el mismo orden en que
+// WriteFileContent los reserva. También devuelve cuáles de ellos son bloques de apuntadores
+func (sb *SuperBlock) inodeLayout(path string, inode *Inode) ([]int32, map[int32]bool, error) {
+	for _, blockNum := range inode.I_block[:12] {
+		if blockNum < -1 || blockNum >= sb.S_blocks_count {
+			return nil, nil, fmt.Errorf("apuntador directo inválido: %d", blockNum)
+		}
+	}
+	pointerSet := make(map[int32]bool)
+	if inode.I_type[0] != '1' {
+		var layout []int32
+		for _, blockNum := range inode.I_block[:12] {
+			if blockNum != -1 {
+				layout = append(layout, blockNum)
+			}
+		}
+		return layout, pointerSet, nil
+	}
+
+	data, pointers, err := sb.FileBlocks(path, inode)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, blockNum := range pointers {
+		pointerSet[blockNum] = true
+	}
+	return append(data, pointers...), pointerSet, nil
+}
+
+// Fragmentation devuelve el porcentaje de saltos entre bloques consecutivos de cada inodo:
+// 0 cuando todos ocupan bloques seguidos y 100 cuando ningún bloque está a continuación
+// del anterior
+func Fragmentation(layouts [][]int32) float64 {
+	gaps, pairs := 0, 0
+	for _, layout := range layouts {
+		for i := 1; i < len(layout); i++ {
+			pairs++
+			if layout[i] != layout[i-1]+1 {
+				gaps++
+			}
+		}
+	}
+	if pairs == 0 {
+		return 0
+	}
+	return 100 * float64(gaps) / float64(pairs)
+}
+
+// Defrag mueve los bloques de cada inodo de inodeNums a un tramo de bloques libres seguidos,
+// el primero del bitmap que alcance. Solo se mueven los inodos cuyos bloques tienen una
+// única referencia: un bloque compartido con otro archivo o con un snapshot debe seguir
+// en su lugar. Los contadores del superbloque no cambian, cada inodo movido reserva tantos
+// bloques como libera
+func (sb *SuperBlock) Defrag(path string, inodeNums []int32) (*DefragResult, error) {
+	// El bitmap se lleva en memoria aparte y se escribe por tramos
+	defer forgetAllocators(path)
+	bitmap := make([]byte, sb.S_blocks_count)
+	if err := readAt(path, bitmap, int64(sb.S_bm_block_start)); err != nil {
+		return nil, fmt.Errorf("error al leer bitmap de bloques: %v", err)
+	}
+
+	result := &DefragResult{Inodes: int32(len(inodeNums))}
+	inodes := make([]Inode, len(inodeNums))
+	layouts := make([][]int32, len(inodeNums))
+	pointers := make([]map[int32]bool, len(inodeNums))
+	for i, inodeNum := range inodeNums {
+		if err := inodes[i].Deserialize(path, int64(sb.S_inode_start)+int64(inodeNum)*int64(sb.S_inode_size)); err != nil {
+			return nil, fmt.Errorf("error al leer inodo %d: %v", inodeNum, err)
+		}
+		layout, pointerSet, err := sb.inodeLayout(path, &inodes[i])
+		if err != nil {
+			return nil, fmt.Errorf("error al leer bloques del inodo %d: %v", inodeNum, err)
+		}
+		layouts[i], pointers[i] = layout, pointerSet
+	}
+	result.Before = Fragmentation(layouts)
+
+	for i, inodeNum := range inodeNums {
+		layout := layouts[i]
+		if Fragmentation([][]int32{layout}) == 0 {
+			continue
+		}
+		shared := false
+		for _, blockNum := range layout {
+			shared = shared || bitmap[blockNum] != '1'
+		}
+		if shared {
+			result.Shared++
+			continue
+		}
+		start := findFreeRun(bitmap, int32(len(layout)))
+		if start == -1 {
+			result.Full++
+			continue
+		}
+
+		if err := sb.relocateInode(path, inodeNum, &inodes[i], layout, pointers[i], start, bitmap); err != nil {
+			return result, fmt.Errorf("error al mover los bloques del inodo %d: %v", inodeNum, err)
+		}
+		for j := range layout {
+			layout[j] = start + int32(j)
+		}
+		result.Moved++
+		result.Blocks += int32(len(layout))
+	}
+	result.After = Fragmentation(layouts)
+	return result, nil
+}
+
+// findFreeRun devuelve el primer bloque de un tramo de n bloques libres seguidos, o -1
+func findFreeRun(bitmap []byte, n int32) int32 {
+	run := int32(0)
+	for i := range bitmap {
+		if bitmap[i] != '0' {
+			run = 0
+			continue
+		}
+		if run++; run == n {
+			return int32(i) - n + 1
+		}
+	}
+	return -1
+}
+
+// relocateInode copia los bloques de layout a partir de start y apunta el inodo a ellos.
+// El orden de escritura deja el sistema de archivos consistente si se interrumpe: primero
+// se reservan y llenan los bloques nuevos, luego se escribe el inodo y al final se liberan
+// los anteriores, así que una interrupción solo deja bloques reservados que nadie usa
+func (sb *SuperBlock) relocateInode(path string, inodeNum int32, inode *Inode, layout []int32, pointers map[int32]bool, start int32, bitmap []byte) error {
+	n := int32(len(layout))
+	target := make(map[int32]int32, n)
+	for i, blockNum := range layout {
+		target[blockNum] = start + int32(i)
+	}
+
+	run := bitmap[start : start+n]
+	for i := range run {
+		run[i] = '1'
+	}
+	if err := writeAt(path, run, int64(sb.S_bm_block_start)+int64(start)); err != nil {
+		return fmt.Errorf("error al reservar bloques %d-%d: %v", start, start+n-1, err)
+	}
+
+	buffer := make([]byte, sb.S_block_size)
+	for _, blockNum := range layout {
+		from := int64(sb.S_block_start) + int64(blockNum)*int64(sb.S_block_size)
+		to := int64(sb.S_block_start) + int64(target[blockNum])*int64(sb.S_block_size)
+		if !pointers[blockNum] {
+			if err := readAt(path, buffer, from); err != nil {
+				return fmt.Errorf("error al leer bloque %d: %v", blockNum, err)
+			}
+			if err := writeAt(path, buffer, to); err != nil {
+				return fmt.Errorf("error al escribir bloque %d: %v", target[blockNum], err)
+			}
+			continue
+		}
+
+		// Los bloques de apuntadores se copian con los números nuevos de sus hijos
+		pb := &PointerBlock{}
+		if err := pb.Deserialize(path, from); err != nil {
+			return fmt.Errorf("error al leer bloque de apuntadores %d: %v", blockNum, err)
+		}
+		for j, child := range pb.P_pointers {
+			if child != -1 {
+				pb.P_pointers[j] = target[child]
+			}
+		}
+		if err := pb.Serialize(path, to); err != nil {
+			return fmt.Errorf("error al escribir bloque de apuntadores %d: %v", target[blockNum], err)
+		}
+	}
+
+	for i, blockNum := range inode.I_block {
+		if blockNum != -1 {
+			inode.I_block[i] = target[blockNum]
+		}
+	}
+	if err := inode.Serialize(path, int64(sb.S_inode_start)+int64(inodeNum)*int64(sb.S_inode_size)); err != nil {
+		return fmt.Errorf("error al actualizar inodo %d: %v", inodeNum, err)
+	}
+
+	for _, blockNum := range layout {
+		bitmap[blockNum] = '0'
+		if err := writeAt(path, bitmap[blockNum:blockNum+1], int64(sb.S_bm_block_start)+int64(blockNum)); err != nil {
+			return fmt.Errorf("error al liberar bloque %d: %v", blockNum, err)
+		}
+	}
+	return nil
+}
+
+// ReclaimLeakedBlocks libera los bloques con una sola referencia en el bitmap que ningún
+// inodo usa ni retiene un snapshot (held). Son los que deja una desfragmentación
+// interrumpida entre la reserva de los bloques nuevos y la liberación de los anteriores;
+// como Defrag no cambia los contadores del superbloque, tampoco se actualizan aquí.
+// Devuelve la cantidad de bloques liberados
+func (sb *SuperBlock) ReclaimLeakedBlocks(path string, held []int32) (int32, error) {
+	inodeBitmap, err := sb.ReadInodeBitmap(path)
+	if err != nil {
+		return 0, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+	inodes, err := sb.ReadInodeTable(path)
+	if err != nil {
+		return 0, fmt.Errorf("error al leer tabla de inodos: %v", err)
+	}
+
+	used := make([]bool, sb.S_blocks_count)
+	for _, blockNum := range held {
+		if blockNum >= 0 && blockNum < sb.S_blocks_count {
+			used[blockNum] = true
+		}
+	}
+	for i := range inodes {
+		if inodeBitmap[i] != '1' {
+			continue
+		}
+		layout, _, err := sb.inodeLayout(path, &inodes[i])
+		if err != nil {
+			return 0, fmt.Errorf("error al leer bloques del inodo %d: %v", i, err)
+		}
+		for _, blockNum := range layout {
+			used[blockNum] = true
+		}
+	}
+
+	bitmap := make([]byte, sb.S_blocks_count)
+	if err := readAt(path, bitmap, int64(sb.S_bm_block_start)); err != nil {
+		return 0, fmt.Errorf("error al leer bitmap de bloques: %v", err)
+	}
+	forgetAllocators(path)
+	reclaimed := int32(0)
+	for i := range bitmap {
+		if bitmap[i] == '1' && !used[i] {
+			bitmap[i] = '0'
+			reclaimed++
+		}
+	}
+	if reclaimed == 0 {
+		return 0, nil
+	}
+	return reclaimed, writeAt(path, bitmap, int64(sb.S_bm_block_start))
+}
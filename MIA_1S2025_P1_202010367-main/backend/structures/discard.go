@@ -0,0 +1,137 @@
+package structures
+
+import (
+	"os"
+	"slices"
+)
+
+// zeroRange escribe ceros en los bytes [offset, offset+length) del archivo
+func zeroRange(file *os.File, offset, length int64) error {
+	buffer := make([]byte, min(length, relocateChunk))
+	for length > 0 {
+		n := min(length, int64(len(buffer)))
+		if _, err := file.WriteAt(buffer[:n], offset); err != nil {
+			return err
+		}
+		offset += n
+		length -= n
+	}
+	return nil
+}
+
+// PunchHole descarta los bytes [offset, offset+length) del disco, como TRIM: en Linux el
+// rango se convierte en un hueco del archivo y deja de ocupar espacio en el anfitrión
+func PunchHole(path string, offset, length int64) error {
+	if length <= 0 {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return punchHole(file, offset, length)
+}
+
+// DiscardFreeBlocks descarta los bloques libres del bitmap, juntando los que están
+// seguidos en un solo rango. Devuelve la cantidad de bloques descartados. Su contenido se
+// pierde, así que undelete ya no puede recuperar los archivos que los usaban
+func (sb *SuperBlock) DiscardFreeBlocks(path string) (int32, error) {
+	bitmap := make([]byte, sb.S_blocks_count)
+	if err := readAt(path, bitmap, int64(sb.S_bm_block_start)); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	discarded := int32(0)
+	blockSize := int64(sb.S_block_size)
+	for i := int32(0); i < sb.S_blocks_count; {
+		if bitmap[i] != '0' {
+			i++
+			continue
+		}
+		end := i
+		for end < sb.S_blocks_count && bitmap[end] == '0' {
+			end++
+		}
+		if err := punchHole(file, int64(sb.S_block_start)+int64(i)*blockSize, int64(end-i)*blockSize); err != nil {
+			return discarded, err
+		}
+		discarded += end - i
+		i = end
+	}
+	return discarded, nil
+}
+
+// releasedBlocks guarda, por partición, los bloques que quedaron libres durante el comando
+// actual, para que la opción de montaje discard descarte solo esos. rewrittenBitmaps marca
+// los discos cuyo bitmap de bloques se escribió completo (mkfs, defrag, restore, ...): en
+// esas particiones no se sabe qué se liberó y se descartan todos los bloques libres
+var (
+	releasedBlocks   = make(map[allocatorKey][]int32)
+	rewrittenBitmaps = make(map[string]bool)
+)
+
+// recordRelease anota un bloque que quedó libre
+func (sb *SuperBlock) recordRelease(path string, blockIndex int32) {
+	key := allocatorKey{path, sb.S_bm_inode_start}
+	releasedBlocks[key] = append(releasedBlocks[key], blockIndex)
+}
+
+// ResetReleasedBlocks olvida los bloques liberados; se llama al terminar cada comando
+func ResetReleasedBlocks() {
+	releasedBlocks = make(map[allocatorKey][]int32)
+	rewrittenBitmaps = make(map[string]bool)
+}
+
+// DiscardReleasedBlocks descarta los bloques que se liberaron durante el comando y siguen
+// libres, juntando los que están seguidos en un solo rango. Devuelve la cantidad de bloques
+// descartados
+func (sb *SuperBlock) DiscardReleasedBlocks(path string) (int32, error) {
+	if rewrittenBitmaps[path] {
+		return sb.DiscardFreeBlocks(path)
+	}
+	blocks := releasedBlocks[allocatorKey{path, sb.S_bm_inode_start}]
+	if len(blocks) == 0 {
+		return 0, nil
+	}
+	slices.Sort(blocks)
+	blocks = slices.Compact(blocks)
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	// Un bloque liberado pudo volver a reservarse en el mismo comando; el bitmap se lee solo
+	// en el tramo que cubren los bloques liberados
+	bitmap := make([]byte, blocks[len(blocks)-1]-blocks[0]+1)
+	if _, err := file.ReadAt(bitmap, int64(sb.S_bm_block_start)+int64(blocks[0])); err != nil {
+		return 0, err
+	}
+
+	discarded := int32(0)
+	blockSize := int64(sb.S_block_size)
+	for i := 0; i < len(blocks); {
+		if bitmap[blocks[i]-blocks[0]] != '0' {
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(blocks) && blocks[end] == blocks[end-1]+1 && bitmap[blocks[end]-blocks[0]] == '0' {
+			end++
+		}
+		count := int32(end - i)
+		if err := punchHole(file, int64(sb.S_block_start)+int64(blocks[i])*blockSize, int64(count)*blockSize); err != nil {
+			return discarded, err
+		}
+		discarded += count
+		i = end
+	}
+	return discarded, nil
+}
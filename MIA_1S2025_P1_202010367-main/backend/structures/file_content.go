@@ -0,0 +1,296 @@
+package structures
+
+import (
+	"errors"
+	"fmt"
+)
+
+const pointersPerBlock = 16 // Apuntadores en un PointerBlock
+
+// MaxFileBlocks es la cantidad de bloques de datos que puede indexar un inodo: 12 directos,
+// 16 con el indirecto simple (I_block[12]), 256 con el doble (I_block[13]) y 4096 con el
+// triple (I_block[14])
+const MaxFileBlocks = 12 + pointersPerBlock + pointersPerBlock*pointersPerBlock + pointersPerBlock*pointersPerBlock*pointersPerBlock
+
+// PointerBlocksFor devuelve cuántos bloques de apuntadores necesita un archivo de blocks
+// bloques de datos
+func PointerBlocksFor(blocks int) int32 {
+	total := 0
+	rest := blocks - 12
+	span := 1
+	for level := 1; level <= 3 && rest > 0; level++ {
+		span *= pointersPerBlock
+		used := min(rest, span)
+		// Un bloque por cada grupo de per bloques de datos en cada nivel del árbol
+		for per := pointersPerBlock; per <= span; per *= pointersPerBlock {
+			total += (used + per - 1) / per
+		}
+		rest -= used
+	}
+	return int32(total)
+}
+
+// FileBlocks devuelve los bloques de datos de un archivo en orden y los bloques de
+// apuntadores que los indexan
+func (sb *SuperBlock) FileBlocks(path string, inode *Inode) ([]int32, []int32, error) {
+	var data, pointers []int32
+	for _, blockNum := range inode.I_block[:12] {
+		if blockNum != -1 {
+			data = append(data, blockNum)
+		}
+	}
+
+	var walk func(blockNum int32, level int) error
+	walk = func(blockNum int32, level int) error {
+		if blockNum < 0 || blockNum >= sb.S_blocks_count {
+			return fmt.Errorf("apuntador indirecto inválido: %d", blockNum)
+		}
+		pointers = append(pointers, blockNum)
+		pb := &PointerBlock{}
+		if err := pb.Deserialize(path, int64(sb.S_block_start)+int64(blockNum)*int64(sb.S_block_size)); err != nil {
+			return fmt.Errorf("error al leer bloque de apuntadores %d: %v", blockNum, err)
+		}
+		for _, child := range pb.P_pointers {
+			if child == -1 {
+				continue
+			}
+			if level > 1 {
+				if err := walk(child, level-1); err != nil {
+					return err
+				}
+				continue
+			}
+			if child < 0 || child >= sb.S_blocks_count {
+				return fmt.Errorf("el bloque de apuntadores %d apunta a un bloque inválido: %d", blockNum, child)
+			}
+			data = append(data, child)
+		}
+		return nil
+	}
+
+	for level := 1; level <= 3; level++ {
+		if blockNum := inode.I_block[11+level]; blockNum != -1 {
+			if err := walk(blockNum, level); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return data, pointers, nil
+}
+
+// ReadFileContent devuelve el contenido de un archivo, incluidos sus bloques indirectos,
+// limitado a I_size
+func (sb *SuperBlock) ReadFileContent(path string, inode *Inode) ([]byte, error) {
+	data, _, err := sb.FileBlocks(path, inode)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, 0, len(data)*int(sb.S_block_size))
+	for _, blockNum := range data {
+		fileBlock := &FileBlock{}
+		if err := fileBlock.Deserialize(path, int64(sb.S_block_start)+int64(blockNum)*int64(sb.S_block_size)); err != nil {
+			return nil, fmt.Errorf("error al leer bloque %d: %v", blockNum, err)
+		}
+		content = append(content, fileBlock.B_content[:]...)
+	}
+	if size := int(inode.I_size); size >= 0 && size < len(content) {
+		content = content[:size]
+	}
+	return content, nil
+}
+
+// SetFileBlocks apunta el inodo a la lista de bloques de datos. Los bloques de apuntadores
+// anteriores se sueltan y se crean otros nuevos, por lo que nunca se modifica en su lugar
+// uno compartido con un snapshot. No serializa el inodo.
+func (sb *SuperBlock) SetFileBlocks(path string, inode *Inode, data []int32) error {
+	if len(data) > MaxFileBlocks {
+		return fmt.Errorf("un archivo no puede tener más de %d bloques", MaxFileBlocks)
+	}
+	_, oldPointers, err := sb.FileBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+
+	released := int32(0)
+	for _, blockNum := range oldPointers {
+		refs, err := sb.GetBlockRefs(path, blockNum)
+		if err != nil {
+			return err
+		}
+		if refs == 1 {
+			released++
+		}
+	}
+	if PointerBlocksFor(len(data)) > sb.S_free_blocks_count+released {
+		return errors.New("no hay suficientes bloques libres para los apuntadores indirectos")
+	}
+	for _, blockNum := range oldPointers {
+		if _, err := sb.ReleaseBlock(path, blockNum); err != nil {
+			return fmt.Errorf("error al liberar bloque de apuntadores %d: %v", blockNum, err)
+		}
+	}
+
+	for i := range inode.I_block {
+		inode.I_block[i] = -1
+	}
+	rest := data[copy(inode.I_block[:12], data):]
+	span := 1
+	for level := 1; level <= 3 && len(rest) > 0; level++ {
+		span *= pointersPerBlock
+		take := min(len(rest), span)
+		root, err := sb.writePointerTree(path, rest[:take], span)
+		if err != nil {
+			return err
+		}
+		inode.I_block[11+level] = root
+		rest = rest[take:]
+	}
+	return nil
+}
+
+// writePointerTree guarda los bloques de datos en un árbol de apuntadores que abarca span
+// bloques y devuelve el bloque raíz
+func (sb *SuperBlock) writePointerTree(path string, data []int32, span int) (int32, error) {
+	blockNum, err := sb.FindFreeBlock(path)
+	if err != nil {
+		return -1, err
+	}
+	if err := sb.SetBlockRefs(path, blockNum, 1); err != nil {
+		return -1, err
+	}
+	sb.S_free_blocks_count--
+
+	pb := NewPointerBlock()
+	per := span / pointersPerBlock
+	for i := 0; len(data) > 0; i++ {
+		take := min(len(data), per)
+		if per == 1 {
+			pb.P_pointers[i] = data[0]
+		} else {
+			child, err := sb.writePointerTree(path, data[:take], per)
+			if err != nil {
+				return -1, err
+			}
+			pb.P_pointers[i] = child
+		}
+		data = data[take:]
+	}
+	return blockNum, pb.Serialize(path, int64(sb.S_block_start)+int64(blockNum)*int64(sb.S_block_size))
+}
+
+// ReleaseFileBlocks suelta la referencia del archivo a sus bloques de datos y de
+// apuntadores. No modifica el inodo.
+func (sb *SuperBlock) ReleaseFileBlocks(path string, inode *Inode) error {
+	data, pointers, err := sb.FileBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+	for _, blockNum := range append(data, pointers...) {
+		if _, err := sb.ReleaseBlock(path, blockNum); err != nil {
+			return fmt.Errorf("error al liberar bloque %d: %v", blockNum, err)
+		}
+	}
+	return nil
+}
+
+// WriteFileContent reemplaza el contenido de un archivo. Los bloques propios se reescriben
+// en su lugar, los compartidos se copian antes de escribir (copy-on-write) y los que
+// sobran se liberan. Más allá de los 12 bloques directos se usan los apuntadores
+// indirectos. No serializa el inodo ni el superbloque; eso queda a cargo de quien llama.
+func (sb *SuperBlock) WriteFileContent(path string, inode *Inode, content []byte) error {
+	blockSize := int(sb.S_block_size)
+	needed := (len(content) + blockSize - 1) / blockSize
+	if needed > MaxFileBlocks {
+		return fmt.Errorf("el contenido excede el máximo de %d bytes", MaxFileBlocks*blockSize)
+	}
+	old, oldPointers, err := sb.FileBlocks(path, inode)
+	if err != nil {
+		return err
+	}
+
+	// Contar los bloques nuevos que harán falta y los que quedarán libres antes de
+	// modificar el disco
+	refs := make([]int32, len(old))
+	for i, blockNum := range old {
+		if refs[i], err = sb.GetBlockRefs(path, blockNum); err != nil {
+			return err
+		}
+	}
+	required := PointerBlocksFor(needed)
+	released := int32(0)
+	for i := range old {
+		if i >= needed && refs[i] == 1 {
+			released++
+		}
+	}
+	for i := 0; i < needed; i++ {
+		if i >= len(old) || refs[i] != 1 {
+			required++
+		}
+	}
+	for _, blockNum := range oldPointers {
+		pointerRefs, err := sb.GetBlockRefs(path, blockNum)
+		if err != nil {
+			return err
+		}
+		if pointerRefs == 1 {
+			released++
+		}
+	}
+	if required > sb.S_free_blocks_count+released {
+		return errors.New("no hay suficientes bloques libres para almacenar el contenido")
+	}
+
+	// Liberar primero los bloques que ya no se usan para poder reutilizarlos
+	for i := needed; i < len(old); i++ {
+		if _, err := sb.ReleaseBlock(path, old[i]); err != nil {
+			return fmt.Errorf("error al liberar bloque %d: %v", old[i], err)
+		}
+	}
+
+	// Los bloques nuevos se reservan juntos, en un solo tramo si hay espacio, a
+	// continuación del último bloque propio que conserva el archivo
+	fresh, goal := int32(0), int32(-1)
+	for i := 0; i < needed; i++ {
+		if i < len(old) && refs[i] == 1 {
+			goal = old[i] + 1
+		} else {
+			fresh++
+		}
+	}
+	freshBlocks, err := sb.AllocateBlocks(path, fresh, goal)
+	if err != nil {
+		return err
+	}
+
+	blocks := make([]int32, needed)
+	for i := 0; i < needed; i++ {
+		// Bloque nuevo o compartido: se usa uno de los bloques reservados
+		if i < len(old) && refs[i] == 1 {
+			blocks[i] = old[i]
+		} else {
+			// El contador se vuelve a leer: dedup puede repetir un bloque dentro del
+			// mismo archivo y cada aparición suelta una referencia
+			if i < len(old) && refs[i] > 1 {
+				if _, err := sb.ReleaseBlock(path, old[i]); err != nil {
+					return fmt.Errorf("error al liberar bloque %d: %v", old[i], err)
+				}
+			}
+			blocks[i], freshBlocks = freshBlocks[0], freshBlocks[1:]
+		}
+
+		fileBlock := &FileBlock{}
+		end := min((i+1)*blockSize, len(content))
+		copy(fileBlock.B_content[:], content[i*blockSize:end])
+		err := fileBlock.Serialize(path, int64(sb.S_block_start)+int64(blocks[i])*int64(sb.S_block_size))
+		if err != nil {
+			return fmt.Errorf("error al escribir bloque %d: %v", blocks[i], err)
+		}
+	}
+
+	if err := sb.SetFileBlocks(path, inode, blocks); err != nil {
+		return err
+	}
+	inode.I_size = int32(len(content))
+	return nil
+}
@@ -0,0 +1,398 @@
+package structures
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	GPTEntryCount   = 128 // Entradas de la tabla de particiones GPT
+	GPTEntrySize    = 128 // Tamaño de cada entrada en bytes
+	gptEntrySectors = GPTEntryCount * GPTEntrySize / SectorSize
+	gptSideSectors  = 4 // Sectores reservados para la tabla de datos del proyecto
+	gptProtectiveID = 0xEE
+)
+
+var gptSignature = [8]byte{'E', 'F', 'I', ' ', 'P', 'A', 'R', 'T'}
+var gptSideMagic = [4]byte{'M', 'I', 'A', 'G'}
+
+// Tipo "Linux filesystem data" (0FC63DAF-8483-4772-8E79-3D69D8477DE4) en el orden de bytes
+// de GPT: los tres primeros campos en little-endian
+var gptLinuxDataType = [16]byte{
+	0xAF, 0x3D, 0xC6, 0x0F, 0x83, 0x84, 0x72, 0x47,
+	0x8E, 0x79, 0x3D, 0x69, 0xD8, 0x47, 0x7D, 0xE4,
+}
+
+// GPTHeader es la cabecera GPT (LBA 1 y copia de respaldo en el último LBA)
+type GPTHeader struct {
+	Signature                [8]byte
+	Revision                 uint32
+	HeaderSize               uint32
+	HeaderCRC32              uint32
+	Reserved                 uint32
+	MyLBA                    uint64
+	AlternateLBA             uint64
+	FirstUsableLBA           uint64
+	LastUsableLBA            uint64
+	DiskGUID                 [16]byte
+	PartitionEntryLBA        uint64
+	NumberOfPartitionEntries uint32
+	SizeOfPartitionEntry     uint32
+	PartitionEntryArrayCRC32 uint32
+	// Total: 92 bytes
+}
+
+// GPTEntry es una entrada de la tabla de particiones GPT
+type GPTEntry struct {
+	TypeGUID   [16]byte
+	UniqueGUID [16]byte
+	FirstLBA   uint64
+	LastLBA    uint64
+	Attributes uint64
+	Name       [36]uint16 // Nombre en UTF-16LE
+	// Total: 128 bytes
+}
+
+// GPTSideEntry guarda los campos del proyecto que GPT no tiene para una entrada
+type GPTSideEntry struct {
+	Part_status      [1]byte
+	Part_fit         [1]byte
+	Part_size        int32 // Tamaño exacto en bytes; el rango de LBAs se redondea al sector
+	Part_correlative int32
+	Part_id          [4]byte
+}
+
+// GPTSideTable se guarda en los sectores que hay entre la tabla de entradas y el primer
+// LBA utilizable, un espacio que las herramientas GPT no tocan
+type GPTSideTable struct {
+	Magic        [4]byte
+	CreationDate float32
+	Signature    int32
+	Fit          [1]byte
+	Entries      [GPTEntryCount]GPTSideEntry
+}
+
+// GPT representa la tabla de particiones GPT de un disco
+type GPT struct {
+	Header   GPTHeader
+	Entries  [GPTEntryCount]GPTEntry
+	Side     GPTSideTable
+	Parts    [GPTEntryCount]Partition // Entradas con los campos del proyecto
+	DiskSize int32
+}
+
+// EmptyPartition devuelve una partición sin asignar
+func EmptyPartition() Partition {
+	return Partition{
+		Part_status:      [1]byte{'N'},
+		Part_type:        [1]byte{'N'},
+		Part_fit:         [1]byte{'N'},
+		Part_start:       -1,
+		Part_size:        -1,
+		Part_name:        [16]byte{'N'},
+		Part_correlative: -1,
+		Part_id:          [4]byte{'N'},
+	}
+}
+
+// isEmptyPartition indica si una partición no está asignada
+func isEmptyPartition(p *Partition) bool {
+	return p.Part_status[0] == 'N' || p.Part_start < 0 || p.Part_size <= 0
+}
+
+// newGUID genera un GUID aleatorio (versión 4)
+func newGUID() [16]byte {
+	var guid [16]byte
+	rand.Read(guid[:])
+	guid[7] = (guid[7] & 0x0F) | 0x40
+	guid[8] = (guid[8] & 0x3F) | 0x80
+	return guid
+}
+
+// NewGPT crea una tabla GPT vacía para un disco de diskSize bytes
+func NewGPT(diskSize int32, creationDate float32, signature int32, fit byte) (*GPT, error) {
+	lastLBA := uint64(diskSize)/SectorSize - 1
+	firstUsable := uint64(2 + gptEntrySectors + gptSideSectors)
+	if lastLBA < firstUsable+uint64(gptEntrySectors)+1 {
+		return nil, errors.New("el disco es demasiado pequeño para una tabla GPT")
+	}
+
+	gpt := &GPT{DiskSize: diskSize}
+	gpt.Header = GPTHeader{
+		Signature:                gptSignature,
+		Revision:                 0x00010000,
+		HeaderSize:               92,
+		MyLBA:                    1,
+		AlternateLBA:             lastLBA,
+		FirstUsableLBA:           firstUsable,
+		LastUsableLBA:            lastLBA - gptEntrySectors - 1,
+		DiskGUID:                 newGUID(),
+		PartitionEntryLBA:        2,
+		NumberOfPartitionEntries: GPTEntryCount,
+		SizeOfPartitionEntry:     GPTEntrySize,
+	}
+	gpt.Side = GPTSideTable{
+		Magic:        gptSideMagic,
+		CreationDate: creationDate,
+		Signature:    signature,
+		Fit:          [1]byte{fit},
+	}
+	for i := range gpt.Parts {
+		gpt.Parts[i] = EmptyPartition()
+	}
+	return gpt, nil
+}
+
+// IsGPTDisk indica si el disco tiene un MBR protector y una cabecera GPT
+func IsGPTDisk(path string) bool {
+	var mbr StandardMBR
+	if err := mbr.Deserialize(path); err != nil || !mbr.IsValid() {
+		return false
+	}
+	if mbr.Partitions[0].Type != gptProtectiveID {
+		return false
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	signature := make([]byte, 8)
+	if _, err := file.ReadAt(signature, SectorSize); err != nil {
+		return false
+	}
+	return bytes.Equal(signature, gptSignature[:])
+}
+
+// UsableStart devuelve el primer byte donde puede empezar una partición
+func (gpt *GPT) UsableStart() int64 {
+	return int64(gpt.Header.FirstUsableLBA) * SectorSize
+}
+
+// UsableEnd devuelve el byte siguiente al último utilizable por las particiones
+func (gpt *GPT) UsableEnd() int64 {
+	return int64(gpt.Header.LastUsableLBA+1) * SectorSize
+}
+
+// headerCRC calcula el CRC32 de una cabecera con el campo HeaderCRC32 en cero
+func headerCRC(header GPTHeader) uint32 {
+	header.HeaderCRC32 = 0
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, &header)
+	return crc32.ChecksumIEEE(buffer.Bytes())
+}
+
+// entriesCRC calcula el CRC32 de la tabla de entradas
+func entriesCRC(entries *[GPTEntryCount]GPTEntry) uint32 {
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, entries)
+	return crc32.ChecksumIEEE(buffer.Bytes())
+}
+
+// syncEntries traduce las particiones del proyecto a entradas GPT y a la tabla auxiliar
+func (gpt *GPT) syncEntries() {
+	for i := range gpt.Parts {
+		p := &gpt.Parts[i]
+		if isEmptyPartition(p) {
+			gpt.Entries[i] = GPTEntry{}
+			gpt.Side.Entries[i] = GPTSideEntry{}
+			continue
+		}
+
+		entry := &gpt.Entries[i]
+		if entry.UniqueGUID == [16]byte{} {
+			entry.UniqueGUID = newGUID()
+		}
+		entry.TypeGUID = gptLinuxDataType
+		entry.FirstLBA = uint64(p.Part_start) / SectorSize
+		entry.LastLBA = (uint64(p.Part_start)+uint64(p.Part_size)+SectorSize-1)/SectorSize - 1
+		entry.Name = [36]uint16{}
+		copy(entry.Name[:], utf16.Encode([]rune(strings.TrimRight(string(p.Part_name[:]), "\x00"))))
+
+		gpt.Side.Entries[i] = GPTSideEntry{
+			Part_status:      p.Part_status,
+			Part_fit:         p.Part_fit,
+			Part_size:        p.Part_size,
+			Part_correlative: p.Part_correlative,
+			Part_id:          p.Part_id,
+		}
+	}
+}
+
+// Serialize escribe el MBR protector, la cabecera y la tabla GPT con sus copias de
+// respaldo al final del disco
+func (gpt *GPT) Serialize(path string) error {
+	gpt.syncEntries()
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	lastLBA := gpt.Header.AlternateLBA
+	if gpt.Header.MyLBA != 1 {
+		lastLBA = gpt.Header.MyLBA
+	}
+	sectors := uint32(0xFFFFFFFF)
+	if lastLBA < 0xFFFFFFFF {
+		sectors = uint32(lastLBA)
+	}
+	protective := StandardMBR{Signature: [2]byte{0x55, 0xAA}}
+	protective.Partitions[0] = StandardPartitionEntry{
+		CHSFirst: [3]byte{0x00, 0x02, 0x00},
+		Type:     gptProtectiveID,
+		CHSLast:  [3]byte{0xFF, 0xFF, 0xFF},
+		LBAStart: 1,
+		Sectors:  sectors,
+	}
+
+	arrayCRC := entriesCRC(&gpt.Entries)
+	primary := gpt.Header
+	primary.MyLBA = 1
+	primary.AlternateLBA = lastLBA
+	primary.PartitionEntryLBA = 2
+	primary.PartitionEntryArrayCRC32 = arrayCRC
+	primary.HeaderCRC32 = headerCRC(primary)
+
+	backup := primary
+	backup.MyLBA = lastLBA
+	backup.AlternateLBA = 1
+	backup.PartitionEntryLBA = lastLBA - gptEntrySectors
+	backup.HeaderCRC32 = headerCRC(backup)
+	gpt.Header = primary
+
+	writes := []struct {
+		lba  uint64
+		data any
+	}{
+		{0, &protective},
+		{1, &primary},
+		{primary.PartitionEntryLBA, &gpt.Entries},
+		{primary.PartitionEntryLBA + gptEntrySectors, &gpt.Side},
+		{backup.PartitionEntryLBA, &gpt.Entries},
+		{lastLBA, &backup},
+	}
+	for _, w := range writes {
+		if _, err := file.Seek(int64(w.lba)*SectorSize, 0); err != nil {
+			return err
+		}
+		if err := binary.Write(file, binary.LittleEndian, w.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readHeader lee y valida la cabecera GPT del LBA indicado junto con su tabla de entradas
+func readHeader(file *os.File, lba uint64) (GPTHeader, [GPTEntryCount]GPTEntry, error) {
+	var header GPTHeader
+	var entries [GPTEntryCount]GPTEntry
+
+	buffer := make([]byte, binary.Size(header))
+	if _, err := file.ReadAt(buffer, int64(lba)*SectorSize); err != nil {
+		return header, entries, err
+	}
+	if err := binary.Read(bytes.NewReader(buffer), binary.LittleEndian, &header); err != nil {
+		return header, entries, err
+	}
+	if header.Signature != gptSignature {
+		return header, entries, fmt.Errorf("firma GPT inválida en el LBA %d", lba)
+	}
+	if headerCRC(header) != header.HeaderCRC32 {
+		return header, entries, fmt.Errorf("CRC32 de la cabecera GPT inválido en el LBA %d", lba)
+	}
+	if header.NumberOfPartitionEntries != GPTEntryCount || header.SizeOfPartitionEntry != GPTEntrySize {
+		return header, entries, errors.New("formato de la tabla de entradas GPT no soportado")
+	}
+
+	buffer = make([]byte, binary.Size(entries))
+	if _, err := file.ReadAt(buffer, int64(header.PartitionEntryLBA)*SectorSize); err != nil {
+		return header, entries, err
+	}
+	if err := binary.Read(bytes.NewReader(buffer), binary.LittleEndian, &entries); err != nil {
+		return header, entries, err
+	}
+	if entriesCRC(&entries) != header.PartitionEntryArrayCRC32 {
+		return header, entries, fmt.Errorf("CRC32 de la tabla de entradas GPT inválido en el LBA %d", lba)
+	}
+	return header, entries, nil
+}
+
+// Deserialize lee la tabla GPT del disco. Si la cabecera principal está dañada se usa la
+// copia de respaldo
+func (gpt *GPT) Deserialize(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	gpt.DiskSize = int32(info.Size())
+
+	header, entries, err := readHeader(file, 1)
+	if err != nil {
+		lastLBA := uint64(info.Size())/SectorSize - 1
+		var backupErr error
+		header, entries, backupErr = readHeader(file, lastLBA)
+		if backupErr != nil {
+			return fmt.Errorf("cabecera GPT principal dañada (%v) y copia de respaldo inválida (%v)", err, backupErr)
+		}
+		// Trabajar con la copia como si fuera la principal; se reescribe al guardar
+		header.AlternateLBA = header.MyLBA
+		header.MyLBA = 1
+		header.PartitionEntryLBA = 2
+	}
+	gpt.Header = header
+	gpt.Entries = entries
+
+	buffer := make([]byte, binary.Size(gpt.Side))
+	if _, err := file.ReadAt(buffer, int64(2+gptEntrySectors)*SectorSize); err != nil {
+		return err
+	}
+	if err := binary.Read(bytes.NewReader(buffer), binary.LittleEndian, &gpt.Side); err != nil {
+		return err
+	}
+	hasSide := gpt.Side.Magic == gptSideMagic
+	if !hasSide {
+		gpt.Side = GPTSideTable{Magic: gptSideMagic, Fit: [1]byte{'F'}}
+	}
+
+	for i, entry := range gpt.Entries {
+		if entry.TypeGUID == [16]byte{} {
+			gpt.Parts[i] = EmptyPartition()
+			continue
+		}
+		side := gpt.Side.Entries[i]
+		p := Partition{
+			Part_status:      [1]byte{'0'},
+			Part_type:        [1]byte{'P'},
+			Part_fit:         [1]byte{'F'},
+			Part_start:       int32(entry.FirstLBA * SectorSize),
+			Part_size:        int32((entry.LastLBA - entry.FirstLBA + 1) * SectorSize),
+			Part_correlative: -1,
+		}
+		name := utf16.Decode(entry.Name[:])
+		copy(p.Part_name[:], strings.TrimRight(string(name), "\x00"))
+		if hasSide && side.Part_status[0] != 0 {
+			p.Part_status = side.Part_status
+			p.Part_fit = side.Part_fit
+			p.Part_size = side.Part_size
+			p.Part_correlative = side.Part_correlative
+			p.Part_id = side.Part_id
+		}
+		gpt.Parts[i] = p
+	}
+	return nil
+}
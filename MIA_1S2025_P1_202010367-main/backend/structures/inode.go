@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -97,3 +98,49 @@ func (inode *Inode) Print() {
 	fmt.Printf("I_type: %s\n", string(inode.I_type[:]))
 	fmt.Printf("I_perm: %s\n", string(inode.I_perm[:]))
 }
+
+// Un inodo liberado conserva su contenido como lápida para poder recuperarlo: la fecha
+// de eliminación reemplaza a la del último acceso y, si el inodo no usa los tres
+// apuntadores indirectos, el nombre de su entrada se guarda en ellos. Los archivos de más
+// de 12 bloques conservan sus apuntadores y su lápida queda sin nombre.
+
+// SetTombstone convierte el inodo en una lápida con el nombre que tenía en su carpeta
+func (inode *Inode) SetTombstone(name string) {
+	if inode.I_block[12] == -1 {
+		packed := ToByte12(name)
+		for i := 0; i < 3; i++ {
+			inode.I_block[12+i] = int32(binary.LittleEndian.Uint32(packed[i*4 : i*4+4]))
+		}
+	}
+	inode.I_atime = float32(time.Now().Unix())
+}
+
+// TombstoneUsesIndirect indica si la lápida conserva apuntadores indirectos en lugar del
+// nombre: solo un archivo de más de 12 bloques de blockSize bytes los usa
+func (inode *Inode) TombstoneUsesIndirect(blockSize int32) bool {
+	return inode.I_type[0] == '1' && inode.I_size > 12*blockSize
+}
+
+// TombstoneName devuelve el nombre guardado en la lápida de un inodo liberado, o "" si la
+// lápida conserva los apuntadores indirectos
+func (inode *Inode) TombstoneName(blockSize int32) string {
+	if inode.TombstoneUsesIndirect(blockSize) {
+		return ""
+	}
+	var packed [12]byte
+	for i := 0; i < 3; i++ {
+		binary.LittleEndian.PutUint32(packed[i*4:i*4+4], uint32(inode.I_block[12+i]))
+	}
+	return strings.Trim(string(packed[:]), "\x00")
+}
+
+// ClearTombstone restaura los apuntadores indirectos de un inodo recuperado cuya lápida
+// guardaba el nombre
+func (inode *Inode) ClearTombstone(blockSize int32) {
+	if inode.TombstoneUsesIndirect(blockSize) {
+		return
+	}
+	for i := 12; i < 15; i++ {
+		inode.I_block[i] = -1
+	}
+}
@@ -0,0 +1,306 @@
+package structures
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// LayoutDump es el respaldo de la tabla de particiones de un disco, como sfdisk --dump.
+// Guarda las entradas primarias asignadas y todos los EBR de la partición extendida
+type LayoutDump struct {
+	Table        string          `json:"table"` // "MBR", "MSDOS" o "GPT"
+	DiskSize     int32           `json:"diskSize"`
+	CreationDate float32         `json:"creationDate"`
+	Signature    int32           `json:"signature"`
+	Fit          string          `json:"fit"`
+	Partitions   []PartitionDump `json:"partitions"`
+	Logical      []LogicalDump   `json:"logical,omitempty"`
+}
+
+// PartitionDump es una entrada de la tabla de particiones
+type PartitionDump struct {
+	Slot        int    `json:"slot"` // Posición de la entrada en la tabla
+	Status      string `json:"status"`
+	Type        string `json:"type"`
+	Fit         string `json:"fit"`
+	Start       int32  `json:"start"`
+	Size        int32  `json:"size"`
+	Name        string `json:"name"`
+	Correlative int32  `json:"correlative"`
+	ID          string `json:"id"`
+}
+
+// LogicalDump es un EBR de la cadena de la partición extendida
+type LogicalDump struct {
+	Offset int64  `json:"offset"` // Byte del disco donde está el EBR
+	Status string `json:"status"`
+	Fit    string `json:"fit"`
+	Start  int32  `json:"start"`
+	Size   int32  `json:"size"`
+	Next   int32  `json:"next"`
+	Name   string `json:"name"`
+	ID     string `json:"id"`
+}
+
+// DumpLayout lee la tabla de particiones y la cadena de EBR del disco
+func DumpLayout(path string) (*LayoutDump, error) {
+	table, err := ReadPartitionTable(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer la tabla de particiones: %v", err)
+	}
+
+	layout := &LayoutDump{
+		Table:        table.TypeName(),
+		DiskSize:     table.DiskSize(),
+		CreationDate: table.CreationDate(),
+		Signature:    table.DiskSignature(),
+		Fit:          string(table.DiskFit()),
+	}
+	for i, p := range table.Partitions() {
+		if isEmptyPartition(p) {
+			continue
+		}
+		layout.Partitions = append(layout.Partitions, PartitionDump{
+			Slot:        i,
+			Status:      string(p.Part_status[:]),
+			Type:        string(p.Part_type[:]),
+			Fit:         string(p.Part_fit[:]),
+			Start:       p.Part_start,
+			Size:        p.Part_size,
+			Name:        strings.Trim(string(p.Part_name[:]), "\x00"),
+			Correlative: p.Part_correlative,
+			ID:          strings.Trim(string(p.Part_id[:]), "\x00"),
+		})
+	}
+
+	extPartition := table.ExtendedPartition()
+	if extPartition == nil || isEmptyPartition(extPartition) {
+		return layout, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Una cadena dañada puede apuntar a un EBR ya visitado; se corta para no dar vueltas
+	visited := make(map[int64]bool)
+	offset := int64(extPartition.Part_start)
+	for !visited[offset] {
+		visited[offset] = true
+		var ebr EBR
+		if err := ebr.Deserialize(file, offset); err != nil {
+			return nil, fmt.Errorf("error al leer EBR en %d: %v", offset, err)
+		}
+		if ebr.Part_status[0] == 0 {
+			break // La extendida todavía no tiene particiones lógicas
+		}
+		layout.Logical = append(layout.Logical, LogicalDump{
+			Offset: offset,
+			Status: string(ebr.Part_status[:]),
+			Fit:    string(ebr.Part_fit[:]),
+			Start:  ebr.Part_start,
+			Size:   ebr.Part_size,
+			Next:   ebr.Part_next,
+			Name:   strings.Trim(string(ebr.Part_name[:]), "\x00"),
+			ID:     strings.Trim(string(ebr.Part_id[:]), "\x00"),
+		})
+		if ebr.Part_next == -1 {
+			break
+		}
+		offset = int64(ebr.Part_next)
+	}
+	return layout, nil
+}
+
+// RestoreLayout vuelve a escribir la tabla de particiones y los EBR de un respaldo. La tabla
+// se crea desde cero, así que también sirve si la del disco está dañada. Los datos de las
+// particiones no se tocan
+func RestoreLayout(path string, layout *LayoutDump) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() != int64(layout.DiskSize) {
+		return fmt.Errorf("el respaldo es de un disco de %d bytes y %s mide %d", layout.DiskSize, path, info.Size())
+	}
+
+	table, err := newLayoutTable(layout)
+	if err != nil {
+		return err
+	}
+	entries := table.Partitions()
+	for _, dump := range layout.Partitions {
+		if dump.Slot < 0 || dump.Slot >= len(entries) {
+			return fmt.Errorf("la entrada %d no existe en una tabla %s", dump.Slot, table.TypeName())
+		}
+		p := entries[dump.Slot]
+		if !isEmptyPartition(p) {
+			return fmt.Errorf("la entrada %d aparece dos veces", dump.Slot)
+		}
+		if dump.Start < 0 || dump.Size <= 0 {
+			return fmt.Errorf("la partición %s no tiene inicio o tamaño válidos", dump.Name)
+		}
+		if int64(dump.Start) < table.MetadataSize() || int64(dump.Start)+int64(dump.Size) > table.UsableEnd() {
+			return fmt.Errorf("la partición %s queda fuera del área utilizable del disco", dump.Name)
+		}
+		if dump.Type == "E" && table.PrimaryOnly() {
+			return fmt.Errorf("una tabla %s no admite particiones extendidas", table.TypeName())
+		}
+		*p = Partition{
+			Part_start:       dump.Start,
+			Part_size:        dump.Size,
+			Part_correlative: dump.Correlative,
+		}
+		copy(p.Part_status[:], dump.Status)
+		copy(p.Part_type[:], dump.Type)
+		copy(p.Part_fit[:], dump.Fit)
+		copy(p.Part_name[:], dump.Name)
+		copy(p.Part_id[:], dump.ID)
+	}
+
+	used := table.UsedPartitions()
+	for i := 1; i < len(used); i++ {
+		if used[i-1].Part_start+used[i-1].Part_size > used[i].Part_start {
+			return fmt.Errorf("las particiones %s y %s se superponen",
+				strings.Trim(string(used[i-1].Part_name[:]), "\x00"), strings.Trim(string(used[i].Part_name[:]), "\x00"))
+		}
+	}
+	extended := 0
+	for _, p := range used {
+		if p.Part_type[0] == 'E' {
+			extended++
+		}
+	}
+	if extended > 1 {
+		return errors.New("el respaldo tiene más de una partición extendida")
+	}
+	if err := checkLogicalLayout(table.ExtendedPartition(), layout.Logical); err != nil {
+		return err
+	}
+
+	if err := table.Write(path); err != nil {
+		return fmt.Errorf("error al escribir la tabla de particiones: %v", err)
+	}
+	ext := table.ExtendedPartition()
+	if len(layout.Logical) == 0 && ext == nil {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	// Una extendida sin lógicas en el respaldo empieza con un EBR vacío, para que no
+	// sobrevivan las particiones lógicas creadas después del respaldo
+	if len(layout.Logical) == 0 {
+		empty := EBR{Part_start: -1, Part_next: -1}
+		if err := empty.Serialize(file, int64(ext.Part_start)); err != nil {
+			return fmt.Errorf("error al escribir EBR en %d: %v", ext.Part_start, err)
+		}
+		return nil
+	}
+	for _, dump := range layout.Logical {
+		ebr := EBR{Part_start: dump.Start, Part_size: dump.Size, Part_next: dump.Next}
+		copy(ebr.Part_status[:], dump.Status)
+		copy(ebr.Part_fit[:], dump.Fit)
+		copy(ebr.Part_name[:], dump.Name)
+		copy(ebr.Part_id[:], dump.ID)
+		if err := ebr.Serialize(file, dump.Offset); err != nil {
+			return fmt.Errorf("error al escribir EBR en %d: %v", dump.Offset, err)
+		}
+	}
+	return nil
+}
+
+// newLayoutTable crea una tabla vacía del tipo y con los datos del disco del respaldo
+func newLayoutTable(layout *LayoutDump) (*PartitionTable, error) {
+	fit := byte('F')
+	if layout.Fit != "" {
+		fit = layout.Fit[0]
+	}
+	switch layout.Table {
+	case "MBR":
+		mbr := &MBR{
+			Mbr_size:           layout.DiskSize,
+			Mbr_creation_date:  layout.CreationDate,
+			Mbr_disk_signature: layout.Signature,
+			Mbr_disk_fit:       [1]byte{fit},
+		}
+		for i := range mbr.Mbr_partitions {
+			mbr.Mbr_partitions[i] = EmptyPartition()
+		}
+		return &PartitionTable{MBR: mbr}, nil
+	case "MSDOS":
+		return &PartitionTable{MSDOS: NewMSDOSTable(layout.DiskSize, layout.CreationDate, layout.Signature, fit)}, nil
+	case "GPT":
+		gpt, err := NewGPT(layout.DiskSize, layout.CreationDate, layout.Signature, fit)
+		if err != nil {
+			return nil, err
+		}
+		return &PartitionTable{GPT: gpt}, nil
+	}
+	return nil, fmt.Errorf("tipo de tabla desconocido: %q", layout.Table)
+}
+
+// checkLogicalLayout valida que la cadena de EBR empiece al inicio de la partición
+// extendida, quede dentro de ella y se pueda recorrer hasta el final sin ciclos
+func checkLogicalLayout(ext *Partition, logical []LogicalDump) error {
+	if len(logical) == 0 {
+		return nil
+	}
+	if ext == nil {
+		return errors.New("el respaldo tiene EBR pero ninguna partición extendida")
+	}
+	extStart, extEnd := int64(ext.Part_start), int64(ext.Part_start)+int64(ext.Part_size)
+	ebrSize := int64(binary.Size(EBR{}))
+
+	byOffset := make(map[int64]LogicalDump)
+	for _, dump := range logical {
+		if dump.Offset < extStart || dump.Offset+ebrSize > extEnd {
+			return fmt.Errorf("el EBR en %d queda fuera de la partición extendida", dump.Offset)
+		}
+		if dump.Size > 0 && (int64(dump.Start) < extStart || int64(dump.Start)+int64(dump.Size) > extEnd) {
+			return fmt.Errorf("la partición lógica %s queda fuera de la partición extendida", dump.Name)
+		}
+		if _, exists := byOffset[dump.Offset]; exists {
+			return fmt.Errorf("el EBR en %d aparece dos veces", dump.Offset)
+		}
+		byOffset[dump.Offset] = dump
+	}
+
+	offset, walked := extStart, 0
+	for {
+		dump, exists := byOffset[offset]
+		if !exists {
+			return fmt.Errorf("la cadena de EBR apunta a %d, que no está en el respaldo", offset)
+		}
+		walked++
+		if dump.Next == -1 {
+			break
+		}
+		if walked == len(logical) {
+			return errors.New("la cadena de EBR tiene un ciclo")
+		}
+		offset = int64(dump.Next)
+	}
+	if walked != len(logical) {
+		return fmt.Errorf("%d EBR del respaldo no forman parte de la cadena", len(logical)-walked)
+	}
+
+	// Las particiones lógicas no se superponen entre sí
+	sorted := append([]LogicalDump(nil), logical...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	for i := 1; i < len(sorted); i++ {
+		prev := sorted[i-1]
+		if prev.Size > 0 && sorted[i].Size > 0 && int64(prev.Start)+int64(prev.Size) > int64(sorted[i].Start) {
+			return fmt.Errorf("las particiones lógicas %s y %s se superponen", prev.Name, sorted[i].Name)
+		}
+	}
+	return nil
+}
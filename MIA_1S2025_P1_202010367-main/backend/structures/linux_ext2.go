@@ -0,0 +1,824 @@
+package structures
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Estructuras del formato ext2 de Linux, usadas para exportar particiones a imágenes que
+// las herramientas del anfitrión (e2fsck, mount, debugfs) pueden leer
+
+const (
+	LinuxBlockSize       = 1024 // Bloques de 1 KiB
+	linuxInodeSize       = 128
+	linuxBlocksPerGroup  = 8192 // Un bloque de bitmap cubre 8192 bloques
+	linuxFirstIno        = 11   // Primer inodo no reservado (lost+found)
+	linuxRootIno         = 2
+	linuxPointersPerBlk  = LinuxBlockSize / 4
+	linuxIncompatFiletyp = 0x0002 // Las entradas de directorio guardan el tipo de archivo
+
+	LinuxModeDir  = 0x4000
+	LinuxModeFile = 0x8000
+)
+
+// LinuxExt2SuperBlock es el superbloque de ext2 (revisión 1), 1024 bytes
+type LinuxExt2SuperBlock struct {
+	InodesCount     uint32
+	BlocksCount     uint32
+	RBlocksCount    uint32
+	FreeBlocksCount uint32
+	FreeInodesCount uint32
+	FirstDataBlock  uint32
+	LogBlockSize    uint32
+	LogFragSize     uint32
+	BlocksPerGroup  uint32
+	FragsPerGroup   uint32
+	InodesPerGroup  uint32
+	Mtime           uint32
+	Wtime           uint32
+	MntCount        uint16
+	MaxMntCount     int16
+	Magic           uint16
+	State           uint16
+	Errors          uint16
+	MinorRevLevel   uint16
+	LastCheck       uint32
+	CheckInterval   uint32
+	CreatorOS       uint32
+	RevLevel        uint32
+	DefResuid       uint16
+	DefResgid       uint16
+	FirstIno        uint32
+	InodeSize       uint16
+	BlockGroupNr    uint16
+	FeatureCompat   uint32
+	FeatureIncompat uint32
+	FeatureRoCompat uint32
+	UUID            [16]byte
+	VolumeName      [16]byte
+	LastMounted     [64]byte
+	AlgoBitmap      uint32
+	Padding         [820]byte
+}
+
+// LinuxGroupDesc es el descriptor de un grupo de bloques, 32 bytes
+type LinuxGroupDesc struct {
+	BlockBitmap     uint32
+	InodeBitmap     uint32
+	InodeTable      uint32
+	FreeBlocksCount uint16
+	FreeInodesCount uint16
+	UsedDirsCount   uint16
+	Pad             uint16
+	Reserved        [12]byte
+}
+
+// LinuxInode es un inodo de ext2, 128 bytes
+type LinuxInode struct {
+	Mode       uint16
+	Uid        uint16
+	Size       uint32
+	Atime      uint32
+	Ctime      uint32
+	Mtime      uint32
+	Dtime      uint32
+	Gid        uint16
+	LinksCount uint16
+	Blocks     uint32 // En sectores de 512 bytes
+	Flags      uint32
+	Osd1       uint32
+	Block      [15]uint32
+	Generation uint32
+	FileACL    uint32
+	DirACL     uint32
+	Faddr      uint32
+	Osd2       [12]byte
+}
+
+// Ext2ImageNode es un archivo o carpeta que se escribe en una imagen ext2. Un mismo nodo
+// referenciado desde varias entradas se escribe como un único inodo con enlaces duros
+type Ext2ImageNode struct {
+	IsDir    bool
+	Perm     uint16 // Permisos en octal (9 bits)
+	Uid      uint16
+	Gid      uint16
+	Atime    uint32
+	Ctime    uint32
+	Mtime    uint32
+	Content  []byte           // Contenido de los archivos
+	Children []Ext2ImageEntry // Entradas de las carpetas, sin "." ni ".."
+}
+
+// Ext2ImageEntry es una entrada con nombre dentro de una carpeta
+type Ext2ImageEntry struct {
+	Name string
+	Node *Ext2ImageNode
+}
+
+// ext2Layout guarda la geometría de la imagen
+type ext2Layout struct {
+	blocks         uint32
+	groups         uint32
+	inodesPerGroup uint32
+	gdtBlocks      uint32
+	overhead       uint32 // Bloques de metadatos al inicio de cada grupo
+}
+
+func (l *ext2Layout) groupStart(g uint32) uint32 {
+	return 1 + g*linuxBlocksPerGroup
+}
+
+func (l *ext2Layout) groupBlocks(g uint32) uint32 {
+	if g == l.groups-1 {
+		return l.blocks - l.groupStart(g)
+	}
+	return linuxBlocksPerGroup
+}
+
+func (l *ext2Layout) inodeTable(g uint32) uint32 {
+	return l.groupStart(g) + 1 + l.gdtBlocks + 2
+}
+
+// newExt2Layout calcula una geometría con al menos minBlocks bloques donde quepan los
+// inodos y bloques de datos pedidos
+func newExt2Layout(minBlocks, inodes, dataBlocks uint32) (*ext2Layout, error) {
+	blocks := minBlocks
+	if blocks < 64 {
+		blocks = 64
+	}
+	for attempt := 0; attempt < 64; attempt++ {
+		l := &ext2Layout{blocks: blocks}
+		l.groups = (blocks - 1 + linuxBlocksPerGroup - 1) / linuxBlocksPerGroup
+
+		wanted := inodes
+		if blocks/8 > wanted {
+			wanted = blocks / 8
+		}
+		perBlock := uint32(LinuxBlockSize / linuxInodeSize)
+		l.inodesPerGroup = (wanted + l.groups - 1) / l.groups
+		l.inodesPerGroup = (l.inodesPerGroup + perBlock - 1) / perBlock * perBlock
+		if l.inodesPerGroup > linuxBlocksPerGroup {
+			l.inodesPerGroup = linuxBlocksPerGroup
+		}
+		l.gdtBlocks = (l.groups*32 + LinuxBlockSize - 1) / LinuxBlockSize
+		l.overhead = 1 + l.gdtBlocks + 2 + l.inodesPerGroup/perBlock
+
+		// El último grupo debe tener espacio para sus metadatos y algún bloque de datos
+		if last := l.groupBlocks(l.groups - 1); last < l.overhead+16 {
+			blocks += l.overhead + 16 - last
+			continue
+		}
+		if l.inodesPerGroup*l.groups < inodes {
+			blocks += linuxBlocksPerGroup
+			continue
+		}
+		needed := 1 + l.groups*l.overhead + dataBlocks
+		if needed > blocks {
+			blocks = needed + needed/16
+			continue
+		}
+		return l, nil
+	}
+	return nil, errors.New("no se pudo calcular la geometría de la imagen ext2")
+}
+
+// ext2FileBlocks devuelve los bloques de datos más los de punteros indirectos que ocupa un
+// archivo de n bloques de datos
+func ext2FileBlocks(n uint32) (uint32, error) {
+	total := n
+	if n > 12 {
+		total++ // Indirecto simple
+	}
+	if n > 12+linuxPointersPerBlk {
+		rest := n - 12 - linuxPointersPerBlk
+		if rest > linuxPointersPerBlk*linuxPointersPerBlk {
+			return 0, errors.New("archivo demasiado grande para la imagen ext2")
+		}
+		total += 1 + (rest+linuxPointersPerBlk-1)/linuxPointersPerBlk
+	}
+	return total, nil
+}
+
+// ext2DirBlocks empaqueta las entradas de una carpeta en bloques de 1 KiB con rec_len
+func ext2DirBlocks(entries []ext2Dirent) [][]byte {
+	var blocks [][]byte
+	block := make([]byte, 0, LinuxBlockSize)
+	lastOffset := -1
+	flush := func() {
+		// La última entrada del bloque se extiende hasta el final
+		recLen := LinuxBlockSize - lastOffset
+		binary.LittleEndian.PutUint16(block[lastOffset+4:], uint16(recLen))
+		full := make([]byte, LinuxBlockSize)
+		copy(full, block)
+		blocks = append(blocks, full)
+		block = block[:0]
+	}
+	for _, e := range entries {
+		size := (8 + len(e.name) + 3) / 4 * 4
+		if len(block)+size > LinuxBlockSize {
+			flush()
+		}
+		lastOffset = len(block)
+		entry := make([]byte, size)
+		binary.LittleEndian.PutUint32(entry[0:], e.inode)
+		binary.LittleEndian.PutUint16(entry[4:], uint16(size))
+		entry[6] = byte(len(e.name))
+		entry[7] = e.fileType
+		copy(entry[8:], e.name)
+		block = append(block, entry...)
+	}
+	flush()
+	return blocks
+}
+
+type ext2Dirent struct {
+	inode    uint32
+	fileType byte
+	name     string
+}
+
+// WriteExt2Image escribe en imagePath una imagen ext2 de Linux con el árbol de root. La
+// imagen tiene al menos minSize bytes y crece si el contenido no cabe
+func WriteExt2Image(imagePath string, root *Ext2ImageNode, minSize int64, volume string) error {
+	if root == nil || !root.IsDir {
+		return errors.New("la raíz de la imagen debe ser una carpeta")
+	}
+
+	// lost+found debe existir y ocupar el inodo 11
+	var lostFound *Ext2ImageNode
+	for _, e := range root.Children {
+		if e.Name == "lost+found" && e.Node.IsDir {
+			lostFound = e.Node
+		}
+	}
+	if lostFound == nil {
+		now := uint32(time.Now().Unix())
+		lostFound = &Ext2ImageNode{IsDir: true, Perm: 0700, Atime: now, Ctime: now, Mtime: now}
+		root.Children = append(root.Children, Ext2ImageEntry{Name: "lost+found", Node: lostFound})
+	}
+
+	// Asignar números de inodo recorriendo el árbol
+	inoOf := map[*Ext2ImageNode]uint32{root: linuxRootIno, lostFound: linuxFirstIno}
+	order := []*Ext2ImageNode{root, lostFound}
+	links := map[*Ext2ImageNode]uint16{root: 2}
+	next := uint32(linuxFirstIno + 1)
+	var assign func(dir *Ext2ImageNode) error
+	assign = func(dir *Ext2ImageNode) error {
+		for _, e := range dir.Children {
+			if len(e.Name) == 0 || len(e.Name) > 255 {
+				return fmt.Errorf("nombre inválido para ext2: %q", e.Name)
+			}
+			_, seen := inoOf[e.Node]
+			if e.Node.IsDir {
+				if seen && e.Node != lostFound {
+					return fmt.Errorf("la carpeta %s tiene más de un enlace", e.Name)
+				}
+				links[e.Node] = 2
+				links[dir]++
+			} else {
+				links[e.Node]++
+			}
+			if !seen {
+				inoOf[e.Node] = next
+				order = append(order, e.Node)
+				next++
+			}
+			if e.Node.IsDir {
+				if err := assign(e.Node); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := assign(root); err != nil {
+		return err
+	}
+	usedInodes := next - 1
+
+	// Contenido de cada inodo: entradas de carpeta o datos de archivo
+	parentOf := map[*Ext2ImageNode]*Ext2ImageNode{root: root}
+	for _, dir := range order {
+		for _, e := range dir.Children {
+			if e.Node.IsDir {
+				parentOf[e.Node] = dir
+			}
+		}
+	}
+	dirData := make(map[*Ext2ImageNode][][]byte)
+	dataBlocks := uint32(0)
+	for _, node := range order {
+		if node.IsDir {
+			entries := []ext2Dirent{
+				{inoOf[node], 2, "."},
+				{inoOf[parentOf[node]], 2, ".."},
+			}
+			for _, e := range node.Children {
+				fileType := byte(1)
+				if e.Node.IsDir {
+					fileType = 2
+				}
+				entries = append(entries, ext2Dirent{inoOf[e.Node], fileType, e.Name})
+			}
+			dirData[node] = ext2DirBlocks(entries)
+			dataBlocks += uint32(len(dirData[node]))
+			continue
+		}
+		n, err := ext2FileBlocks(uint32((len(node.Content) + LinuxBlockSize - 1) / LinuxBlockSize))
+		if err != nil {
+			return err
+		}
+		dataBlocks += n
+	}
+
+	layout, err := newExt2Layout(uint32(minSize/LinuxBlockSize), usedInodes, dataBlocks)
+	if err != nil {
+		return err
+	}
+
+	// Bitmaps en memoria; los metadatos de cada grupo quedan marcados como usados
+	blockBitmap := make([][]byte, layout.groups)
+	inodeBitmap := make([][]byte, layout.groups)
+	for g := uint32(0); g < layout.groups; g++ {
+		blockBitmap[g] = make([]byte, LinuxBlockSize)
+		inodeBitmap[g] = make([]byte, LinuxBlockSize)
+		for i := uint32(0); i < layout.overhead; i++ {
+			blockBitmap[g][i/8] |= 1 << (i % 8)
+		}
+		// Los bits que quedan fuera del grupo se marcan como usados
+		for i := layout.groupBlocks(g); i < linuxBlocksPerGroup; i++ {
+			blockBitmap[g][i/8] |= 1 << (i % 8)
+		}
+		for i := layout.inodesPerGroup; i < LinuxBlockSize*8; i++ {
+			inodeBitmap[g][i/8] |= 1 << (i % 8)
+		}
+	}
+	// Los inodos reservados (1 a 10) también cuentan como usados
+	for ino := uint32(1); ino <= usedInodes; ino++ {
+		g, i := (ino-1)/layout.inodesPerGroup, (ino-1)%layout.inodesPerGroup
+		inodeBitmap[g][i/8] |= 1 << (i % 8)
+	}
+
+	nextGroup, nextIndex := uint32(0), layout.overhead
+	allocBlock := func() (uint32, error) {
+		for nextGroup < layout.groups {
+			if nextIndex < layout.groupBlocks(nextGroup) {
+				block := layout.groupStart(nextGroup) + nextIndex
+				blockBitmap[nextGroup][nextIndex/8] |= 1 << (nextIndex % 8)
+				nextIndex++
+				return block, nil
+			}
+			nextGroup++
+			nextIndex = layout.overhead
+		}
+		return 0, errors.New("no hay bloques libres en la imagen ext2")
+	}
+
+	if err := os.WriteFile(imagePath, nil, 0644); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(imagePath, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := file.Truncate(int64(layout.blocks) * LinuxBlockSize); err != nil {
+		return err
+	}
+	writeBlock := func(block uint32, data []byte) error {
+		_, err := file.WriteAt(data, int64(block)*LinuxBlockSize)
+		return err
+	}
+
+	usedDirs := make([]uint16, layout.groups)
+	for _, node := range order {
+		ino := inoOf[node]
+		inode := LinuxInode{
+			Uid:        node.Uid,
+			Gid:        node.Gid,
+			Atime:      node.Atime,
+			Ctime:      node.Ctime,
+			Mtime:      node.Mtime,
+			LinksCount: links[node],
+		}
+
+		var chunks [][]byte
+		if node.IsDir {
+			inode.Mode = LinuxModeDir | node.Perm&0o7777
+			chunks = dirData[node]
+			usedDirs[(ino-1)/layout.inodesPerGroup]++
+		} else {
+			inode.Mode = LinuxModeFile | node.Perm&0o7777
+			for off := 0; off < len(node.Content); off += LinuxBlockSize {
+				chunk := make([]byte, LinuxBlockSize)
+				copy(chunk, node.Content[off:])
+				chunks = append(chunks, chunk)
+			}
+		}
+		inode.Size = uint32(len(chunks)) * LinuxBlockSize
+		if !node.IsDir {
+			inode.Size = uint32(len(node.Content))
+		}
+
+		// Bloques de datos y de punteros indirectos
+		var single, double []uint32
+		var doubleChildren [][]uint32
+		for i, chunk := range chunks {
+			block, err := allocBlock()
+			if err != nil {
+				return err
+			}
+			if err := writeBlock(block, chunk); err != nil {
+				return err
+			}
+			inode.Blocks += LinuxBlockSize / 512
+			switch {
+			case i < 12:
+				inode.Block[i] = block
+			case i < 12+linuxPointersPerBlk:
+				single = append(single, block)
+			default:
+				j := (i - 12 - linuxPointersPerBlk) / linuxPointersPerBlk
+				if j == len(doubleChildren) {
+					doubleChildren = append(doubleChildren, nil)
+				}
+				doubleChildren[j] = append(doubleChildren[j], block)
+			}
+		}
+		writePointers := func(pointers []uint32) (uint32, error) {
+			block, err := allocBlock()
+			if err != nil {
+				return 0, err
+			}
+			inode.Blocks += LinuxBlockSize / 512
+			data := make([]byte, LinuxBlockSize)
+			for i, p := range pointers {
+				binary.LittleEndian.PutUint32(data[i*4:], p)
+			}
+			return block, writeBlock(block, data)
+		}
+		if len(single) > 0 {
+			if inode.Block[12], err = writePointers(single); err != nil {
+				return err
+			}
+		}
+		for _, children := range doubleChildren {
+			block, err := writePointers(children)
+			if err != nil {
+				return err
+			}
+			double = append(double, block)
+		}
+		if len(double) > 0 {
+			if inode.Block[13], err = writePointers(double); err != nil {
+				return err
+			}
+		}
+
+		g, i := (ino-1)/layout.inodesPerGroup, (ino-1)%layout.inodesPerGroup
+		var buffer bytes.Buffer
+		if err := binary.Write(&buffer, binary.LittleEndian, &inode); err != nil {
+			return err
+		}
+		offset := int64(layout.inodeTable(g))*LinuxBlockSize + int64(i)*linuxInodeSize
+		if _, err := file.WriteAt(buffer.Bytes(), offset); err != nil {
+			return err
+		}
+	}
+
+	// Descriptores de grupo y contadores libres
+	descs := make([]LinuxGroupDesc, layout.groups)
+	totalFreeBlocks, totalFreeInodes := uint32(0), uint32(0)
+	for g := uint32(0); g < layout.groups; g++ {
+		start := layout.groupStart(g)
+		descs[g] = LinuxGroupDesc{
+			BlockBitmap:   start + 1 + layout.gdtBlocks,
+			InodeBitmap:   start + 2 + layout.gdtBlocks,
+			InodeTable:    layout.inodeTable(g),
+			UsedDirsCount: usedDirs[g],
+		}
+		freeBlocks := uint32(0)
+		for i := uint32(0); i < layout.groupBlocks(g); i++ {
+			if blockBitmap[g][i/8]&(1<<(i%8)) == 0 {
+				freeBlocks++
+			}
+		}
+		freeInodes := uint32(0)
+		for i := uint32(0); i < layout.inodesPerGroup; i++ {
+			if inodeBitmap[g][i/8]&(1<<(i%8)) == 0 {
+				freeInodes++
+			}
+		}
+		descs[g].FreeBlocksCount = uint16(freeBlocks)
+		descs[g].FreeInodesCount = uint16(freeInodes)
+		totalFreeBlocks += freeBlocks
+		totalFreeInodes += freeInodes
+
+		if err := writeBlock(descs[g].BlockBitmap, blockBitmap[g]); err != nil {
+			return err
+		}
+		if err := writeBlock(descs[g].InodeBitmap, inodeBitmap[g]); err != nil {
+			return err
+		}
+	}
+
+	now := uint32(time.Now().Unix())
+	sb := LinuxExt2SuperBlock{
+		InodesCount:     layout.inodesPerGroup * layout.groups,
+		BlocksCount:     layout.blocks,
+		FreeBlocksCount: totalFreeBlocks,
+		FreeInodesCount: totalFreeInodes,
+		FirstDataBlock:  1,
+		BlocksPerGroup:  linuxBlocksPerGroup,
+		FragsPerGroup:   linuxBlocksPerGroup,
+		InodesPerGroup:  layout.inodesPerGroup,
+		Wtime:           now,
+		MaxMntCount:     -1,
+		Magic:           0xEF53,
+		State:           1, // Desmontado limpiamente
+		Errors:          1, // Continuar ante errores
+		LastCheck:       now,
+		RevLevel:        1,
+		FirstIno:        linuxFirstIno,
+		InodeSize:       linuxInodeSize,
+		FeatureIncompat: linuxIncompatFiletyp,
+	}
+	rand.Read(sb.UUID[:])
+	copy(sb.VolumeName[:], volume)
+
+	var gdt bytes.Buffer
+	if err := binary.Write(&gdt, binary.LittleEndian, descs); err != nil {
+		return err
+	}
+	// Cada grupo lleva una copia del superbloque y de la tabla de descriptores
+	for g := uint32(0); g < layout.groups; g++ {
+		sb.BlockGroupNr = uint16(g)
+		var buffer bytes.Buffer
+		if err := binary.Write(&buffer, binary.LittleEndian, &sb); err != nil {
+			return err
+		}
+		if err := writeBlock(layout.groupStart(g), buffer.Bytes()); err != nil {
+			return err
+		}
+		if err := writeBlock(layout.groupStart(g)+1, gdt.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Características incompatibles que el lector sabe interpretar: tipo en las entradas de
+// directorio, grupos flexibles y descriptores de 64 bits. Los archivos con extents se
+// informan como no soportados
+const linuxSupportedIncompat = 0x0002 | 0x0040 | 0x0080 | 0x0200
+
+const linuxExtentsFlag = 0x80000
+
+// ext2Reader lee una imagen ext2 de Linux en modo solo lectura
+type ext2Reader struct {
+	file           *os.File
+	sb             LinuxExt2SuperBlock
+	blockSize      int64
+	inodeSize      int64
+	descSize       int64
+	descs          []byte
+	fileTypeInDirs bool
+	warnings       []string
+	nodes          map[uint32]*Ext2ImageNode
+}
+
+// ReadExt2Image lee el árbol completo de una imagen ext2 de Linux. Los elementos que no se
+// pueden representar (enlaces simbólicos, dispositivos, archivos con extents) se omiten y
+// se describen en la lista de advertencias
+func ReadExt2Image(imagePath string) (*Ext2ImageNode, []string, error) {
+	file, err := os.Open(imagePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	r := &ext2Reader{file: file, nodes: make(map[uint32]*Ext2ImageNode)}
+	raw := make([]byte, 1024)
+	if _, err := file.ReadAt(raw, 1024); err != nil {
+		return nil, nil, fmt.Errorf("error al leer el superbloque: %v", err)
+	}
+	if err := binary.Read(bytes.NewReader(raw), binary.LittleEndian, &r.sb); err != nil {
+		return nil, nil, err
+	}
+	if r.sb.Magic != 0xEF53 {
+		return nil, nil, errors.New("la imagen no es un sistema de archivos ext2")
+	}
+	if r.sb.FeatureIncompat&0x0004 != 0 {
+		return nil, nil, errors.New("el journal de la imagen necesita recuperación; ejecute e2fsck primero")
+	}
+	if unsupported := r.sb.FeatureIncompat &^ linuxSupportedIncompat; unsupported != 0 {
+		return nil, nil, fmt.Errorf("características incompatibles no soportadas: 0x%x", unsupported)
+	}
+	if r.sb.InodesPerGroup == 0 || r.sb.BlocksPerGroup == 0 || r.sb.LogBlockSize > 6 {
+		return nil, nil, errors.New("superbloque ext2 inválido")
+	}
+
+	r.blockSize = 1024 << r.sb.LogBlockSize
+	r.inodeSize, r.descSize = 128, 32
+	if r.sb.RevLevel >= 1 {
+		r.inodeSize = int64(r.sb.InodeSize)
+		r.fileTypeInDirs = r.sb.FeatureIncompat&0x0002 != 0
+	}
+	if r.sb.FeatureIncompat&0x0080 != 0 {
+		if size := int64(binary.LittleEndian.Uint16(raw[254:256])); size >= 32 {
+			r.descSize = size
+		}
+	}
+
+	groups := (int64(r.sb.BlocksCount) - int64(r.sb.FirstDataBlock) + int64(r.sb.BlocksPerGroup) - 1) / int64(r.sb.BlocksPerGroup)
+	r.descs = make([]byte, groups*r.descSize)
+	if _, err := file.ReadAt(r.descs, (int64(r.sb.FirstDataBlock)+1)*r.blockSize); err != nil {
+		return nil, nil, fmt.Errorf("error al leer los descriptores de grupo: %v", err)
+	}
+
+	root, err := r.readNode(linuxRootIno, "/")
+	if err != nil {
+		return nil, nil, err
+	}
+	return root, r.warnings, nil
+}
+
+// readInode lee el inodo número ino
+func (r *ext2Reader) readInode(ino uint32) (*LinuxInode, error) {
+	if ino == 0 || ino > r.sb.InodesCount {
+		return nil, fmt.Errorf("número de inodo inválido: %d", ino)
+	}
+	group := int64((ino - 1) / r.sb.InodesPerGroup)
+	index := int64((ino - 1) % r.sb.InodesPerGroup)
+	if (group+1)*r.descSize > int64(len(r.descs)) {
+		return nil, fmt.Errorf("inodo %d fuera de los grupos de la imagen", ino)
+	}
+	table := int64(binary.LittleEndian.Uint32(r.descs[group*r.descSize+8:]))
+
+	buffer := make([]byte, 128)
+	if _, err := r.file.ReadAt(buffer, table*r.blockSize+index*r.inodeSize); err != nil {
+		return nil, fmt.Errorf("error al leer inodo %d: %v", ino, err)
+	}
+	inode := &LinuxInode{}
+	if err := binary.Read(bytes.NewReader(buffer), binary.LittleEndian, inode); err != nil {
+		return nil, err
+	}
+	return inode, nil
+}
+
+// readBlocks devuelve los números de bloque de datos del inodo en orden lógico; 0 indica
+// un hueco
+func (r *ext2Reader) readBlocks(inode *LinuxInode, count int64) ([]uint32, error) {
+	var blocks []uint32
+	var walk func(block uint32, depth int) error
+	walk = func(block uint32, depth int) error {
+		if int64(len(blocks)) >= count {
+			return nil
+		}
+		if depth == 0 {
+			blocks = append(blocks, block)
+			return nil
+		}
+		perBlock := r.blockSize / 4
+		if block == 0 {
+			// Hueco: todos los bloques que cubre este puntero son ceros
+			span := int64(1)
+			for i := 0; i < depth; i++ {
+				span *= perBlock
+			}
+			for i := int64(0); i < span && int64(len(blocks)) < count; i++ {
+				blocks = append(blocks, 0)
+			}
+			return nil
+		}
+		data := make([]byte, r.blockSize)
+		if _, err := r.file.ReadAt(data, int64(block)*r.blockSize); err != nil {
+			return fmt.Errorf("error al leer bloque de punteros %d: %v", block, err)
+		}
+		for i := int64(0); i < perBlock && int64(len(blocks)) < count; i++ {
+			if err := walk(binary.LittleEndian.Uint32(data[i*4:]), depth-1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i := 0; i < 12; i++ {
+		if err := walk(inode.Block[i], 0); err != nil {
+			return nil, err
+		}
+	}
+	for depth := 1; depth <= 3; depth++ {
+		if err := walk(inode.Block[11+depth], depth); err != nil {
+			return nil, err
+		}
+	}
+	return blocks, nil
+}
+
+// readData devuelve el contenido completo de un inodo
+func (r *ext2Reader) readData(inode *LinuxInode, size int64) ([]byte, error) {
+	count := (size + r.blockSize - 1) / r.blockSize
+	blocks, err := r.readBlocks(inode, count)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, count*r.blockSize)
+	for i, block := range blocks {
+		if block == 0 {
+			continue
+		}
+		if _, err := r.file.ReadAt(data[int64(i)*r.blockSize:int64(i+1)*r.blockSize], int64(block)*r.blockSize); err != nil {
+			return nil, fmt.Errorf("error al leer bloque %d: %v", block, err)
+		}
+	}
+	return data[:size], nil
+}
+
+// readNode lee un inodo de carpeta o archivo y, en las carpetas, todas sus entradas
+func (r *ext2Reader) readNode(ino uint32, path string) (*Ext2ImageNode, error) {
+	if node, ok := r.nodes[ino]; ok {
+		return node, nil
+	}
+	inode, err := r.readInode(ino)
+	if err != nil {
+		return nil, err
+	}
+	if inode.Flags&linuxExtentsFlag != 0 {
+		return nil, fmt.Errorf("%s usa extents, no soportado", path)
+	}
+	node := &Ext2ImageNode{
+		IsDir: inode.Mode&0xF000 == LinuxModeDir,
+		Perm:  inode.Mode & 0o777,
+		Uid:   inode.Uid,
+		Gid:   inode.Gid,
+		Atime: inode.Atime,
+		Ctime: inode.Ctime,
+		Mtime: inode.Mtime,
+	}
+	r.nodes[ino] = node
+
+	size := int64(inode.Size)
+	if !node.IsDir {
+		size |= int64(inode.DirACL) << 32
+	}
+	data, err := r.readData(inode, size)
+	if err != nil {
+		return nil, err
+	}
+	if !node.IsDir {
+		node.Content = data
+		return node, nil
+	}
+
+	for offset := int64(0); offset+8 <= int64(len(data)); {
+		childIno := binary.LittleEndian.Uint32(data[offset:])
+		recLen := int64(binary.LittleEndian.Uint16(data[offset+4:]))
+		nameLen := int64(data[offset+6])
+		if !r.fileTypeInDirs {
+			nameLen = int64(binary.LittleEndian.Uint16(data[offset+6:]))
+		}
+		if recLen < 8 || offset+recLen > int64(len(data)) || 8+nameLen > recLen {
+			r.warnings = append(r.warnings, fmt.Sprintf("%s: entrada de directorio dañada, se omite el resto", path))
+			break
+		}
+		name := string(data[offset+8 : offset+8+nameLen])
+		offset += recLen
+		if childIno == 0 || name == "." || name == ".." {
+			continue
+		}
+
+		childPath := path + name
+		child, err := r.readInode(childIno)
+		if err != nil {
+			r.warnings = append(r.warnings, fmt.Sprintf("%s: %v", childPath, err))
+			continue
+		}
+		switch {
+		case child.Flags&linuxExtentsFlag != 0:
+			r.warnings = append(r.warnings, fmt.Sprintf("%s: usa extents, no soportado", childPath))
+			continue
+		case child.Mode&0xF000 == 0xA000:
+			r.warnings = append(r.warnings, fmt.Sprintf("%s: enlace simbólico, no soportado", childPath))
+			continue
+		case child.Mode&0xF000 != LinuxModeDir && child.Mode&0xF000 != LinuxModeFile:
+			r.warnings = append(r.warnings, fmt.Sprintf("%s: archivo especial (modo 0%o), no soportado", childPath, child.Mode))
+			continue
+		}
+
+		if child.Mode&0xF000 == LinuxModeDir {
+			childPath += "/"
+		}
+		childNode, err := r.readNode(childIno, childPath)
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, Ext2ImageEntry{Name: name, Node: childNode})
+	}
+	return node, nil
+}
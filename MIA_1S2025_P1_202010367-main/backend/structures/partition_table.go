@@ -0,0 +1,286 @@
+package structures
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PartitionTable es la tabla de particiones de un disco: el MBR del proyecto, un MBR
+// estándar (msdos) o GPT. Solo uno de los campos es distinto de nil
+type PartitionTable struct {
+	MBR   *MBR
+	MSDOS *MSDOSTable
+	GPT   *GPT
+}
+
+// ReadPartitionTable lee la tabla de particiones del disco detectando su tipo
+func ReadPartitionTable(path string) (*PartitionTable, error) {
+	if IsGPTDisk(path) {
+		gpt := &GPT{}
+		if err := gpt.Deserialize(path); err != nil {
+			return nil, err
+		}
+		return &PartitionTable{GPT: gpt}, nil
+	}
+	if IsMSDOSDisk(path) {
+		msdos := &MSDOSTable{}
+		if err := msdos.Deserialize(path); err != nil {
+			return nil, err
+		}
+		return &PartitionTable{MSDOS: msdos}, nil
+	}
+
+	mbr := &MBR{}
+	if err := mbr.Deserialize(path); err != nil {
+		return nil, err
+	}
+	return &PartitionTable{MBR: mbr}, nil
+}
+
+// Write escribe la tabla de particiones en el disco
+func (t *PartitionTable) Write(path string) error {
+	if t.GPT != nil {
+		return t.GPT.Serialize(path)
+	}
+	if t.MSDOS != nil {
+		return t.MSDOS.Serialize(path)
+	}
+	return t.MBR.Serialize(path)
+}
+
+// IsGPT indica si la tabla es GPT
+func (t *PartitionTable) IsGPT() bool {
+	return t.GPT != nil
+}
+
+// PrimaryOnly indica si la tabla solo admite particiones primarias (GPT y MBR estándar)
+func (t *PartitionTable) PrimaryOnly() bool {
+	return t.MBR == nil
+}
+
+// TypeName devuelve el nombre del tipo de tabla
+func (t *PartitionTable) TypeName() string {
+	if t.GPT != nil {
+		return "GPT"
+	}
+	if t.MSDOS != nil {
+		return "MSDOS"
+	}
+	return "MBR"
+}
+
+// DiskSize devuelve el tamaño del disco en bytes
+func (t *PartitionTable) DiskSize() int32 {
+	if t.GPT != nil {
+		return t.GPT.DiskSize
+	}
+	if t.MSDOS != nil {
+		return t.MSDOS.DiskSize
+	}
+	return t.MBR.Mbr_size
+}
+
+// CreationDate devuelve la fecha de creación del disco
+func (t *PartitionTable) CreationDate() float32 {
+	if t.GPT != nil {
+		return t.GPT.Side.CreationDate
+	}
+	if t.MSDOS != nil {
+		return t.MSDOS.Side.CreationDate
+	}
+	return t.MBR.Mbr_creation_date
+}
+
+// DiskSignature devuelve la firma del disco
+func (t *PartitionTable) DiskSignature() int32 {
+	if t.GPT != nil {
+		return t.GPT.Side.Signature
+	}
+	if t.MSDOS != nil {
+		return t.MSDOS.DiskSignature()
+	}
+	return t.MBR.Mbr_disk_signature
+}
+
+// DiskFit devuelve el ajuste del disco
+func (t *PartitionTable) DiskFit() byte {
+	if t.GPT != nil {
+		return t.GPT.Side.Fit[0]
+	}
+	if t.MSDOS != nil {
+		return t.MSDOS.Side.Fit[0]
+	}
+	return t.MBR.Mbr_disk_fit[0]
+}
+
+// Resize cambia el tamaño del disco registrado en la tabla. En GPT la cabecera de respaldo
+// y su tabla de entradas pasan a los últimos sectores del nuevo tamaño al escribir la tabla
+func (t *PartitionTable) Resize(size int32) error {
+	switch {
+	case t.GPT != nil:
+		lastLBA := uint64(size)/SectorSize - 1
+		if lastLBA < t.GPT.Header.FirstUsableLBA+uint64(gptEntrySectors)+1 {
+			return errors.New("el disco es demasiado pequeño para una tabla GPT")
+		}
+		t.GPT.Header.AlternateLBA = lastLBA
+		t.GPT.Header.LastUsableLBA = lastLBA - gptEntrySectors - 1
+		t.GPT.DiskSize = size
+	case t.MSDOS != nil:
+		t.MSDOS.DiskSize = size
+	default:
+		t.MBR.Mbr_size = size
+	}
+	return nil
+}
+
+// BackupArea devuelve el rango de bytes que ocupa la copia de respaldo de la tabla al final
+// del disco (solo GPT)
+func (t *PartitionTable) BackupArea() (int64, int64) {
+	if t.GPT == nil {
+		return 0, 0
+	}
+	start := t.GPT.UsableEnd()
+	return start, int64(t.GPT.Header.AlternateLBA+1)*SectorSize - start
+}
+
+// MetadataSize devuelve los bytes del inicio del disco ocupados por la tabla
+func (t *PartitionTable) MetadataSize() int64 {
+	if t.GPT != nil {
+		return t.GPT.UsableStart()
+	}
+	if t.MSDOS != nil {
+		return t.MSDOS.UsableStart()
+	}
+	return int64(binary.Size(t.MBR))
+}
+
+// UsableEnd devuelve el byte siguiente al último que pueden ocupar las particiones
+func (t *PartitionTable) UsableEnd() int64 {
+	if t.GPT != nil {
+		return t.GPT.UsableEnd()
+	}
+	if t.MSDOS != nil {
+		return t.MSDOS.UsableEnd()
+	}
+	return int64(t.MBR.Mbr_size)
+}
+
+// Partitions devuelve punteros a todas las entradas de la tabla, incluidas las vacías
+func (t *PartitionTable) Partitions() []*Partition {
+	var parts []*Partition
+	if t.GPT != nil {
+		for i := range t.GPT.Parts {
+			parts = append(parts, &t.GPT.Parts[i])
+		}
+		return parts
+	}
+	if t.MSDOS != nil {
+		for i := range t.MSDOS.Parts {
+			parts = append(parts, &t.MSDOS.Parts[i])
+		}
+		return parts
+	}
+	for i := range t.MBR.Mbr_partitions {
+		parts = append(parts, &t.MBR.Mbr_partitions[i])
+	}
+	return parts
+}
+
+// UsedPartitions devuelve las particiones asignadas ordenadas por su byte de inicio
+func (t *PartitionTable) UsedPartitions() []*Partition {
+	var parts []*Partition
+	for _, p := range t.Partitions() {
+		if !isEmptyPartition(p) {
+			parts = append(parts, p)
+		}
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Part_start < parts[j].Part_start })
+	return parts
+}
+
+// GetPartitionByName devuelve la partición con el nombre indicado y su índice en la tabla
+func (t *PartitionTable) GetPartitionByName(name string) (*Partition, int) {
+	inputName := strings.Trim(name, "\x00 ")
+	for i, p := range t.Partitions() {
+		if t.PrimaryOnly() && isEmptyPartition(p) {
+			continue
+		}
+		partitionName := strings.Trim(string(p.Part_name[:]), "\x00 ")
+		if strings.EqualFold(partitionName, inputName) {
+			return p, i
+		}
+	}
+	return nil, -1
+}
+
+// GetPartitionByID devuelve la partición montada con el ID indicado
+func (t *PartitionTable) GetPartitionByID(id string) (*Partition, error) {
+	inputID := strings.Trim(id, "\x00 ")
+	for _, p := range t.Partitions() {
+		if t.PrimaryOnly() && isEmptyPartition(p) {
+			continue
+		}
+		partitionID := strings.Trim(string(p.Part_id[:]), "\x00 ")
+		if strings.EqualFold(partitionID, inputID) {
+			return p, nil
+		}
+	}
+	return nil, errors.New("partición no encontrada")
+}
+
+// ExtendedPartition devuelve la partición extendida, o nil si no hay (siempre en GPT y msdos)
+func (t *PartitionTable) ExtendedPartition() *Partition {
+	if t.PrimaryOnly() {
+		return nil
+	}
+	for _, p := range t.Partitions() {
+		if p.Part_type[0] == 'E' {
+			return p
+		}
+	}
+	return nil
+}
+
+// AllocatePartition busca una entrada libre y un byte de inicio para una partición de
+// size bytes. En GPT y msdos se usa el primer hueco alineado a sector donde quepa
+func (t *PartitionTable) AllocatePartition(size int) (*Partition, int, error) {
+	if t.MBR != nil {
+		partition, start, _ := t.MBR.GetFirstAvailablePartition()
+		if partition == nil {
+			return nil, -1, errors.New("no hay particiones disponibles en el MBR")
+		}
+		if int64(start)+int64(size) > t.UsableEnd() {
+			return nil, -1, errors.New("no hay espacio suficiente en el disco")
+		}
+		return partition, start, nil
+	}
+
+	var slot *Partition
+	for _, p := range t.Partitions() {
+		if isEmptyPartition(p) {
+			slot = p
+			break
+		}
+	}
+	if slot == nil {
+		return nil, -1, fmt.Errorf("no hay entradas disponibles en la tabla %s", t.TypeName())
+	}
+
+	start := t.MetadataSize()
+	for _, p := range t.UsedPartitions() {
+		if start+int64(size) <= int64(p.Part_start) {
+			break
+		}
+		end := int64(p.Part_start) + int64(p.Part_size)
+		if end > start {
+			start = (end + SectorSize - 1) / SectorSize * SectorSize
+		}
+	}
+	if start+int64(size) > t.UsableEnd() {
+		return nil, -1, errors.New("no hay espacio suficiente en el disco")
+	}
+	return slot, int(start), nil
+}
@@ -1,6 +1,51 @@
 package structures
 
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+)
+
 type PointerBlock struct {
 	P_pointers [16]int32 // 16 * 4 = 64 bytes
 	// Total: 64 bytes
 }
+
+// NewPointerBlock devuelve un bloque de apuntadores vacío
+func NewPointerBlock() *PointerBlock {
+	pb := &PointerBlock{}
+	for i := range pb.P_pointers {
+		pb.P_pointers[i] = -1
+	}
+	return pb
+}
+
+// Serialize escribe la estructura PointerBlock en un archivo binario en la posición especificada
+func (pb *PointerBlock) Serialize(path string, offset int64) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Seek(offset, 0)
+	if err != nil {
+		return err
+	}
+	return binary.Write(file, binary.LittleEndian, pb)
+}
+
+// Deserialize lee la estructura PointerBlock desde un archivo binario en la posición especificada
+func (pb *PointerBlock) Deserialize(path string, offset int64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, binary.Size(pb))
+	if _, err := file.ReadAt(buffer, offset); err != nil {
+		return err
+	}
+	return binary.Read(bytes.NewReader(buffer), binary.LittleEndian, pb)
+}
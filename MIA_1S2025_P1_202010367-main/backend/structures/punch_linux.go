@@ -0,0 +1,25 @@
+//go:build linux
+
+package structures
+
+import (
+	"os"
+	"syscall"
+)
+
+// Banderas de fallocate(2): libera el rango del archivo sin cambiar su tamaño
+const (
+	fallocKeepSize  = 0x01 // FALLOC_FL_KEEP_SIZE
+	fallocPunchHole = 0x02 // FALLOC_FL_PUNCH_HOLE
+)
+
+// punchHole libera en el anfitrión los bytes [offset, offset+length) del archivo. Las
+// lecturas posteriores devuelven ceros. Si el sistema de archivos del anfitrión no admite
+// huecos se escriben ceros
+func punchHole(file *os.File, offset, length int64) error {
+	err := syscall.Fallocate(int(file.Fd()), fallocPunchHole|fallocKeepSize, offset, length)
+	if err == syscall.EOPNOTSUPP || err == syscall.ENOSYS {
+		return zeroRange(file, offset, length)
+	}
+	return err
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package structures
+
+import "os"
+
+// punchHole escribe ceros en los bytes [offset, offset+length) del archivo. Fuera de Linux
+// no se usa fallocate, así que el espacio no se libera en el anfitrión
+func punchHole(file *os.File, offset, length int64) error {
+	return zeroRange(file, offset, length)
+}
@@ -0,0 +1,49 @@
+package structures
+
+import "os"
+
+// relocateChunk es el tamaño de cada lectura al mover datos dentro del disco
+const relocateChunk = 1 << 20
+
+// MoveBytes copia length bytes del disco desde src hasta dst. Los dos rangos pueden
+// superponerse, como en memmove: si el destino está después del origen se copia desde el
+// final para no pisar datos que todavía no se han leído
+func MoveBytes(path string, src, dst, length int64) error {
+	if src == dst || length <= 0 {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, relocateChunk)
+	for done := int64(0); done < length; {
+		n := min(int64(relocateChunk), length-done)
+		offset := done
+		if dst > src {
+			offset = length - done - n
+		}
+		if _, err := file.ReadAt(buffer[:n], src+offset); err != nil {
+			return err
+		}
+		if _, err := file.WriteAt(buffer[:n], dst+offset); err != nil {
+			return err
+		}
+		done += n
+	}
+	return nil
+}
+
+// Rebase corre los inicios de las áreas del sistema de archivos delta bytes, para una
+// partición cuyos datos se movieron a otra posición del disco
+func (sb *SuperBlock) Rebase(delta int64) {
+	sb.S_bm_inode_start += int32(delta)
+	sb.S_bm_block_start += int32(delta)
+	sb.S_inode_start += int32(delta)
+	sb.S_block_start += int32(delta)
+	if sb.S_journal_start > 0 {
+		sb.S_journal_start += int32(delta)
+	}
+}
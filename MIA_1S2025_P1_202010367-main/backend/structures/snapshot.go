@@ -0,0 +1,367 @@
+package structures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// SnapshotMagic identifica los archivos de snapshot ("SNAP")
+const SnapshotMagic int32 = 0x50414E53
+
+// SnapshotHeader encabeza el archivo de un snapshot de partición
+type SnapshotHeader struct {
+	Magic        int32
+	Name         [16]byte
+	Date         float32
+	PartStart    int32 // Inicio de la partición a la que pertenece
+	FolderBlocks int32 // Bloques de carpeta copiados en el snapshot
+	SharedBlocks int32 // Bloques de archivo compartidos con la partición
+	// Total: 36 bytes
+}
+
+// SnapshotFolderBlock guarda la copia de un bloque de carpeta junto con su dueño
+type SnapshotFolderBlock struct {
+	Index int32 // Bloque original
+	Inode int32 // Inodo carpeta que lo apunta
+	Slot  int32 // Posición dentro de I_block
+	Block FolderBlock
+	// Total: 76 bytes
+}
+
+// Snapshot es una copia de los metadatos de la partición. Los bloques de archivo no se
+// copian: el snapshot mantiene una referencia sobre cada uno en el bitmap de bloques y
+// cualquier escritura posterior sobre ellos hace copy-on-write.
+type Snapshot struct {
+	Header       SnapshotHeader
+	SuperBlock   SuperBlock
+	Journal      []byte
+	InodeBitmap  []byte
+	Inodes       []Inode
+	FolderBlocks []SnapshotFolderBlock
+	SharedBlocks []int32
+}
+
+// Serialize escribe el snapshot en un archivo del sistema anfitrión
+func (s *Snapshot) Serialize(path string) error {
+	var buffer bytes.Buffer
+	for _, data := range []interface{}{&s.Header, &s.SuperBlock, s.Journal, s.InodeBitmap, s.Inodes, s.FolderBlocks, s.SharedBlocks} {
+		if err := binary.Write(&buffer, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, buffer.Bytes(), 0644)
+}
+
+// Deserialize lee un snapshot desde un archivo del sistema anfitrión
+func (s *Snapshot) Deserialize(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	reader := bytes.NewReader(data)
+
+	if err := binary.Read(reader, binary.LittleEndian, &s.Header); err != nil {
+		return err
+	}
+	if s.Header.Magic != SnapshotMagic {
+		return fmt.Errorf("%s no es un archivo de snapshot válido", path)
+	}
+	if err := binary.Read(reader, binary.LittleEndian, &s.SuperBlock); err != nil {
+		return err
+	}
+
+	s.Journal = make([]byte, s.SuperBlock.journalSize())
+	s.InodeBitmap = make([]byte, s.SuperBlock.S_inodes_count)
+	s.Inodes = make([]Inode, s.SuperBlock.S_inodes_count)
+	s.FolderBlocks = make([]SnapshotFolderBlock, s.Header.FolderBlocks)
+	s.SharedBlocks = make([]int32, s.Header.SharedBlocks)
+	for _, data := range []interface{}{s.Journal, s.InodeBitmap, s.Inodes, s.FolderBlocks, s.SharedBlocks} {
+		if err := binary.Read(reader, binary.LittleEndian, data); err != nil {
+			return fmt.Errorf("snapshot incompleto: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetName devuelve el nombre del snapshot sin los bytes nulos
+func (s *Snapshot) GetName() string {
+	return strings.Trim(string(s.Header.Name[:]), "\x00")
+}
+
+// journalSize devuelve el tamaño en bytes del área del Journal (0 en EXT2)
+func (sb *SuperBlock) journalSize() int32 {
+	if sb.S_filesystem_type != 3 {
+		return 0
+	}
+	return sb.S_journal_count * int32(binary.Size(Journal{}))
+}
+
+// ReadInodeTable lee la tabla de inodos completa
+func (sb *SuperBlock) ReadInodeTable(path string) ([]Inode, error) {
+	buffer := make([]byte, int64(sb.S_inodes_count)*int64(sb.S_inode_size))
+	if err := readAt(path, buffer, int64(sb.S_inode_start)); err != nil {
+		return nil, err
+	}
+	inodes := make([]Inode, sb.S_inodes_count)
+	if err := binary.Read(bytes.NewReader(buffer), binary.LittleEndian, inodes); err != nil {
+		return nil, err
+	}
+	return inodes, nil
+}
+
+// WriteInodeTable escribe la tabla de inodos completa
+func (sb *SuperBlock) WriteInodeTable(path string, inodes []Inode) error {
+	var buffer bytes.Buffer
+	if err := binary.Write(&buffer, binary.LittleEndian, inodes); err != nil {
+		return err
+	}
+	return writeAt(path, buffer.Bytes(), int64(sb.S_inode_start))
+}
+
+// TakeSnapshot copia los metadatos de la partición. Todavía no toma referencias sobre
+// los bloques compartidos; eso lo hace HoldSnapshot una vez guardado el archivo.
+func (sb *SuperBlock) TakeSnapshot(path, name string, partStart int32) (*Snapshot, error) {
+	snap := &Snapshot{SuperBlock: *sb}
+	snap.Header.Magic = SnapshotMagic
+	copy(snap.Header.Name[:], name)
+	snap.Header.Date = float32(time.Now().Unix())
+	snap.Header.PartStart = partStart
+
+	snap.Journal = make([]byte, sb.journalSize())
+	if err := readAt(path, snap.Journal, int64(sb.S_journal_start)); err != nil {
+		return nil, fmt.Errorf("error al leer Journal: %v", err)
+	}
+	inodeBitmap, err := sb.ReadInodeBitmap(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+	snap.InodeBitmap = inodeBitmap
+	inodes, err := sb.ReadInodeTable(path)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer tabla de inodos: %v", err)
+	}
+	snap.Inodes = inodes
+
+	shared := make(map[int32]bool)
+	for i, inode := range inodes {
+		if snap.InodeBitmap[i] != '1' {
+			continue
+		}
+		switch inode.I_type[0] {
+		case '0':
+			for slot, blockNum := range inode.I_block[:12] {
+				if blockNum == -1 {
+					continue
+				}
+				folderBlock := &FolderBlock{}
+				err := folderBlock.Deserialize(path, int64(sb.S_block_start)+int64(blockNum)*int64(sb.S_block_size))
+				if err != nil {
+					return nil, fmt.Errorf("error al leer bloque de carpeta %d: %v", blockNum, err)
+				}
+				snap.FolderBlocks = append(snap.FolderBlocks, SnapshotFolderBlock{
+					Index: blockNum,
+					Inode: int32(i),
+					Slot:  int32(slot),
+					Block: *folderBlock,
+				})
+			}
+		case '1':
+			// Los bloques de apuntadores también se retienen: nunca se modifican en su
+			// lugar, así que siguen describiendo el archivo del snapshot
+			data, pointers, err := sb.FileBlocks(path, &inodes[i])
+			if err != nil {
+				return nil, fmt.Errorf("error al leer bloques del inodo %d: %v", i, err)
+			}
+			for _, blockNum := range append(data, pointers...) {
+				if !shared[blockNum] {
+					shared[blockNum] = true
+					snap.SharedBlocks = append(snap.SharedBlocks, blockNum)
+				}
+			}
+		}
+	}
+
+	snap.Header.FolderBlocks = int32(len(snap.FolderBlocks))
+	snap.Header.SharedBlocks = int32(len(snap.SharedBlocks))
+	return snap, nil
+}
+
+// HoldSnapshot añade la referencia del snapshot a cada bloque de archivo compartido
+func (sb *SuperBlock) HoldSnapshot(path string, snap *Snapshot) error {
+	for _, blockNum := range snap.SharedBlocks {
+		if err := sb.IncBlockRefs(path, blockNum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReleaseSnapshot suelta las referencias del snapshot; los bloques que solo él usaba quedan libres
+func (sb *SuperBlock) ReleaseSnapshot(path string, snap *Snapshot) error {
+	for _, blockNum := range snap.SharedBlocks {
+		if _, err := sb.ReleaseBlock(path, blockNum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreSnapshot devuelve la partición al estado del snapshot. others son los demás
+// snapshots de la partición, cuyos bloques deben seguir reservados.
+func (sb *SuperBlock) RestoreSnapshot(path string, snap *Snapshot, others []*Snapshot) error {
+	old := snap.SuperBlock
+	if old.S_filesystem_type != sb.S_filesystem_type || old.S_inodes_count != sb.S_inodes_count ||
+		old.S_blocks_count != sb.S_blocks_count || old.S_bm_inode_start != sb.S_bm_inode_start ||
+		old.S_block_start != sb.S_block_start {
+		return errors.New("el snapshot no corresponde al formato actual de la partición")
+	}
+
+	// Los bloques compartidos deben seguir reservados; un loss o un formateo los invalida
+	for _, blockNum := range snap.SharedBlocks {
+		refs, err := sb.GetBlockRefs(path, blockNum)
+		if err != nil {
+			return err
+		}
+		if refs == 0 {
+			return fmt.Errorf("el snapshot está dañado: el bloque %d ya no está reservado", blockNum)
+		}
+	}
+
+	refs := make([]int32, sb.S_blocks_count)
+	held := make(map[int32]bool)
+	for _, s := range append([]*Snapshot{snap}, others...) {
+		for _, blockNum := range s.SharedBlocks {
+			if blockNum < 0 || blockNum >= sb.S_blocks_count {
+				return fmt.Errorf("el snapshot %s reserva un bloque inválido: %d", s.GetName(), blockNum)
+			}
+			refs[blockNum]++
+			held[blockNum] = true
+		}
+	}
+
+	// Referencias de los archivos restaurados
+	inodes := make([]Inode, len(snap.Inodes))
+	copy(inodes, snap.Inodes)
+	for i, inode := range inodes {
+		if snap.InodeBitmap[i] != '1' || inode.I_type[0] != '1' {
+			continue
+		}
+		for _, blockNum := range inode.I_block[:12] {
+			if blockNum < -1 || blockNum >= sb.S_blocks_count {
+				return fmt.Errorf("el inodo %d del snapshot apunta a un bloque inválido: %d", i, blockNum)
+			}
+		}
+		data, pointers, err := sb.FileBlocks(path, &inodes[i])
+		if err != nil {
+			return fmt.Errorf("el inodo %d del snapshot tiene apuntadores inválidos: %v", i, err)
+		}
+		for _, blockNum := range append(data, pointers...) {
+			refs[blockNum]++
+		}
+	}
+
+	// Restaurar los bloques de carpeta. Si otro snapshot reservó el bloque original
+	// para uno de sus archivos, la carpeta se reubica en un bloque libre.
+	restoredFolders := make(map[int32]int32)
+	for _, fb := range snap.FolderBlocks {
+		// Un bloque apuntado por dos carpetas (discos creados con versiones anteriores)
+		// se restaura una sola vez
+		if target, ok := restoredFolders[fb.Index]; ok {
+			inodes[fb.Inode].I_block[fb.Slot] = target
+			continue
+		}
+
+		target := fb.Index
+		if refs[target] > 0 {
+			target = -1
+			for i := range refs {
+				if refs[i] == 0 {
+					target = int32(i)
+					break
+				}
+			}
+			if target == -1 {
+				return errors.New("no hay bloques libres para restaurar las carpetas del snapshot")
+			}
+			inodes[fb.Inode].I_block[fb.Slot] = target
+		}
+		refs[target] = 1
+		restoredFolders[fb.Index] = target
+
+		block := fb.Block
+		err := block.Serialize(path, int64(sb.S_block_start)+int64(target)*int64(sb.S_block_size))
+		if err != nil {
+			return fmt.Errorf("error al restaurar bloque de carpeta %d: %v", target, err)
+		}
+	}
+
+	bitmap := make([]byte, sb.S_blocks_count)
+	freeBlocks := int32(0)
+	for i, r := range refs {
+		if r > MaxBlockRefs {
+			return fmt.Errorf("el bloque %d supera el máximo de referencias", i)
+		}
+		if r == 0 {
+			freeBlocks++
+		}
+		bitmap[i] = byte('0' + r)
+	}
+
+	if err := writeAt(path, snap.Journal, int64(sb.S_journal_start)); err != nil {
+		return fmt.Errorf("error al restaurar Journal: %v", err)
+	}
+	if err := writeAt(path, snap.InodeBitmap, int64(sb.S_bm_inode_start)); err != nil {
+		return fmt.Errorf("error al restaurar bitmap de inodos: %v", err)
+	}
+	if err := writeAt(path, bitmap, int64(sb.S_bm_block_start)); err != nil {
+		return fmt.Errorf("error al restaurar bitmap de bloques: %v", err)
+	}
+	forgetAllocators(path)
+	if err := sb.WriteInodeTable(path, inodes); err != nil {
+		return fmt.Errorf("error al restaurar tabla de inodos: %v", err)
+	}
+
+	// Los datos de montaje pertenecen a la sesión actual, no al snapshot
+	restored := snap.SuperBlock
+	restored.S_mtime = sb.S_mtime
+	restored.S_umtime = sb.S_umtime
+	restored.S_mnt_count = sb.S_mnt_count
+	restored.S_free_blocks_count = freeBlocks
+	*sb = restored
+	return sb.Serialize(path, sb.Offset())
+}
+
+// readAt lee len(buffer) bytes del archivo a partir de offset
+func readAt(path string, buffer []byte, offset int64) error {
+	if len(buffer) == 0 {
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.ReadAt(buffer, offset)
+	return err
+}
+
+// writeAt escribe buffer en el archivo a partir de offset
+func writeAt(path string, buffer []byte, offset int64) error {
+	if len(buffer) == 0 {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteAt(buffer, offset)
+	return err
+}
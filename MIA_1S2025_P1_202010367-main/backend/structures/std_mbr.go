@@ -0,0 +1,241 @@
+package structures
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+const SectorSize = 512 // Tamaño de un sector (LBA) en bytes
+
+// StandardPartitionEntry es una entrada de 16 bytes de la tabla de particiones de un MBR
+// estándar, como la leen las herramientas del sistema anfitrión
+type StandardPartitionEntry struct {
+	Status   byte    // 0x80 si es arrancable
+	CHSFirst [3]byte // Dirección CHS del primer sector (no se usa)
+	Type     byte    // Código del tipo de partición
+	CHSLast  [3]byte // Dirección CHS del último sector (no se usa)
+	LBAStart uint32  // Primer sector de la partición
+	Sectors  uint32  // Número de sectores
+}
+
+// StandardMBR es el sector 0 de un disco con el formato estándar: 446 bytes de código de
+// arranque, 4 entradas de partición y la firma 0x55AA
+type StandardMBR struct {
+	BootCode   [446]byte
+	Partitions [4]StandardPartitionEntry
+	Signature  [2]byte
+	// Total: 512 bytes
+}
+
+// Serialize escribe el MBR estándar en el sector 0 del disco
+func (mbr *StandardMBR) Serialize(path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return binary.Write(file, binary.LittleEndian, mbr)
+}
+
+// Deserialize lee el sector 0 del disco como un MBR estándar
+func (mbr *StandardMBR) Deserialize(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, SectorSize)
+	if _, err := file.ReadAt(buffer, 0); err != nil {
+		return err
+	}
+	return binary.Read(bytes.NewReader(buffer), binary.LittleEndian, mbr)
+}
+
+// IsValid indica si el sector tiene la firma 0x55AA
+func (mbr *StandardMBR) IsValid() bool {
+	return mbr.Signature == [2]byte{0x55, 0xAA}
+}
+
+const (
+	msdosLinuxType  = 0x83 // Código de tipo "Linux" para las particiones
+	msdosFirstLBA   = 2    // Sector 0: MBR estándar; sector 1: tabla auxiliar del proyecto
+	msdosSideSector = 1
+)
+
+var msdosSideMagic = [4]byte{'M', 'I', 'A', 'M'}
+
+// MSDOSSideEntry guarda los campos del proyecto que una entrada estándar no tiene
+type MSDOSSideEntry struct {
+	Part_status      [1]byte
+	Part_fit         [1]byte
+	Part_size        int32 // Tamaño exacto en bytes; la entrada estándar lo redondea a sectores
+	Part_name        [16]byte
+	Part_correlative int32
+	Part_id          [4]byte
+}
+
+// MSDOSSideTable se guarda en el sector 1, que las herramientas del anfitrión ignoran
+type MSDOSSideTable struct {
+	Magic        [4]byte
+	CreationDate float32
+	Fit          [1]byte
+	Entries      [4]MSDOSSideEntry
+}
+
+// MSDOSTable es una tabla MBR con el formato estándar que entienden fdisk o sfdisk
+type MSDOSTable struct {
+	Sector   StandardMBR
+	Side     MSDOSSideTable
+	Parts    [4]Partition // Entradas con los campos del proyecto
+	DiskSize int32
+}
+
+// NewMSDOSTable crea una tabla MBR estándar vacía para un disco de diskSize bytes
+func NewMSDOSTable(diskSize int32, creationDate float32, signature int32, fit byte) *MSDOSTable {
+	table := &MSDOSTable{DiskSize: diskSize}
+	table.Sector.Signature = [2]byte{0x55, 0xAA}
+	binary.LittleEndian.PutUint32(table.Sector.BootCode[440:444], uint32(signature))
+	table.Side = MSDOSSideTable{
+		Magic:        msdosSideMagic,
+		CreationDate: creationDate,
+		Fit:          [1]byte{fit},
+	}
+	for i := range table.Parts {
+		table.Parts[i] = EmptyPartition()
+	}
+	return table
+}
+
+// IsMSDOSDisk indica si el disco tiene un MBR estándar creado por el proyecto
+func IsMSDOSDisk(path string) bool {
+	var mbr StandardMBR
+	if err := mbr.Deserialize(path); err != nil || !mbr.IsValid() {
+		return false
+	}
+	if mbr.Partitions[0].Type == gptProtectiveID {
+		return false
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	magic := make([]byte, 4)
+	if _, err := file.ReadAt(magic, msdosSideSector*SectorSize); err != nil {
+		return false
+	}
+	return bytes.Equal(magic, msdosSideMagic[:])
+}
+
+// DiskSignature devuelve la firma del disco guardada en el área de arranque
+func (t *MSDOSTable) DiskSignature() int32 {
+	return int32(binary.LittleEndian.Uint32(t.Sector.BootCode[440:444]))
+}
+
+// UsableStart devuelve el primer byte donde puede empezar una partición
+func (t *MSDOSTable) UsableStart() int64 {
+	return msdosFirstLBA * SectorSize
+}
+
+// UsableEnd devuelve el byte siguiente al último sector completo del disco
+func (t *MSDOSTable) UsableEnd() int64 {
+	return int64(t.DiskSize) / SectorSize * SectorSize
+}
+
+// Serialize escribe el sector 0 estándar y la tabla auxiliar del proyecto
+func (t *MSDOSTable) Serialize(path string) error {
+	for i := range t.Parts {
+		p := &t.Parts[i]
+		if isEmptyPartition(p) {
+			t.Sector.Partitions[i] = StandardPartitionEntry{}
+			t.Side.Entries[i] = MSDOSSideEntry{}
+			continue
+		}
+		t.Sector.Partitions[i] = StandardPartitionEntry{
+			CHSFirst: [3]byte{0xFE, 0xFF, 0xFF}, // Fuera del rango CHS: usar LBA
+			Type:     msdosLinuxType,
+			CHSLast:  [3]byte{0xFE, 0xFF, 0xFF},
+			LBAStart: uint32(p.Part_start) / SectorSize,
+			Sectors:  (uint32(p.Part_size) + SectorSize - 1) / SectorSize,
+		}
+		t.Side.Entries[i] = MSDOSSideEntry{
+			Part_status:      p.Part_status,
+			Part_fit:         p.Part_fit,
+			Part_size:        p.Part_size,
+			Part_name:        p.Part_name,
+			Part_correlative: p.Part_correlative,
+			Part_id:          p.Part_id,
+		}
+	}
+
+	if err := t.Sector.Serialize(path); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Seek(msdosSideSector*SectorSize, 0); err != nil {
+		return err
+	}
+	return binary.Write(file, binary.LittleEndian, &t.Side)
+}
+
+// Deserialize lee el sector 0 estándar y completa las particiones con la tabla auxiliar
+func (t *MSDOSTable) Deserialize(path string) error {
+	if err := t.Sector.Deserialize(path); err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	t.DiskSize = int32(info.Size())
+
+	buffer := make([]byte, binary.Size(t.Side))
+	if _, err := file.ReadAt(buffer, msdosSideSector*SectorSize); err != nil {
+		return err
+	}
+	if err := binary.Read(bytes.NewReader(buffer), binary.LittleEndian, &t.Side); err != nil {
+		return err
+	}
+
+	for i, entry := range t.Sector.Partitions {
+		if entry.Type == 0 || entry.Sectors == 0 {
+			t.Parts[i] = EmptyPartition()
+			continue
+		}
+		side := t.Side.Entries[i]
+		p := Partition{
+			Part_status:      [1]byte{'0'},
+			Part_type:        [1]byte{'P'},
+			Part_fit:         [1]byte{'F'},
+			Part_start:       int32(entry.LBAStart) * SectorSize,
+			Part_size:        int32(entry.Sectors) * SectorSize,
+			Part_correlative: -1,
+		}
+		copy(p.Part_name[:], fmt.Sprintf("part%d", i+1))
+		if side.Part_status[0] != 0 {
+			p.Part_status = side.Part_status
+			p.Part_fit = side.Part_fit
+			p.Part_size = side.Part_size
+			p.Part_name = side.Part_name
+			p.Part_correlative = side.Part_correlative
+			p.Part_id = side.Part_id
+		}
+		t.Parts[i] = p
+	}
+	return nil
+}
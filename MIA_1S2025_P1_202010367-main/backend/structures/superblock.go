@@ -241,11 +241,11 @@ func (sb *SuperBlock) CreateFolder(path string, parentsDir []string, destDir str
 		}
 		if !found {
 			// Crear nuevo directorio padre
-			newInodeNum, err := sb.FindFreeInode(path)
+			newInodeNum, err := sb.FindFreeInodeNear(path, currentInodeNum)
 			if err != nil {
 				return fmt.Errorf("error al encontrar inodo libre: %v", err)
 			}
-			newBlockNum, err := sb.FindFreeBlock(path)
+			newBlockNum, err := sb.FindFreeBlockNear(path, BlockGoal(currentInode))
 			if err != nil {
 				return fmt.Errorf("error al encontrar bloque libre: %v", err)
 			}
@@ -290,26 +290,9 @@ func (sb *SuperBlock) CreateFolder(path string, parentsDir []string, destDir str
 			sb.S_free_blocks_count--
 
 			// Vincular al padre
-			for i, blockNum := range currentInode.I_block[:12] {
-				if blockNum == -1 {
-					currentInode.I_block[i] = newBlockNum
-					break
-				}
-				folderBlock := &FolderBlock{}
-				err = folderBlock.Deserialize(path, int64(sb.S_block_start+blockNum*sb.S_block_size))
-				if err != nil {
-					return err
-				}
-				for j, content := range folderBlock.B_content {
-					if content.B_inodo == -1 {
-						folderBlock.B_content[j] = FolderContent{B_name: ToByte12(dir), B_inodo: newInodeNum}
-						err = folderBlock.Serialize(path, int64(sb.S_block_start+blockNum*sb.S_block_size))
-						if err != nil {
-							return err
-						}
-						break
-					}
-				}
+			err = sb.linkToParent(path, currentInode, dir, newInodeNum)
+			if err != nil {
+				return err
 			}
 			err = currentInode.Serialize(path, int64(sb.S_inode_start+currentInodeNum*sb.S_inode_size))
 			if err != nil {
@@ -324,11 +307,11 @@ func (sb *SuperBlock) CreateFolder(path string, parentsDir []string, destDir str
 	}
 
 	// Crear el directorio final
-	newInodeNum, err := sb.FindFreeInode(path)
+	newInodeNum, err := sb.FindFreeInodeNear(path, currentInodeNum)
 	if err != nil {
 		return fmt.Errorf("error al encontrar inodo libre para %s: %v", destDir, err)
 	}
-	newBlockNum, err := sb.FindFreeBlock(path)
+	newBlockNum, err := sb.FindFreeBlockNear(path, BlockGoal(currentInode))
 	if err != nil {
 		return fmt.Errorf("error al encontrar bloque libre para %s: %v", destDir, err)
 	}
@@ -373,26 +356,9 @@ func (sb *SuperBlock) CreateFolder(path string, parentsDir []string, destDir str
 	sb.S_free_blocks_count--
 
 	// Vincular al padre
-	for i, blockNum := range currentInode.I_block[:12] {
-		if blockNum == -1 {
-			currentInode.I_block[i] = newBlockNum
-			break
-		}
-		folderBlock := &FolderBlock{}
-		err = folderBlock.Deserialize(path, int64(sb.S_block_start+blockNum*sb.S_block_size))
-		if err != nil {
-			return err
-		}
-		for j, content := range folderBlock.B_content {
-			if content.B_inodo == -1 {
-				folderBlock.B_content[j] = FolderContent{B_name: ToByte12(destDir), B_inodo: newInodeNum}
-				err = folderBlock.Serialize(path, int64(sb.S_block_start+blockNum*sb.S_block_size))
-				if err != nil {
-					return err
-				}
-				break
-			}
-		}
+	err = sb.linkToParent(path, currentInode, destDir, newInodeNum)
+	if err != nil {
+		return err
 	}
 	err = currentInode.Serialize(path, int64(sb.S_inode_start+currentInodeNum*sb.S_inode_size))
 	if err != nil {
@@ -400,7 +366,7 @@ func (sb *SuperBlock) CreateFolder(path string, parentsDir []string, destDir str
 	}
 
 	// Serializar el superbloque
-	err = sb.Serialize(path, int64(sb.S_bm_inode_start)-int64(binary.Size(sb)))
+	err = sb.Serialize(path, sb.Offset())
 	if err != nil {
 		return fmt.Errorf("error al serializar superbloque: %v", err)
 	}
@@ -408,63 +374,75 @@ func (sb *SuperBlock) CreateFolder(path string, parentsDir []string, destDir str
 	return nil
 }
 
-func (sb *SuperBlock) FindFreeInode(path string) (int32, error) {
-	if sb.S_free_inodes_count <= 0 {
-		return -1, errors.New("no hay inodos libres disponibles")
-	}
-	file, err := os.OpenFile(path, os.O_RDWR, 0644)
-	if err != nil {
-		return -1, err
-	}
-	defer file.Close()
-
-	_, err = file.Seek(int64(sb.S_bm_inode_start), 0)
-	if err != nil {
-		return -1, err
-	}
-
-	bm := make([]byte, sb.S_inodes_count)
-	_, err = file.Read(bm)
-	if err != nil {
-		return -1, err
-	}
+// linkToParent agrega la entrada name -> inodeNum en el primer espacio libre de la carpeta
+// padre; si todos sus bloques están llenos, le asigna un bloque de carpeta nuevo
+func (sb *SuperBlock) linkToParent(path string, parent *Inode, name string, inodeNum int32) error {
+	for i, blockNum := range parent.I_block[:12] {
+		if blockNum == -1 {
+			newBlockNum, err := sb.FindFreeBlockNear(path, BlockGoal(parent))
+			if err != nil {
+				return fmt.Errorf("error al encontrar bloque libre para la carpeta padre: %v", err)
+			}
+			folderBlock := &FolderBlock{
+				B_content: [4]FolderContent{
+					{B_name: ToByte12(name), B_inodo: inodeNum},
+					{B_name: ToByte12("-"), B_inodo: -1},
+					{B_name: ToByte12("-"), B_inodo: -1},
+					{B_name: ToByte12("-"), B_inodo: -1},
+				},
+			}
+			err = folderBlock.Serialize(path, int64(sb.S_block_start+newBlockNum*sb.S_block_size))
+			if err != nil {
+				return err
+			}
+			err = sb.UpdateBitmapBlock(path, newBlockNum)
+			if err != nil {
+				return err
+			}
+			sb.S_free_blocks_count--
+			parent.I_block[i] = newBlockNum
+			return nil
+		}
 
-	for i := int32(0); i < sb.S_inodes_count; i++ {
-		if bm[i] == '0' {
-			return i, nil
+		folderBlock := &FolderBlock{}
+		err := folderBlock.Deserialize(path, int64(sb.S_block_start+blockNum*sb.S_block_size))
+		if err != nil {
+			return err
+		}
+		for j, content := range folderBlock.B_content {
+			if content.B_inodo == -1 {
+				folderBlock.B_content[j] = FolderContent{B_name: ToByte12(name), B_inodo: inodeNum}
+				return folderBlock.Serialize(path, int64(sb.S_block_start+blockNum*sb.S_block_size))
+			}
 		}
 	}
-	return -1, fmt.Errorf("no se encontraron inodos libres, pero S_free_inodes_count es %d", sb.S_free_inodes_count)
+	return errors.New("la carpeta padre no tiene espacio en sus bloques directos")
 }
 
-// FindFreeBlock busca un bloque libre en el bitmap de bloques
-func (sb *SuperBlock) FindFreeBlock(path string) (int32, error) {
-	if sb.S_free_blocks_count <= 0 {
-		return -1, errors.New("no hay bloques libres disponibles")
-	}
-	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+// LinkInode agrega la entrada name -> inodeNum a la carpeta parentNum y la guarda
+func (sb *SuperBlock) LinkInode(path string, parentNum int32, name string, inodeNum int32) error {
+	parent := &Inode{}
+	err := parent.Deserialize(path, int64(sb.S_inode_start+parentNum*sb.S_inode_size))
 	if err != nil {
-		return -1, err
+		return fmt.Errorf("error al leer inodo padre %d: %v", parentNum, err)
 	}
-	defer file.Close()
-
-	_, err = file.Seek(int64(sb.S_bm_block_start), 0)
-	if err != nil {
-		return -1, err
+	if err := sb.linkToParent(path, parent, name, inodeNum); err != nil {
+		return err
 	}
+	parent.I_mtime = float32(time.Now().Unix())
+	return parent.Serialize(path, int64(sb.S_inode_start+parentNum*sb.S_inode_size))
+}
 
-	bm := make([]byte, sb.S_blocks_count)
-	_, err = file.Read(bm)
-	if err != nil {
-		return -1, err
-	}
+// FindFreeInode busca un inodo libre en el bitmap de inodos, a partir del siguiente al
+// último reservado en el comando
+func (sb *SuperBlock) FindFreeInode(path string) (int32, error) {
+	return sb.FindFreeInodeNear(path, -1)
+}
 
-	for i := int32(0); i < sb.S_blocks_count; i++ {
-		if bm[i] == '0' {
-			return i, nil
-		}
-	}
-	return -1, fmt.Errorf("no se encontraron bloques libres, pero S_free_blocks_count es %d", sb.S_free_blocks_count)
+// FindFreeBlock busca un bloque libre en el bitmap de bloques, a partir del siguiente al
+// último reservado en el comando
+func (sb *SuperBlock) FindFreeBlock(path string) (int32, error) {
+	return sb.FindFreeBlockNear(path, -1)
 }
 
 // toByte12 convierte un string a un array de 12 bytes
@@ -473,3 +451,12 @@ func ToByte12(name string) [12]byte {
 	copy(b[:], name)
 	return b
 }
+
+// Offset devuelve la posición del superbloque dentro del disco. En EXT3 el Journal
+// queda entre el superbloque y el bitmap de inodos.
+func (sb *SuperBlock) Offset() int64 {
+	if sb.S_filesystem_type == 3 && sb.S_journal_start > 0 {
+		return int64(sb.S_journal_start) - int64(binary.Size(SuperBlock{}))
+	}
+	return int64(sb.S_bm_inode_start) - int64(binary.Size(SuperBlock{}))
+}
@@ -0,0 +1,219 @@
+package structures
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// TarOwnerNames devuelve los nombres de usuario y grupo que se guardan en el tar
+type TarOwnerNames func(uid, gid uint16) (uname, gname string)
+
+// TarOwnerIDs resuelve el propietario de una entrada del tar a los IDs de la partición
+type TarOwnerIDs func(uname, gname string, uid, gid int) (uint16, uint16)
+
+// WriteTarArchive escribe el árbol en un archivo tar con formato PAX para conservar las
+// tres fechas. Si name no está vacío el nodo raíz se guarda con ese nombre; si no, solo
+// se guardan sus hijos. Los nodos compartidos se escriben como enlaces duros
+func WriteTarArchive(archivePath, name string, root *Ext2ImageNode, owner TarOwnerNames) error {
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	tw := tar.NewWriter(file)
+	seen := make(map[*Ext2ImageNode]string)
+
+	var write func(name string, node *Ext2ImageNode) error
+	write = func(name string, node *Ext2ImageNode) error {
+		uname, gname := owner(node.Uid, node.Gid)
+		header := &tar.Header{
+			Name:       name,
+			Mode:       int64(node.Perm & 0o777),
+			Uid:        int(node.Uid),
+			Gid:        int(node.Gid),
+			Uname:      uname,
+			Gname:      gname,
+			ModTime:    time.Unix(int64(node.Mtime), 0),
+			AccessTime: time.Unix(int64(node.Atime), 0),
+			ChangeTime: time.Unix(int64(node.Ctime), 0),
+			Format:     tar.FormatPAX,
+		}
+
+		switch {
+		case node.IsDir:
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+		case seen[node] != "":
+			header.Typeflag = tar.TypeLink
+			header.Linkname = seen[node]
+		default:
+			header.Typeflag = tar.TypeReg
+			header.Size = int64(len(node.Content))
+			seen[node] = name
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("error al escribir la cabecera de %s: %v", name, err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write(node.Content); err != nil {
+				return fmt.Errorf("error al escribir el contenido de %s: %v", name, err)
+			}
+		}
+		if !node.IsDir {
+			return nil
+		}
+		for _, child := range node.Children {
+			if err := write(name+"/"+child.Name, child.Node); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if name != "" {
+		err = write(name, root)
+	} else {
+		for _, child := range root.Children {
+			if err = write(child.Name, child.Node); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// ReadTarArchive lee un archivo tar como un árbol de nodos. Las carpetas que no tienen
+// entrada propia se crean con permisos 755. Los enlaces duros comparten el nodo de su
+// destino; los enlaces simbólicos, archivos especiales y rutas fuera del árbol se
+// devuelven como advertencias
+func ReadTarArchive(archivePath string, owner TarOwnerIDs) (*Ext2ImageNode, []string, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	now := uint32(time.Now().Unix())
+	root := &Ext2ImageNode{IsDir: true, Perm: 0o755, Atime: now, Ctime: now, Mtime: now}
+	nodes := map[string]*Ext2ImageNode{"": root}
+	var warnings []string
+
+	// parentOf devuelve la carpeta que contendrá name, creando las que falten
+	var parentOf func(name string, header *tar.Header) (*Ext2ImageNode, error)
+	parentOf = func(name string, header *tar.Header) (*Ext2ImageNode, error) {
+		dir := path.Dir(name)
+		if dir == "." {
+			return root, nil
+		}
+		if node, ok := nodes[dir]; ok {
+			if !node.IsDir {
+				return nil, fmt.Errorf("/%s no es una carpeta", dir)
+			}
+			return node, nil
+		}
+		parent, err := parentOf(dir, header)
+		if err != nil {
+			return nil, err
+		}
+		uid, gid := owner(header.Uname, header.Gname, header.Uid, header.Gid)
+		mtime := uint32(header.ModTime.Unix())
+		node := &Ext2ImageNode{IsDir: true, Perm: 0o755, Uid: uid, Gid: gid, Atime: mtime, Ctime: mtime, Mtime: mtime}
+		parent.Children = append(parent.Children, Ext2ImageEntry{Name: path.Base(dir), Node: node})
+		nodes[dir] = node
+		return node, nil
+	}
+
+	tr := tar.NewReader(file)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error al leer el archivo tar: %v", err)
+		}
+
+		name := path.Clean(strings.TrimLeft(header.Name, "/"))
+		if name == "." {
+			continue
+		}
+		if name == ".." || strings.HasPrefix(name, "../") {
+			warnings = append(warnings, fmt.Sprintf("%s: la ruta sale del archivo", header.Name))
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeReg, tar.TypeLink:
+		case tar.TypeSymlink:
+			warnings = append(warnings, fmt.Sprintf("/%s: enlace simbólico", name))
+			continue
+		case tar.TypeChar, tar.TypeBlock, tar.TypeFifo:
+			warnings = append(warnings, fmt.Sprintf("/%s: archivo especial", name))
+			continue
+		default:
+			warnings = append(warnings, fmt.Sprintf("/%s: tipo de entrada %q no soportado", name, header.Typeflag))
+			continue
+		}
+
+		parent, err := parentOf(name, header)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("/%s: %v", name, err))
+			continue
+		}
+
+		if header.Typeflag == tar.TypeLink {
+			target := nodes[path.Clean(strings.TrimLeft(header.Linkname, "/"))]
+			if target == nil || target.IsDir {
+				warnings = append(warnings, fmt.Sprintf("/%s: destino del enlace %s no encontrado", name, header.Linkname))
+				continue
+			}
+			if _, exists := nodes[name]; !exists {
+				parent.Children = append(parent.Children, Ext2ImageEntry{Name: path.Base(name), Node: target})
+				nodes[name] = target
+			}
+			continue
+		}
+
+		node, exists := nodes[name]
+		isDir := header.Typeflag == tar.TypeDir
+		if exists && node.IsDir != isDir {
+			warnings = append(warnings, fmt.Sprintf("/%s: aparece como carpeta y como archivo", name))
+			continue
+		}
+		if !exists {
+			node = &Ext2ImageNode{IsDir: isDir}
+			parent.Children = append(parent.Children, Ext2ImageEntry{Name: path.Base(name), Node: node})
+			nodes[name] = node
+		}
+
+		node.Perm = uint16(header.Mode & 0o777)
+		node.Uid, node.Gid = owner(header.Uname, header.Gname, header.Uid, header.Gid)
+		node.Mtime = uint32(header.ModTime.Unix())
+		node.Atime, node.Ctime = node.Mtime, node.Mtime
+		if !header.AccessTime.IsZero() {
+			node.Atime = uint32(header.AccessTime.Unix())
+		}
+		if !header.ChangeTime.IsZero() {
+			node.Ctime = uint32(header.ChangeTime.Unix())
+		}
+		if !isDir {
+			node.Content, err = io.ReadAll(tr)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error al leer /%s: %v", name, err)
+			}
+		}
+	}
+	return root, warnings, nil
+}
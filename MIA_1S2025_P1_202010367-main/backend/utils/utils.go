@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 // ConvertToBytes convierte un tamaño y una unidad a bytes
@@ -59,6 +61,20 @@ func GetLetterAndPartitionCorrelative(path string) (string, int, error) {
 	return pathToLetter[path], nextIndex, nil
 }
 
+// RestoreLetterAndPartitionCorrelative registra la letra y el correlativo de un ID montado
+// antes de reiniciar el servidor, para que los siguientes montajes no los repitan
+func RestoreLetterAndPartitionCorrelative(path, letter string, correlative int) {
+	pathToLetter[path] = letter
+	if correlative > pathToPartitionCount[path] {
+		pathToPartitionCount[path] = correlative
+	}
+	for i, l := range alphabet {
+		if l == letter && i >= nextLetterIndex {
+			nextLetterIndex = i + 1
+		}
+	}
+}
+
 // createParentDirs crea las carpetas padre si no existen
 func CreateParentDirs(path string) error {
 	dir := filepath.Dir(path)
@@ -139,3 +155,17 @@ func StringToInt(s string) (int, error) {
 	}
 	return num, nil
 }
+
+// IsText indica si el contenido es texto UTF-8 sin caracteres de control, salvo saltos
+// de línea, retornos de carro y tabulaciones
+func IsText(content []byte) bool {
+	if !utf8.Valid(content) {
+		return false
+	}
+	for _, r := range string(content) {
+		if unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+	return true
+}